@@ -0,0 +1,203 @@
+"""Thin client for the grpcmock HTTP control API.
+
+Uses only the standard library so it can be dropped into any pytest suite:
+
+    from grpcmock_client import GrpcMockClient
+
+    mock = GrpcMockClient("http://localhost:8081")
+    mock.add_expectation({
+        "fullMethodName": "/pkg.v1.Svc/Get",
+        "response": {"body": {"name": "Bob"}},
+    })
+    ...
+    assert mock.calls("/pkg.v1.Svc/Get")
+"""
+
+import json
+import urllib.error
+import urllib.parse
+import urllib.request
+
+
+class GrpcMockError(Exception):
+    """Raised when the control API answers with an error status."""
+
+    def __init__(self, status, body):
+        super().__init__(f"grpcmock control API returned {status}: {body}")
+        self.status = status
+        self.body = body
+
+
+class GrpcMockClient:
+    def __init__(self, base_url="http://localhost:8081", timeout=5.0):
+        self.base_url = base_url.rstrip("/")
+        self.timeout = timeout
+
+    def _request(self, method, path, body=None):
+        data = None if body is None else json.dumps(body).encode()
+        req = urllib.request.Request(
+            self.base_url + path,
+            data=data,
+            method=method,
+            headers={"Content-Type": "application/json"},
+        )
+        try:
+            with urllib.request.urlopen(req, timeout=self.timeout) as resp:
+                payload = resp.read()
+        except urllib.error.HTTPError as err:
+            raise GrpcMockError(err.code, err.read().decode()) from None
+        return json.loads(payload) if payload else None
+
+    # Server
+
+    def info(self):
+        """Returns the mock's version, ports, services, active modes and fixtures."""
+        return self._request("GET", "/info")
+
+    # Expectations
+
+    def add_expectation(self, expectation):
+        """Registers an expectation (a dict in the GRPCCallExpectation JSON format).
+
+        The answer holds the expectation's id, generated if the expectation has none.
+        """
+        return self._request("POST", "/expectations", expectation)
+
+    def expectation(self, expectation_id):
+        """Returns the expectation with the given id."""
+        return self._request("GET", "/expectations/" + urllib.parse.quote(expectation_id, safe=""))
+
+    def replace_expectation(self, expectation_id, expectation):
+        """Replaces the expectation with the given id, keeping the id; its match count starts over."""
+        return self._request("PUT", "/expectations/" + urllib.parse.quote(expectation_id, safe=""), expectation)
+
+    def remove_expectation(self, expectation_id):
+        """Removes the expectation with the given id."""
+        return self._request("DELETE", "/expectations/" + urllib.parse.quote(expectation_id, safe=""))
+
+    def import_expectations(self, expectations):
+        """Registers a list of expectations atomically: all of them, or none if any is invalid.
+
+        The answer holds their ids; a GrpcMockError's body lists the refused ones.
+        """
+        return self._request("POST", "/expectations/import", expectations)
+
+    def export_expectations(self):
+        """Returns all expectations as a list, in the format accepted by import_expectations and stub files."""
+        return self._request("GET", "/expectations/export")
+
+    def replay_check(self, expectation):
+        """Reports which recorded calls a proposed expectation would have matched, without registering it."""
+        return self._request("POST", "/expectations/replay-check", expectation)
+
+    def expectations(self):
+        """Returns the registered expectations, keyed by full method name."""
+        return self._request("GET", "/expectations")
+
+    def reset(self):
+        """Clears all expectations, recorded calls and match counts."""
+        return self._request("DELETE", "/expectations")
+
+    # Scenarios
+
+    def scenarios(self):
+        """Returns the current state of each scenario, keyed by name."""
+        return self._request("GET", "/scenarios")
+
+    def set_scenario_state(self, name, state):
+        """Moves a scenario to a state."""
+        return self._request("PUT", "/scenarios/" + urllib.parse.quote(name, safe=""), {"state": state})
+
+    def reset_scenarios(self):
+        """Moves every scenario back to its "Started" state."""
+        return self._request("DELETE", "/scenarios")
+
+    # Variables
+
+    def vars(self):
+        """Returns the variables set by responses."""
+        return self._request("GET", "/vars")
+
+    def set_vars(self, variables):
+        """Sets some variables (a dict of strings), keeping the others."""
+        return self._request("PUT", "/vars", variables)
+
+    def clear_vars(self):
+        """Removes all variables."""
+        return self._request("DELETE", "/vars")
+
+    # Fixtures
+
+    def set_fixture(self, name, body):
+        """Registers or replaces a named response body that expectations reference with bodyRef."""
+        return self._request("POST", "/fixtures", {"name": name, "body": body})
+
+    def fixtures(self):
+        """Returns the registered fixtures, keyed by name."""
+        return self._request("GET", "/fixtures")
+
+    def clear_fixtures(self):
+        """Removes all fixtures."""
+        return self._request("DELETE", "/fixtures")
+
+    # Verifications
+
+    def calls(self, full_method_name=None, trace_id=None, since=None, until=None, headers=None):
+        """Returns the recorded calls, optionally only those to one method, of one trace,
+        received from since and before until (RFC 3339 strings or Unix nano timestamps),
+        and with headers, a dict of header names to values ("" only requires the header).
+        """
+        query = []
+        if full_method_name is not None:
+            query.append(("method", full_method_name))
+        if trace_id is not None:
+            query.append(("traceId", trace_id))
+        if since is not None:
+            query.append(("since", str(since)))
+        if until is not None:
+            query.append(("until", str(until)))
+        for name, value in (headers or {}).items():
+            query.append(("header", f"{name}:{value}" if value else name))
+        path = "/verifications"
+        if query:
+            path += "?" + urllib.parse.urlencode(query)
+        return self._request("GET", path) or []
+
+    def stream_calls(self):
+        """Yields every call recorded from now on, with its response, as the mock answers it.
+
+        Blocks between calls; close the generator to stop streaming.
+        """
+        with urllib.request.urlopen(self.base_url + "/verifications/stream") as resp:
+            data = []
+            for line in resp:
+                line = line.decode().rstrip("\r\n")
+                if line.startswith("data:"):
+                    data.append(line[5:].lstrip())
+                elif not line and data:
+                    yield json.loads("\n".join(data))
+                    data = []
+
+    def clear_calls(self):
+        """Clears the recorded calls and match counts, keeping the expectations."""
+        return self._request("DELETE", "/verifications")
+
+    def unmatched(self):
+        """Returns the calls no expectation matched, with their near misses."""
+        return self._request("GET", "/unmatched") or []
+
+    def match_counts(self):
+        """Returns the number of calls matched by each expectation, keyed "method#index"."""
+        return self._request("GET", "/verifications/counts")
+
+    def satisfied(self):
+        """Returns whether each expectation's times constraint holds, keyed "method#index"."""
+        return self._request("GET", "/verifications/satisfied")
+
+    def connection_events(self):
+        """Returns the recorded connection lifecycle events."""
+        return self._request("GET", "/verifications/connections") or []
+
+    def duplicates(self):
+        """Returns the idempotency keys received more than once by expectations tracking them."""
+        return self._request("GET", "/verifications/duplicates") or []
@@ -0,0 +1,320 @@
+// Thin client for the grpcmock HTTP control API.
+//
+// Depends only on the global fetch (Node 18+, browsers), so it can be copied into
+// any Jest suite:
+//
+//   const mock = new GrpcMockClient("http://localhost:8081");
+//   await mock.addExpectation({
+//     fullMethodName: "/pkg.v1.Svc/Get",
+//     response: { body: { name: "Bob" } },
+//   });
+//   expect(await mock.calls("/pkg.v1.Svc/Get")).toHaveLength(1);
+
+export interface GRPCCallExpectation {
+  id?: string;
+  fullMethodName?: string;
+  fullMethodNameRegex?: string; // Set instead of fullMethodName
+  requestMatcher?: Record<string, unknown>;
+  response?: Record<string, unknown>;
+  responses?: Record<string, unknown>[]; // Set instead of response
+  afterResponses?: "repeatLast" | "rotate" | "error";
+  failFirst?: { count: number; code?: string | number; message?: string };
+  scenario?: string;
+  requiredState?: string;
+  newState?: string;
+  webhooks?: {
+    url: string;
+    method?: string;
+    headers?: Record<string, string>;
+    body?: unknown;
+    delayMs?: number;
+  }[];
+  times?: { exact?: number; min?: number; max?: number };
+  priority?: number;
+  [field: string]: unknown;
+}
+
+export interface RecordedGRPCCall {
+  id: number;
+  fullMethodName: string;
+  headers: Record<string, string[]>;
+  body: unknown;
+  timestamp: number; // Unix nano timestamp
+  traceId?: string;
+  response?: RecordedResponse;
+}
+
+export interface RecordedResponse {
+  expectationId?: string;
+  matched: boolean;
+  headers?: Record<string, string>;
+  body?: unknown;
+  bodies?: unknown[];
+  statusCode: string; // Canonical name, e.g. "NOT_FOUND"
+  statusMessage?: string;
+  latencyMs: number;
+}
+
+export interface CallFilter {
+  since?: string | number; // RFC 3339 time or Unix nano timestamp, inclusive
+  until?: string | number; // Exclusive
+  headers?: Record<string, string>;
+}
+
+export interface UnmatchedGRPCCall extends Omit<RecordedGRPCCall, "id" | "response"> {
+  nearMisses: Array<{
+    expectationIndex: number;
+    reason: string;
+    bodyDiff?: Array<{ field: string; reason: string; expected: unknown; actual?: unknown }>;
+    schemaErrors?: string[];
+  }>;
+}
+
+export interface ConnectionEvent {
+  type: "opened" | "closed" | "goAwaySent";
+  connectionId: number;
+  remoteAddr: string;
+  localAddr: string;
+  timestamp: number;
+}
+
+export interface DuplicateRequest {
+  fullMethodName: string;
+  key: string; // Idempotency key, or "sha256:<hex>" body hash
+  count: number;
+  firstSeen: number;
+  lastSeen: number;
+}
+
+export interface MockInfo {
+  version: string;
+  goVersion: string;
+  grpcPort: string;
+  httpPort: string;
+  startedAt: string; // RFC 3339
+  services: { name: string; file?: string; methods: string[] }[];
+  modes: string[]; // e.g. "slo", "sampling", "quotas", "reflectionFilter", "slowCallBudget"
+  fixtures: string[];
+}
+
+export class GrpcMockError extends Error {
+  readonly status: number;
+  readonly body: string;
+
+  constructor(status: number, body: string) {
+    super(`grpcmock control API returned ${status}: ${body}`);
+    this.status = status;
+    this.body = body;
+  }
+}
+
+export class GrpcMockClient {
+  private readonly baseUrl: string;
+
+  constructor(baseUrl = "http://localhost:8081") {
+    this.baseUrl = baseUrl.replace(/\/+$/, "");
+  }
+
+  private async request<T>(method: string, path: string, body?: unknown): Promise<T> {
+    const resp = await fetch(this.baseUrl + path, {
+      method,
+      headers: { "Content-Type": "application/json" },
+      body: body === undefined ? undefined : JSON.stringify(body),
+    });
+    const text = await resp.text();
+    if (!resp.ok) {
+      throw new GrpcMockError(resp.status, text);
+    }
+    return (text ? JSON.parse(text) : undefined) as T;
+  }
+
+  // Expectations
+
+  addExpectation(expectation: GRPCCallExpectation): Promise<{ message: string; id: string }> {
+    return this.request("POST", "/expectations", expectation);
+  }
+
+  /**
+   * Registers expectations atomically: all of them, or none if any is invalid.
+   * A GrpcMockError's body then lists the refused ones.
+   */
+  importExpectations(expectations: GRPCCallExpectation[]): Promise<{ message: string; ids: string[] }> {
+    return this.request("POST", "/expectations/import", expectations);
+  }
+
+  /** Returns all expectations, in the format accepted by importExpectations and stub files. */
+  exportExpectations(): Promise<GRPCCallExpectation[]> {
+    return this.request("GET", "/expectations/export");
+  }
+
+  /** Returns the expectation with the given ID. */
+  expectation(id: string): Promise<GRPCCallExpectation> {
+    return this.request("GET", `/expectations/${encodeURIComponent(id)}`);
+  }
+
+  /** Replaces the expectation with the given ID, keeping the ID; its match count starts over. */
+  replaceExpectation(id: string, expectation: GRPCCallExpectation): Promise<{ message: string; id: string }> {
+    return this.request("PUT", `/expectations/${encodeURIComponent(id)}`, expectation);
+  }
+
+  /** Removes the expectation with the given ID. */
+  removeExpectation(id: string): Promise<{ message: string; id: string }> {
+    return this.request("DELETE", `/expectations/${encodeURIComponent(id)}`);
+  }
+
+  /** Reports which recorded calls a proposed expectation would have matched, without registering it. */
+  replayCheck(expectation: GRPCCallExpectation): Promise<{
+    matchedCount: number;
+    calls: Array<{ callId: number; fullMethodName: string; matched: boolean; nearMiss?: UnmatchedGRPCCall["nearMisses"][number] }>;
+  }> {
+    return this.request("POST", "/expectations/replay-check", expectation);
+  }
+
+  expectations(): Promise<Record<string, GRPCCallExpectation[]>> {
+    return this.request("GET", "/expectations");
+  }
+
+  /** Clears all expectations, recorded calls and match counts. */
+  reset(): Promise<{ message: string }> {
+    return this.request("DELETE", "/expectations");
+  }
+
+  // Scenarios
+
+  scenarios(): Promise<Record<string, { state: string }>> {
+    return this.request("GET", "/scenarios");
+  }
+
+  setScenarioState(name: string, state: string): Promise<{ state: string }> {
+    return this.request("PUT", `/scenarios/${encodeURIComponent(name)}`, { state });
+  }
+
+  /** Moves every scenario back to its "Started" state. */
+  resetScenarios(): Promise<{ message: string }> {
+    return this.request("DELETE", "/scenarios");
+  }
+
+  // Variables
+
+  vars(): Promise<Record<string, string>> {
+    return this.request("GET", "/vars");
+  }
+
+  /** Sets some variables, keeping the others. */
+  setVars(vars: Record<string, string>): Promise<Record<string, string>> {
+    return this.request("PUT", "/vars", vars);
+  }
+
+  clearVars(): Promise<{ message: string }> {
+    return this.request("DELETE", "/vars");
+  }
+
+  // Fixtures
+
+  /** Registers or replaces a named response body that expectations reference with bodyRef. */
+  setFixture(name: string, body: Record<string, unknown>): Promise<{ message: string }> {
+    return this.request("POST", "/fixtures", { name, body });
+  }
+
+  fixtures(): Promise<Record<string, Record<string, unknown>>> {
+    return this.request("GET", "/fixtures");
+  }
+
+  clearFixtures(): Promise<{ message: string }> {
+    return this.request("DELETE", "/fixtures");
+  }
+
+  // Verifications
+
+  /** Version, ports, services, active modes and fixtures of the mock. */
+  info(): Promise<MockInfo> {
+    return this.request("GET", "/info");
+  }
+
+  /**
+   * Returns the recorded calls, optionally only those to one method, of one trace, and
+   * matching filter: received from since and before until (RFC 3339 strings or Unix nano
+   * timestamps), with headers mapping header names to values ("" only requires the header).
+   */
+  async calls(fullMethodName?: string, traceId?: string, filter: CallFilter = {}): Promise<RecordedGRPCCall[]> {
+    const params: [string, string][] = [];
+    if (fullMethodName !== undefined) {
+      params.push(["method", fullMethodName]);
+    }
+    if (traceId !== undefined) {
+      params.push(["traceId", traceId]);
+    }
+    if (filter.since !== undefined) {
+      params.push(["since", String(filter.since)]);
+    }
+    if (filter.until !== undefined) {
+      params.push(["until", String(filter.until)]);
+    }
+    for (const [name, value] of Object.entries(filter.headers ?? {})) {
+      params.push(["header", value ? `${name}:${value}` : name]);
+    }
+    const path = params.length > 0 ? `/verifications?${new URLSearchParams(params)}` : "/verifications";
+    return (await this.request<RecordedGRPCCall[] | null>("GET", path)) ?? [];
+  }
+
+  /** Clears the recorded calls and match counts, keeping the expectations. */
+  clearCalls(): Promise<{ message: string }> {
+    return this.request("DELETE", "/verifications");
+  }
+
+  /** Yields every call recorded from now on, with its response, as the mock answers it; break to stop. */
+  async *streamCalls(): AsyncGenerator<RecordedGRPCCall> {
+    const resp = await fetch(this.baseUrl + "/verifications/stream");
+    if (!resp.ok || !resp.body) {
+      throw new GrpcMockError(resp.status, await resp.text());
+    }
+    const reader = resp.body.pipeThrough(new TextDecoderStream()).getReader();
+    let buffered = "";
+    try {
+      for (;;) {
+        const { value, done } = await reader.read();
+        if (done) {
+          return;
+        }
+        buffered += value;
+        let end: number;
+        while ((end = buffered.indexOf("\n\n")) >= 0) {
+          const data = buffered
+            .slice(0, end)
+            .split("\n")
+            .filter((line) => line.startsWith("data:"))
+            .map((line) => line.slice(5).trimStart());
+          buffered = buffered.slice(end + 2);
+          if (data.length > 0) {
+            yield JSON.parse(data.join("\n")) as RecordedGRPCCall;
+          }
+        }
+      }
+    } finally {
+      await reader.cancel();
+    }
+  }
+
+  async unmatched(): Promise<UnmatchedGRPCCall[]> {
+    return (await this.request<UnmatchedGRPCCall[] | null>("GET", "/unmatched")) ?? [];
+  }
+
+  /** Number of calls matched by each expectation, keyed "method#index". */
+  matchCounts(): Promise<Record<string, number>> {
+    return this.request("GET", "/verifications/counts");
+  }
+
+  /** Whether each expectation's times constraint holds, keyed "method#index". */
+  satisfied(): Promise<Record<string, boolean>> {
+    return this.request("GET", "/verifications/satisfied");
+  }
+
+  async connectionEvents(): Promise<ConnectionEvent[]> {
+    return (await this.request<ConnectionEvent[] | null>("GET", "/verifications/connections")) ?? [];
+  }
+
+  /** Idempotency keys received more than once by expectations tracking them. */
+  async duplicates(): Promise<DuplicateRequest[]> {
+    return (await this.request<DuplicateRequest[] | null>("GET", "/verifications/duplicates")) ?? [];
+  }
+}
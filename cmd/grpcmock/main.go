@@ -0,0 +1,219 @@
+// Command grpcmock drives a running grpcmock server through its HTTP control API.
+//
+// Usage:
+//
+//	grpcmock stub apply [flags] FILE...
+//
+// stub apply registers the expectations found in the given JSON or YAML files
+// (each holding one expectation or a list of them, "-" for standard input) at
+// once, so either all of them are applied or none is, and checks that the server
+// lists them. It is meant for Kubernetes init containers
+// and compose services the system under test depends on.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+)
+
+// Exit codes.
+const (
+	exitOK      = 0
+	exitFailure = 1 // The mock was unreachable or rejected or lost an expectation
+	exitUsage   = 2
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("grpcmock: ")
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	if len(args) < 2 || args[0] != "stub" || args[1] != "apply" {
+		fmt.Fprintln(os.Stderr, "usage: grpcmock stub apply [flags] FILE...")
+		return exitUsage
+	}
+	return stubApply(args[2:])
+}
+
+// stubApply implements "grpcmock stub apply".
+func stubApply(args []string) int {
+	flags := flag.NewFlagSet("stub apply", flag.ContinueOnError)
+	url := flags.String("url", withDefault(os.Getenv("GRPCMOCK_URL"), "http://localhost:8081"), "Base URL of the mock's HTTP control API")
+	waitForReady := flags.Bool("wait-for-ready", false, "Wait for the mock to be reachable before applying")
+	timeout := flags.Duration("timeout", time.Minute, "Maximum time to wait for the mock with -wait-for-ready")
+	exitAfter := flags.Bool("exit-after", false, "Exit once the expectations are applied and verified instead of running until interrupted")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: grpcmock stub apply [flags] FILE...")
+		return exitUsage
+	}
+
+	var exps []runtime.GRPCCallExpectation
+	for _, path := range flags.Args() {
+		fileExps, err := readExpectations(path)
+		if err != nil {
+			log.Print(err)
+			return exitUsage
+		}
+		exps = append(exps, fileExps...)
+	}
+
+	c := &client{baseURL: *url, http: &http.Client{Timeout: 10 * time.Second}}
+	if *waitForReady {
+		if err := c.waitForReady(*timeout); err != nil {
+			log.Print(err)
+			return exitFailure
+		}
+	}
+	ids, err := c.importExpectations(exps)
+	if err != nil {
+		log.Print(err)
+		return exitFailure
+	}
+	for i := range exps {
+		exps[i].ID = ids[i]
+	}
+	if err := c.verify(exps); err != nil {
+		log.Print(err)
+		return exitFailure
+	}
+	log.Printf("applied %d expectation(s) to %s", len(exps), *url)
+
+	if !*exitAfter {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		<-ctx.Done()
+	}
+	return exitOK
+}
+
+// readExpectations reads a file holding one expectation or an array of them, in
+// JSON, or YAML documents each holding one expectation or a list of them.
+func readExpectations(path string) ([]runtime.GRPCCallExpectation, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	exps, err := runtime.ParseExpectations(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode expectations in %s: %w", path, err)
+	}
+	return exps, nil
+}
+
+// client calls the mock's HTTP control API.
+type client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// waitForReady polls the control API until it answers or the timeout expires.
+func (c *client) waitForReady(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		_, err := c.expectations()
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("mock at %s not ready after %v: %w", c.baseURL, timeout, err)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// importExpectations registers expectations atomically, returning their IDs.
+func (c *client) importExpectations(exps []runtime.GRPCCallExpectation) ([]string, error) {
+	body, err := json.Marshal(exps)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode expectations: %w", err)
+	}
+	resp, err := c.http.Post(c.baseURL+"/expectations/import", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to import expectations: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		msg, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("mock rejected the expectations, none applied: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	var imported struct {
+		IDs []string `json:"ids"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&imported); err != nil {
+		return nil, fmt.Errorf("failed to decode import response: %w", err)
+	}
+	if len(imported.IDs) != len(exps) {
+		return nil, fmt.Errorf("mock imported %d expectation(s), want %d", len(imported.IDs), len(exps))
+	}
+	return imported.IDs, nil
+}
+
+// expectations returns the expectations registered in the mock.
+func (c *client) expectations() (map[string][]runtime.GRPCCallExpectation, error) {
+	resp, err := c.http.Get(c.baseURL + "/expectations")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(resp.Status)
+	}
+	var exps map[string][]runtime.GRPCCallExpectation
+	if err := json.NewDecoder(resp.Body).Decode(&exps); err != nil {
+		return nil, err
+	}
+	return exps, nil
+}
+
+// verify checks that the mock lists every applied expectation.
+func (c *client) verify(applied []runtime.GRPCCallExpectation) error {
+	registered, err := c.expectations()
+	if err != nil {
+		return fmt.Errorf("failed to list expectations: %w", err)
+	}
+	for _, exp := range applied {
+		want, _ := json.Marshal(exp)
+		found := false
+		for _, reg := range registered[runtime.ExpectationKey(exp)] {
+			if got, _ := json.Marshal(reg); bytes.Equal(got, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("expectation for %s missing from the mock after applying it", runtime.ExpectationKey(exp))
+		}
+	}
+	return nil
+}
+
+func withDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
@@ -3,8 +3,14 @@ module github.com/rbroggi/grpcmock
 go 1.24
 
 require (
+	github.com/fsnotify/fsnotify v1.8.0
+	go.starlark.net v0.0.0-20250417143717-f57e51f710eb
+	golang.org/x/net v0.35.0
+	golang.org/x/text v0.22.0
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250218202821-56aae31c358a
 	google.golang.org/grpc v1.72.1
 	google.golang.org/protobuf v1.36.6
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require golang.org/x/sys v0.30.0 // indirect
@@ -0,0 +1,226 @@
+// Package alert posts the alerts of expectations that look broken to their webhooks.
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+)
+
+// defaultWindow is the window of AlertMock.UnmatchedCalls when WindowMs is unset.
+const defaultWindow = time.Minute
+
+// storeInterface defines the storage methods needed to watch expectations.
+type storeInterface interface {
+	OnExpectationAdded(fn func(runtime.GRPCCallExpectation))
+	OnUnmatchedRecorded(fn func(runtime.UnmatchedGRPCCall))
+	MatchCountByID(id string) (int, bool)
+	Clock() runtime.Clock
+}
+
+// Alerter watches the expectations with an Alert and posts to their webhooks.
+type Alerter struct {
+	store   storeInterface
+	client  *http.Client
+	mu      sync.Mutex
+	traffic map[string]*trafficWatch // Expectations with an unmatched traffic alert, by ID
+	wake    chan struct{}            // Signals unmatched calls to check
+}
+
+// trafficWatch tracks the unmatched calls to the methods an expectation covers.
+type trafficWatch struct {
+	exp    runtime.GRPCCallExpectation
+	re     *regexp.Regexp // Compiled FullMethodNameRegex, if set
+	window time.Duration
+	// recent holds the timestamps of the latest unmatched calls by method, at
+	// most UnmatchedCalls of them: the alert condition holds when there are
+	// that many and the oldest is within the window.
+	recent  map[string][]int64
+	pending map[string]bool // Methods with unmatched calls not checked yet
+	sent    time.Time       // Last alert
+}
+
+// New creates an Alerter watching the expectations added to the store. Unmatched
+// traffic is counted as calls are recorded, and checked in the background.
+func New(store storeInterface) *Alerter {
+	a := &Alerter{
+		store:   store,
+		client:  &http.Client{Timeout: 5 * time.Second},
+		traffic: make(map[string]*trafficWatch),
+		wake:    make(chan struct{}, 1),
+	}
+	store.OnExpectationAdded(a.watchMatches)
+	store.OnExpectationAdded(a.watchTraffic)
+	store.OnUnmatchedRecorded(a.recordUnmatched)
+	go a.checkUnmatchedTraffic()
+	return a
+}
+
+// watchMatches alerts if the expectation has matched no call UnmatchedForMs after
+// becoming active, i.e. after its registration or its schedule's activeFrom.
+func (a *Alerter) watchMatches(exp runtime.GRPCCallExpectation) {
+	if exp.Alert == nil || exp.Alert.UnmatchedForMs <= 0 {
+		return
+	}
+	clock := a.store.Clock()
+	wait := time.Duration(exp.Alert.UnmatchedForMs) * time.Millisecond
+	if exp.Schedule != nil && exp.Schedule.ActiveFrom != nil {
+		if d := exp.Schedule.ActiveFrom.Sub(clock.Now()); d > 0 {
+			wait += d
+		}
+	}
+	go func() {
+		<-clock.After(wait)
+		// Expectations removed in the meantime are not alerted on.
+		if n, ok := a.store.MatchCountByID(exp.ID); ok && n == 0 {
+			a.post(exp.Alert.WebhookURL, runtime.Alert{
+				Type:          runtime.AlertNeverMatched,
+				ExpectationID: exp.ID,
+				Message:       fmt.Sprintf("expectation %s matched no call within %dms of becoming active", exp.ID, exp.Alert.UnmatchedForMs),
+				Timestamp:     clock.Now().UnixNano(),
+			})
+		}
+	}()
+}
+
+// watchTraffic starts counting the unmatched calls to the methods of an
+// expectation with an unmatched traffic alert, replacing any earlier
+// expectation with the same ID.
+func (a *Alerter) watchTraffic(exp runtime.GRPCCallExpectation) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if exp.Alert == nil || exp.Alert.UnmatchedCalls <= 0 {
+		delete(a.traffic, exp.ID)
+		return
+	}
+	w := &trafficWatch{exp: exp, window: defaultWindow, recent: make(map[string][]int64), pending: make(map[string]bool)}
+	if exp.Alert.WindowMs > 0 {
+		w.window = time.Duration(exp.Alert.WindowMs) * time.Millisecond
+	}
+	if exp.FullMethodNameRegex != "" {
+		w.re, _ = regexp.Compile(exp.FullMethodNameRegex) // Validated at registration
+	}
+	a.traffic[exp.ID] = w
+}
+
+// recordUnmatched counts an unmatched call for the expectations covering its
+// method and wakes checkUnmatchedTraffic, without waiting for it.
+func (a *Alerter) recordUnmatched(call runtime.UnmatchedGRPCCall) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, w := range a.traffic {
+		if !w.covers(call.FullMethodName) {
+			continue
+		}
+		recent := append(w.recent[call.FullMethodName], call.Timestamp)
+		if over := len(recent) - w.exp.Alert.UnmatchedCalls; over > 0 {
+			recent = append(recent[:0], recent[over:]...)
+		}
+		w.recent[call.FullMethodName] = recent
+		w.pending[call.FullMethodName] = true
+	}
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+}
+
+// checkUnmatchedTraffic alerts for the expectations covering the method of
+// unmatched calls if their method received UnmatchedCalls unmatched calls
+// within their window, at most once per window. It runs until the process exits.
+func (a *Alerter) checkUnmatchedTraffic() {
+	for range a.wake {
+		a.forgetRemoved()
+		now := a.store.Clock().Now()
+		var alerts []alertTo
+		a.mu.Lock()
+		for _, w := range a.traffic {
+			for method := range w.pending {
+				delete(w.pending, method)
+				recent := w.recent[method]
+				if len(recent) < w.exp.Alert.UnmatchedCalls || recent[0] < now.Add(-w.window).UnixNano() {
+					continue
+				}
+				if !w.sent.IsZero() && now.Sub(w.sent) < w.window {
+					continue
+				}
+				w.sent = now
+				alerts = append(alerts, alertTo{url: w.exp.Alert.WebhookURL, alert: runtime.Alert{
+					Type:           runtime.AlertUnmatchedTraffic,
+					ExpectationID:  w.exp.ID,
+					FullMethodName: method,
+					Message:        fmt.Sprintf("%d calls to %s matched no expectation within %s", len(recent), method, w.window),
+					UnmatchedCalls: len(recent),
+					Timestamp:      now.UnixNano(),
+				}})
+			}
+		}
+		a.mu.Unlock()
+		for _, t := range alerts {
+			a.post(t.url, t.alert)
+		}
+	}
+}
+
+// alertTo is an alert and the webhook to post it to.
+type alertTo struct {
+	url   string
+	alert runtime.Alert
+}
+
+// forgetRemoved stops watching the traffic of the expectations no longer stored.
+func (a *Alerter) forgetRemoved() {
+	a.mu.Lock()
+	watched := make(map[string]*trafficWatch, len(a.traffic))
+	for id, w := range a.traffic {
+		watched[id] = w
+	}
+	a.mu.Unlock()
+	for id, w := range watched {
+		if _, ok := a.store.MatchCountByID(id); ok {
+			continue
+		}
+		a.mu.Lock()
+		if a.traffic[id] == w { // Not replaced in the meantime
+			delete(a.traffic, id)
+		}
+		a.mu.Unlock()
+	}
+}
+
+// covers reports whether the watched expectation applies to a method.
+func (w *trafficWatch) covers(fullMethodName string) bool {
+	if w.re != nil {
+		return w.re.MatchString(fullMethodName)
+	}
+	return w.exp.FullMethodName == fullMethodName ||
+		(runtime.IsServiceWildcard(w.exp.FullMethodName) && runtime.ServiceWildcard(fullMethodName) == w.exp.FullMethodName)
+}
+
+// post sends an alert to a webhook in the background, logging failures.
+func (a *Alerter) post(url string, alert runtime.Alert) {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		log.Printf("grpcmockruntime: failed to encode %s alert for %s: %v", alert.Type, alert.ExpectationID, err)
+		return
+	}
+	log.Printf("grpcmockruntime: alert: %s", alert.Message)
+	go func() {
+		resp, err := a.client.Post(url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			log.Printf("grpcmockruntime: failed to post %s alert for %s: %v", alert.Type, alert.ExpectationID, err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			log.Printf("grpcmockruntime: %s alert webhook for %s answered %s", alert.Type, alert.ExpectationID, resp.Status)
+		}
+	}()
+}
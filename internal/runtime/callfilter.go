@@ -0,0 +1,95 @@
+package runtime
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CallFilter selects recorded calls; its zero value selects all of them.
+type CallFilter struct {
+	FullMethodName string
+	TraceID        string
+	// Since and Until bound the calls' Unix nano timestamps, Since inclusive and
+	// Until exclusive; 0 leaves them unbounded.
+	Since int64
+	Until int64
+	// Headers maps lowercase header names to a value the calls must have for
+	// them, or to "" if they must only have the header.
+	Headers map[string]string
+}
+
+// ParseCallFilter reads a filter from the query parameters method, traceId,
+// since and until (RFC 3339 times or Unix nano timestamps) and header, repeatable
+// and given as "name:value" or "name".
+func ParseCallFilter(query url.Values) (CallFilter, error) {
+	f := CallFilter{
+		FullMethodName: query.Get("method"),
+		TraceID:        strings.ToLower(query.Get("traceId")),
+	}
+	var err error
+	if f.Since, err = parseCallTime(query.Get("since")); err != nil {
+		return CallFilter{}, fmt.Errorf("since: %w", err)
+	}
+	if f.Until, err = parseCallTime(query.Get("until")); err != nil {
+		return CallFilter{}, fmt.Errorf("until: %w", err)
+	}
+	for _, h := range query["header"] {
+		name, value, _ := strings.Cut(h, ":")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			return CallFilter{}, fmt.Errorf("header %q: name is required", h)
+		}
+		if f.Headers == nil {
+			f.Headers = make(map[string]string)
+		}
+		f.Headers[name] = strings.TrimSpace(value)
+	}
+	return f, nil
+}
+
+// parseCallTime parses an RFC 3339 time or a Unix nano timestamp, "" as 0.
+func parseCallTime(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if ns, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return ns, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is neither an RFC 3339 time nor a Unix nano timestamp", s)
+	}
+	return t.UnixNano(), nil
+}
+
+// IsZero reports whether the filter selects all calls.
+func (f *CallFilter) IsZero() bool {
+	return f.FullMethodName == "" && f.TraceID == "" && f.Since == 0 && f.Until == 0 && len(f.Headers) == 0
+}
+
+// Matches reports whether the filter selects the call.
+func (f *CallFilter) Matches(call *RecordedGRPCCall) bool {
+	if f.FullMethodName != "" && call.FullMethodName != f.FullMethodName {
+		return false
+	}
+	if f.TraceID != "" && !strings.EqualFold(call.TraceID, f.TraceID) {
+		return false
+	}
+	if f.Since != 0 && call.Timestamp < f.Since {
+		return false
+	}
+	if f.Until != 0 && call.Timestamp >= f.Until {
+		return false
+	}
+	for name, value := range f.Headers {
+		values := call.Headers.Get(name)
+		if len(values) == 0 || (value != "" && !slices.Contains(values, value)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,19 @@
+package runtime
+
+import "time"
+
+// Clock abstracts the passage of time so that time-dependent behavior
+// (schedules, operation lifecycles, delays) can be driven by a fake clock in tests.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock is the Clock backed by the time package.
+type SystemClock struct{}
+
+// Now returns the current local time.
+func (SystemClock) Now() time.Time { return time.Now() }
+
+// After waits for the duration to elapse and then sends the current time on the returned channel.
+func (SystemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
@@ -0,0 +1,16 @@
+package runtime
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc/encoding"
+	_ "google.golang.org/grpc/encoding/gzip" // Makes gzip available to Compression
+)
+
+// ValidateCompression checks that Compression names a registered compressor or identity.
+func (r *MockResponse) ValidateCompression() error {
+	if r.Compression == "" || r.Compression == encoding.Identity || encoding.GetCompressor(r.Compression) != nil {
+		return nil
+	}
+	return fmt.Errorf("unknown compression %q, want %q or a registered compressor such as \"gzip\"", r.Compression, encoding.Identity)
+}
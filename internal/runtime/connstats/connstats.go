@@ -0,0 +1,108 @@
+package connstats
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+	_ "google.golang.org/grpc/encoding/gzip" // Accept gzip-compressed requests
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+)
+
+// CompressionHeader is the metadata key under which WithCompression exposes the
+// compression algorithm of a call's requests; gRPC strips it from the incoming metadata.
+const CompressionHeader = "grpc-encoding"
+
+// storeInterface defines the methods for recording connection events.
+type storeInterface interface {
+	RecordConnectionEvent(ev runtime.ConnectionEvent)
+}
+
+type connKey struct{}
+
+type rpcKey struct{}
+
+// rpcInfo holds what the handler learns about an RPC before the method handler runs.
+type rpcInfo struct {
+	compression string
+}
+
+// Handler is a gRPC stats.Handler recording connection lifecycle events.
+type Handler struct {
+	Store  storeInterface
+	nextID atomic.Uint64
+	mu     sync.Mutex
+	open   map[uint64]runtime.ConnectionEvent // Open connections, keyed by ID
+}
+
+// New creates a Handler recording into the given store.
+func New(store storeInterface) *Handler {
+	return &Handler{Store: store, open: make(map[uint64]runtime.ConnectionEvent)}
+}
+
+// TagConn assigns an ID to a new connection.
+func (h *Handler) TagConn(ctx context.Context, info *stats.ConnTagInfo) context.Context {
+	ev := runtime.ConnectionEvent{ConnectionID: h.nextID.Add(1)}
+	if info.RemoteAddr != nil {
+		ev.RemoteAddr = info.RemoteAddr.String()
+	}
+	if info.LocalAddr != nil {
+		ev.LocalAddr = info.LocalAddr.String()
+	}
+	return context.WithValue(ctx, connKey{}, ev)
+}
+
+// HandleConn records the beginning and end of a connection.
+func (h *Handler) HandleConn(ctx context.Context, s stats.ConnStats) {
+	ev, ok := ctx.Value(connKey{}).(runtime.ConnectionEvent)
+	if !ok {
+		return
+	}
+	h.mu.Lock()
+	switch s.(type) {
+	case *stats.ConnBegin:
+		ev.Type = runtime.ConnectionEventOpened
+		h.open[ev.ConnectionID] = ev
+	case *stats.ConnEnd:
+		ev.Type = runtime.ConnectionEventClosed
+		delete(h.open, ev.ConnectionID)
+	default:
+		h.mu.Unlock()
+		return
+	}
+	h.mu.Unlock()
+	h.Store.RecordConnectionEvent(ev)
+}
+
+// TagRPC attaches the RPC's info, filled in by HandleRPC.
+func (h *Handler) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return context.WithValue(ctx, rpcKey{}, &rpcInfo{})
+}
+
+// HandleRPC records the compression algorithm of incoming requests.
+func (h *Handler) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	if in, ok := s.(*stats.InHeader); ok && !in.IsClient() {
+		if info, ok := ctx.Value(rpcKey{}).(*rpcInfo); ok {
+			info.compression = in.Compression
+		}
+	}
+}
+
+// WithCompression returns md with the compression algorithm of the call's requests
+// ("identity" if uncompressed) set under CompressionHeader. md is returned unchanged
+// if the call was not tagged by a Handler.
+func WithCompression(ctx context.Context, md metadata.MD) metadata.MD {
+	info, ok := ctx.Value(rpcKey{}).(*rpcInfo)
+	if !ok {
+		return md
+	}
+	compression := info.compression
+	if compression == "" {
+		compression = "identity"
+	}
+	md = md.Copy()
+	md.Set(CompressionHeader, compression)
+	return md
+}
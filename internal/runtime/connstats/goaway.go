@@ -0,0 +1,127 @@
+package connstats
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+	"golang.org/x/net/http2"
+)
+
+// http2FrameHeaderLen is the length of the header of HTTP/2 frames.
+const http2FrameHeaderLen = 9
+
+// maxGoAwayDebugData bounds the debug data of a GOAWAY frame kept in its event.
+const maxGoAwayDebugData = 256
+
+// Listener wraps the gRPC server's listener so that the GOAWAY frames the
+// server sends, when it shuts down, enforces keepalive or ends connections over
+// their maximum age, are recorded. It follows the HTTP/2 frames of plaintext
+// connections only.
+func (h *Handler) Listener(lis net.Listener) net.Listener {
+	return &listener{Listener: lis, h: h}
+}
+
+type listener struct {
+	net.Listener
+	h *Handler
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: c, h: l.h}, nil
+}
+
+// conn follows the HTTP/2 frames the server writes on a connection to spot the
+// first GOAWAY frame.
+type conn struct {
+	net.Conn
+	h         *Handler
+	mu        sync.Mutex
+	header    [http2FrameHeaderLen]byte
+	headerLen int    // Bytes of the current frame header written so far
+	remaining int    // Payload bytes of the current frame still to be written
+	goAway    bool   // Whether the current frame is a GOAWAY frame
+	payload   []byte // Payload of the current GOAWAY frame written so far
+	done      bool   // Whether a GOAWAY was recorded, or frames can no longer be followed
+}
+
+func (c *conn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.mu.Lock()
+	detail, sent := c.follow(p[:n])
+	c.mu.Unlock()
+	if sent {
+		c.h.goAwaySent(c.RemoteAddr(), detail)
+	}
+	return n, err
+}
+
+// follow advances through the frames in p and returns the detail of the first
+// GOAWAY frame once it is completely written. The caller must hold mu.
+func (c *conn) follow(p []byte) (detail string, sent bool) {
+	for len(p) > 0 && !c.done {
+		if c.headerLen < http2FrameHeaderLen {
+			n := copy(c.header[c.headerLen:], p)
+			c.headerLen += n
+			p = p[n:]
+			if c.headerLen < http2FrameHeaderLen {
+				break
+			}
+			c.remaining = int(c.header[0])<<16 | int(c.header[1])<<8 | int(c.header[2])
+			c.goAway = http2.FrameType(c.header[3]) == http2.FrameGoAway
+			c.payload = c.payload[:0]
+		}
+		n := min(c.remaining, len(p))
+		if c.goAway {
+			c.payload = append(c.payload, p[:min(n, 8+maxGoAwayDebugData-len(c.payload))]...)
+		}
+		c.remaining -= n
+		p = p[n:]
+		if c.remaining == 0 {
+			c.headerLen = 0
+			if c.goAway {
+				c.done = true
+				return goAwayDetail(c.payload), true
+			}
+		}
+	}
+	return "", false
+}
+
+// goAwayDetail describes a GOAWAY frame by its error code and debug data, e.g.
+// "ENHANCE_YOUR_CALM: too_many_pings".
+func goAwayDetail(payload []byte) string {
+	if len(payload) < 8 {
+		return ""
+	}
+	detail := http2.ErrCode(binary.BigEndian.Uint32(payload[4:8])).String()
+	if debug := payload[8:]; len(debug) > 0 {
+		detail += ": " + string(debug)
+	}
+	return detail
+}
+
+// goAwaySent records a GOAWAY sent on the open connection with the given remote address.
+func (h *Handler) goAwaySent(remote net.Addr, detail string) {
+	h.mu.Lock()
+	var ev runtime.ConnectionEvent
+	found := false
+	for _, open := range h.open {
+		if open.RemoteAddr == remote.String() {
+			ev, found = open, true
+			break
+		}
+	}
+	h.mu.Unlock()
+	if !found {
+		return
+	}
+	ev.Type = runtime.ConnectionEventGoAway
+	ev.Detail = detail
+	h.Store.RecordConnectionEvent(ev)
+}
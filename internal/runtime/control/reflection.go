@@ -0,0 +1,132 @@
+package control
+
+import (
+	"strings"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+	v1reflectiongrpc "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	v1alphareflectiongrpc "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// reflectionStore defines the storage methods needed by the reflection service.
+type reflectionStore interface {
+	GetReflection() runtime.Reflection
+}
+
+// RegisterReflection registers the v1 and v1alpha gRPC reflection services on the
+// server, advertising the services and methods selected by the store's Reflection.
+// A service is advertised if any of its methods is; reflection itself always is.
+func RegisterReflection(s *grpc.Server, store reflectionStore) {
+	opts := reflection.ServerOptions{
+		Services:           &advertisedServices{server: s, store: store},
+		DescriptorResolver: &advertisedFiles{store: store},
+	}
+	v1alphareflectiongrpc.RegisterServerReflectionServer(s, reflection.NewServer(opts))
+	v1reflectiongrpc.RegisterServerReflectionServer(s, reflection.NewServerV1(opts))
+}
+
+// isReflectionService reports whether a service is one of the reflection services.
+func isReflectionService(name string) bool {
+	return strings.HasPrefix(name, "grpc.reflection.")
+}
+
+// advertisedServices lists the services of the server with an advertised method.
+type advertisedServices struct {
+	server *grpc.Server
+	store  reflectionStore
+}
+
+// GetServiceInfo implements reflection.ServiceInfoProvider.
+func (a *advertisedServices) GetServiceInfo() map[string]grpc.ServiceInfo {
+	advertised := a.store.GetReflection()
+	infos := make(map[string]grpc.ServiceInfo)
+	for name, info := range a.server.GetServiceInfo() {
+		if isReflectionService(name) {
+			infos[name] = info
+			continue
+		}
+		methods := make([]grpc.MethodInfo, 0, len(info.Methods))
+		for _, m := range info.Methods {
+			if advertised.Advertises("/" + name + "/" + m.Name) {
+				methods = append(methods, m)
+			}
+		}
+		if len(methods) > 0 {
+			info.Methods = methods
+			infos[name] = info
+		}
+	}
+	return infos
+}
+
+// advertisedFiles resolves file descriptors from the global registry, stripping
+// the methods that are not advertised from the services they declare.
+type advertisedFiles struct {
+	store reflectionStore
+}
+
+// FindFileByPath implements protodesc.Resolver.
+func (a *advertisedFiles) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	fd, err := protoregistry.GlobalFiles.FindFileByPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return a.filter(fd)
+}
+
+// FindDescriptorByName implements protodesc.Resolver. Hidden services and methods are not found.
+func (a *advertisedFiles) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(name)
+	if err != nil {
+		return nil, err
+	}
+	fd, err := a.filter(d.ParentFile())
+	if err != nil {
+		return nil, err
+	}
+	files := new(protoregistry.Files)
+	if err := files.RegisterFile(fd); err != nil {
+		return nil, err
+	}
+	return files.FindDescriptorByName(name)
+}
+
+// filter returns fd rebuilt without its hidden methods, and without the services
+// left empty. Files declaring no services are returned as they are.
+func (a *advertisedFiles) filter(fd protoreflect.FileDescriptor) (protoreflect.FileDescriptor, error) {
+	advertised := a.store.GetReflection()
+	if advertised.Methods == nil || fd.Services().Len() == 0 {
+		return fd, nil
+	}
+	fdp := protodesc.ToFileDescriptorProto(fd)
+	services := make([]*descriptorpb.ServiceDescriptorProto, 0, len(fdp.Service))
+	for _, sd := range fdp.Service {
+		fullName := sd.GetName()
+		if fdp.GetPackage() != "" {
+			fullName = fdp.GetPackage() + "." + fullName
+		}
+		if isReflectionService(fullName) {
+			services = append(services, sd)
+			continue
+		}
+		methods := make([]*descriptorpb.MethodDescriptorProto, 0, len(sd.Method))
+		for _, md := range sd.Method {
+			if advertised.Advertises("/" + fullName + "/" + md.GetName()) {
+				methods = append(methods, md)
+			}
+		}
+		if len(methods) > 0 {
+			sd.Method = methods
+			services = append(services, sd)
+		}
+	}
+	fdp.Service = services
+	// Resolve the imports through a as well, so they are filtered too.
+	return protodesc.NewFile(fdp, a)
+}
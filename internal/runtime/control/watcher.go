@@ -0,0 +1,97 @@
+package control
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// WatcherServiceName is the gRPC service streaming expectation changes.
+// See proto/grpcmock/control/v1/watcher.proto for its definition.
+const WatcherServiceName = "grpcmock.control.v1.ExpectationWatcher"
+
+// storeInterface defines the storage methods needed by the control services.
+type storeInterface interface {
+	SubscribeExpectations() (<-chan runtime.ExpectationEvent, func())
+}
+
+// ExpectationWatcherServer is the server API for the ExpectationWatcher service.
+type ExpectationWatcherServer interface {
+	Watch(req *emptypb.Empty, stream grpc.ServerStream) error
+}
+
+// watcher streams expectation events from the store to subscribers.
+type watcher struct {
+	store storeInterface
+}
+
+// RegisterExpectationWatcher registers the read-only ExpectationWatcher service on the gRPC server.
+func RegisterExpectationWatcher(s grpc.ServiceRegistrar, store storeInterface) {
+	s.RegisterService(&watcherServiceDesc, &watcher{store: store})
+}
+
+// Watch sends a snapshot of the registered expectations followed by every change,
+// each as a google.protobuf.Struct holding a runtime.ExpectationEvent, until the client cancels.
+func (w *watcher) Watch(_ *emptypb.Empty, stream grpc.ServerStream) error {
+	events, cancel := w.store.SubscribeExpectations()
+	defer cancel()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case ev, ok := <-events:
+			if !ok {
+				return nil
+			}
+			msg, err := eventToStruct(ev)
+			if err != nil {
+				log.Printf("grpcmockruntime: failed to convert %s expectation event: %v", ev.Type, err)
+				continue
+			}
+			if err := stream.SendMsg(msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// eventToStruct converts an event to its JSON form as a google.protobuf.Struct.
+func eventToStruct(ev runtime.ExpectationEvent) (*structpb.Struct, error) {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return nil, err
+	}
+	msg := &structpb.Struct{}
+	if err := protojson.Unmarshal(b, msg); err != nil {
+		return nil, fmt.Errorf("failed to convert event to struct: %w", err)
+	}
+	return msg, nil
+}
+
+func watchHandler(srv interface{}, stream grpc.ServerStream) error {
+	in := new(emptypb.Empty)
+	if err := stream.RecvMsg(in); err != nil {
+		return err
+	}
+	return srv.(ExpectationWatcherServer).Watch(in, stream)
+}
+
+var watcherServiceDesc = grpc.ServiceDesc{
+	ServiceName: WatcherServiceName,
+	HandlerType: (*ExpectationWatcherServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       watchHandler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "grpcmock/control/v1/watcher.proto",
+}
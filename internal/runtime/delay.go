@@ -0,0 +1,128 @@
+package runtime
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rbroggi/grpcmock/internal/runtime/latency"
+)
+
+// DelayMock delays a response, to test client timeouts and retries: by FixedMs,
+// by a uniformly random duration between MinMs and MaxMs, or by one drawn from
+// a latency distribution, so load tests see realistic tail latencies.
+type DelayMock struct {
+	FixedMs int64 `json:"fixedMs,omitempty"`
+	MinMs   int64 `json:"minMs,omitempty"`
+	MaxMs   int64 `json:"maxMs,omitempty"`
+	// LogNormal draws the delay from a log-normal distribution.
+	LogNormal *LogNormalDelay `json:"logNormal,omitempty"`
+	// Percentiles draws the delay from a percentile table mapping percentiles
+	// ("50", "p99", "99.9") to delays in milliseconds, e.g. {"p50": 20, "p99": 250}.
+	// Delays between percentiles are interpolated linearly; add "p0" and "p100"
+	// to bound the fastest and slowest calls, which otherwise take the first and last delays.
+	Percentiles map[string]float64 `json:"percentiles,omitempty"`
+}
+
+// LogNormalDelay is a log-normal distribution given by its median and the
+// standard deviation of its logarithm; 0.5 to 1 gives typical service tails.
+type LogNormalDelay struct {
+	MedianMs float64 `json:"medianMs"`
+	Sigma    float64 `json:"sigma"`
+}
+
+// delayRand draws random delays; math/rand.Rand is not safe for concurrent use.
+var (
+	delayRandMu sync.Mutex
+	delayRand   = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// Validate checks that the delay sets exactly one valid kind of delay.
+func (d *DelayMock) Validate() error {
+	if d.FixedMs < 0 || d.MinMs < 0 || d.MaxMs < 0 {
+		return fmt.Errorf("delays must not be negative")
+	}
+	kinds := 0
+	if d.FixedMs > 0 {
+		kinds++
+	}
+	if d.MinMs > 0 || d.MaxMs > 0 {
+		kinds++
+	}
+	if d.LogNormal != nil {
+		kinds++
+	}
+	if d.Percentiles != nil {
+		kinds++
+	}
+	if kinds > 1 {
+		return fmt.Errorf("only one of fixedMs, minMs/maxMs, logNormal and percentiles can be set")
+	}
+	if d.MinMs > d.MaxMs {
+		return fmt.Errorf("minMs %d exceeds maxMs %d", d.MinMs, d.MaxMs)
+	}
+	if d.LogNormal != nil && (d.LogNormal.MedianMs < 0 || d.LogNormal.Sigma < 0) {
+		return fmt.Errorf("logNormal medianMs and sigma must not be negative")
+	}
+	_, err := d.table()
+	return err
+}
+
+// table returns the parsed Percentiles.
+func (d *DelayMock) table() (latency.Table, error) {
+	table := make(latency.Table, 0, len(d.Percentiles))
+	for key, ms := range d.Percentiles {
+		p, err := strconv.ParseFloat(strings.TrimPrefix(key, "p"), 64)
+		if err != nil || p < 0 || p > 100 {
+			return nil, fmt.Errorf("invalid percentile %q, want a number from 0 to 100", key)
+		}
+		if ms < 0 {
+			return nil, fmt.Errorf("delay of percentile %q must not be negative", key)
+		}
+		table = append(table, latency.Percentile{P: p, Latency: time.Duration(ms * float64(time.Millisecond))})
+	}
+	sort.Slice(table, func(i, j int) bool { return table[i].P < table[j].P })
+	for i := 1; i < len(table); i++ {
+		if table[i].Latency < table[i-1].Latency {
+			return nil, fmt.Errorf("percentile delays must not decrease")
+		}
+		if table[i].P == table[i-1].P {
+			return nil, fmt.Errorf("percentile %v is given twice", table[i].P)
+		}
+	}
+	return table, nil
+}
+
+// Sample draws the duration of a delay; a nil delay is zero.
+func (d *DelayMock) Sample() time.Duration {
+	if d == nil {
+		return 0
+	}
+	switch {
+	case d.LogNormal != nil:
+		dist := latency.LogNormal{Median: time.Duration(d.LogNormal.MedianMs * float64(time.Millisecond)), Sigma: d.LogNormal.Sigma}
+		delayRandMu.Lock()
+		defer delayRandMu.Unlock()
+		return dist.Sample(delayRand)
+	case d.Percentiles != nil:
+		table, err := d.table()
+		if err != nil {
+			return 0
+		}
+		delayRandMu.Lock()
+		defer delayRandMu.Unlock()
+		return table.Sample(delayRand)
+	case d.MaxMs > d.MinMs:
+		delayRandMu.Lock()
+		defer delayRandMu.Unlock()
+		return time.Duration(d.MinMs+delayRand.Int63n(d.MaxMs-d.MinMs+1)) * time.Millisecond
+	case d.MaxMs > 0:
+		return time.Duration(d.MaxMs) * time.Millisecond
+	default:
+		return time.Duration(d.FixedMs) * time.Millisecond
+	}
+}
@@ -0,0 +1,56 @@
+package runtime
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExecMock builds the response body by running an executable, for logic too
+// dynamic to stub declaratively. The executable receives the protojson request
+// on stdin and writes the JSON response body to stdout. It runs with an
+// environment holding only PATH, Env and GRPCMOCK_FULL_METHOD_NAME and
+// GRPCMOCK_HEADERS (the request headers as a JSON object), in the temporary directory.
+type ExecMock struct {
+	Command   string            `json:"command"` // Path of the executable, which must be allowed by the mock server
+	Args      []string          `json:"args,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+	TimeoutMs int64             `json:"timeoutMs,omitempty"` // Default 5000
+}
+
+// ValidateExec checks that the executables of the expectation's responses are
+// among the allowed commands.
+func (e *GRPCCallExpectation) ValidateExec(allowed []string) error {
+	for _, resp := range e.allResponses() {
+		if resp.Exec == nil {
+			continue
+		}
+		if resp.Exec.TimeoutMs < 0 {
+			return fmt.Errorf("exec: timeoutMs must not be negative")
+		}
+		if !resp.Exec.AllowedBy(allowed) {
+			return fmt.Errorf("exec: command %q is not allowed by the mock server's -exec-commands", resp.Exec.Command)
+		}
+	}
+	return nil
+}
+
+// AllowedBy reports whether the executable is one of the allowed commands.
+func (m *ExecMock) AllowedBy(allowed []string) bool {
+	for _, c := range allowed {
+		if c == m.Command {
+			return c != ""
+		}
+	}
+	return false
+}
+
+// ParseExecCommands parses a comma-separated list of allowed executable paths.
+func ParseExecCommands(s string) []string {
+	var commands []string
+	for _, c := range strings.Split(s, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			commands = append(commands, c)
+		}
+	}
+	return commands
+}
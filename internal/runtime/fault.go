@@ -0,0 +1,17 @@
+package runtime
+
+import "fmt"
+
+// ValidateFault checks that FaultPercentage is a percentage of the calls getting Error.
+func (r *MockResponse) ValidateFault() error {
+	if r.FaultPercentage == 0 {
+		return nil
+	}
+	if r.FaultPercentage < 0 || r.FaultPercentage > 100 {
+		return fmt.Errorf("faultPercentage must be between 0 and 100")
+	}
+	if r.Error == nil {
+		return fmt.Errorf("faultPercentage requires error")
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// LoadFixtures reads a JSON fixtures file, an object mapping fixture names to
+// their bodies, e.g. {"standardCustomer": {"id": "123", "name": "Ada"}}. The
+// fixtures are returned sorted by name.
+func LoadFixtures(path string) ([]Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures file: %w", err)
+	}
+	var bodies map[string]json.RawMessage
+	if err := json.Unmarshal(data, &bodies); err != nil {
+		return nil, fmt.Errorf("failed to parse fixtures file %s: %w", path, err)
+	}
+	fixtures := make([]Fixture, 0, len(bodies))
+	for name, body := range bodies {
+		fixtures = append(fixtures, Fixture{Name: name, Body: body})
+	}
+	sort.Slice(fixtures, func(i, j int) bool { return fixtures[i].Name < fixtures[j].Name })
+	return fixtures, nil
+}
@@ -0,0 +1,22 @@
+package runtime
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// BodySha256 returns the hex-encoded SHA-256 of the deterministic binary
+// serialization of a request message, or "" if it cannot be serialized.
+func BodySha256(msg proto.Message) string {
+	if msg == nil {
+		return ""
+	}
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
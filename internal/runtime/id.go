@@ -0,0 +1,24 @@
+package runtime
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// reservedExpectationIDs are the paths under /expectations/ that the control
+// API routes to other handlers, so expectations with these IDs could not be
+// addressed by GET, PUT or DELETE /expectations/{id}.
+var reservedExpectationIDs = []string{"import", "export", "reload", "replay-check"}
+
+// ValidateID checks that the expectation's ID, if set, can be addressed as
+// /expectations/{id}.
+func (e *GRPCCallExpectation) ValidateID() error {
+	if strings.Contains(e.ID, "/") {
+		return fmt.Errorf("id %q must not contain /", e.ID)
+	}
+	if slices.Contains(reservedExpectationIDs, e.ID) {
+		return fmt.Errorf("id %q is reserved by the control API", e.ID)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ImportError reports why an expectation of an import was refused.
+type ImportError struct {
+	File  string `json:"file,omitempty"` // File of the expectation, when loaded from files
+	Index int    `json:"index"`          // Position of the expectation in the import or file, from 0
+	ID    string `json:"id,omitempty"`   // ID of the expectation, if it has one
+	Error string `json:"error"`
+}
+
+// ImportErrors are the errors of the expectations refused by an import.
+type ImportErrors []ImportError
+
+func (e ImportErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, ie := range e {
+		msgs[i] = fmt.Sprintf("expectation %d: %s", ie.Index, ie.Error)
+		if ie.File != "" {
+			msgs[i] = ie.File + ": " + msgs[i]
+		}
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ParseExpectations parses the expectations of an import: a JSON array of
+// expectations, or YAML documents each holding an expectation or a list of
+// them. Expectations that cannot be decoded are reported as ImportErrors.
+func ParseExpectations(data []byte) ([]GRPCCallExpectation, error) {
+	items, err := splitImport(data)
+	if err != nil {
+		return nil, err
+	}
+	exps := make([]GRPCCallExpectation, len(items))
+	var errs ImportErrors
+	for i, item := range items {
+		if err := json.Unmarshal(item, &exps[i]); err != nil {
+			errs = append(errs, ImportError{Index: i, Error: err.Error()})
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return exps, nil
+}
+
+// splitImport returns the JSON form of each expectation of an import.
+func splitImport(data []byte) ([]json.RawMessage, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var items []json.RawMessage
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, fmt.Errorf("invalid JSON array of expectations: %w", err)
+		}
+		return items, nil
+	}
+	var items []json.RawMessage
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for doc := 0; ; doc++ {
+		var v interface{}
+		if err := dec.Decode(&v); errors.Is(err, io.EOF) {
+			return items, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid YAML document %d: %w", doc, err)
+		}
+		list, ok := v.([]interface{})
+		if !ok {
+			list = []interface{}{v}
+		}
+		for _, exp := range list {
+			if exp == nil {
+				continue
+			}
+			b, err := json.Marshal(exp)
+			if err != nil {
+				return nil, fmt.Errorf("invalid YAML document %d: %w", doc, err)
+			}
+			items = append(items, b)
+		}
+	}
+}
+
+// ExportExpectations returns the expectations in the order they are matched
+// within each method, the methods sorted by key, for ParseExpectations to load
+// them back.
+func ExportExpectations(byKey map[string][]GRPCCallExpectation) []GRPCCallExpectation {
+	keys := make([]string, 0, len(byKey))
+	for key := range byKey {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	exps := make([]GRPCCallExpectation, 0, len(keys))
+	for _, key := range keys {
+		exps = append(exps, byKey[key]...)
+	}
+	return exps
+}
+
+// ExportYAML renders expectations as a YAML list, which ParseExpectations loads back.
+func ExportYAML(exps []GRPCCallExpectation) ([]byte, error) {
+	b, err := json.Marshal(exps)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(v)
+}
@@ -0,0 +1,68 @@
+package runtime
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// modulePath is the path of the module providing the mock runtime.
+const modulePath = "github.com/rbroggi/grpcmock"
+
+// Info describes a running mock server, so scripts and humans can check they
+// talk to the right mock with the right configuration.
+type Info struct {
+	Version   string        `json:"version"` // Version of the grpcmock runtime, "(devel)" for local builds
+	GoVersion string        `json:"goVersion"`
+	GRPCPort  string        `json:"grpcPort"`
+	HTTPPort  string        `json:"httpPort"`
+	StartedAt time.Time     `json:"startedAt"`
+	Services  []ServiceInfo `json:"services"`
+	// Modes lists the active behaviors altering calls or their recording:
+	// "slo", "sampling", "quotas", "retention", "reflectionFilter", "slowCallBudget", "exec"
+	// and "maxConnectionAge".
+	Modes    []string `json:"modes"`
+	Fixtures []string `json:"fixtures"` // Names of the fixtures registered with POST /fixtures
+}
+
+// ServiceInfo describes a gRPC service served by the mock.
+type ServiceInfo struct {
+	Name    string   `json:"name"`
+	File    string   `json:"file,omitempty"` // Proto file declaring the service
+	Methods []string `json:"methods"`        // Full method names
+}
+
+// BuildVersion returns the version of the grpcmock runtime linked into the
+// running binary and the Go version it was built with.
+func BuildVersion() (version, goVersion string) {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown", "unknown"
+	}
+	version = "(devel)"
+	if bi.Main.Path == modulePath {
+		version = bi.Main.Version
+	}
+	for _, dep := range bi.Deps {
+		if dep.Path == modulePath {
+			version = dep.Version
+		}
+	}
+	return version, bi.GoVersion
+}
+
+// Banner formats the information logged when the mock server starts.
+func (i Info) Banner() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "grpcmock %s (%s): gRPC on :%s, HTTP control API on :%s\n", i.Version, i.GoVersion, i.GRPCPort, i.HTTPPort)
+	for _, s := range i.Services {
+		fmt.Fprintf(&b, "  %s (methods: %d)\n", s.Name, len(s.Methods))
+	}
+	modes := "none"
+	if len(i.Modes) > 0 {
+		modes = strings.Join(i.Modes, ", ")
+	}
+	fmt.Fprintf(&b, "  modes: %s; details at GET /info", modes)
+	return b.String()
+}
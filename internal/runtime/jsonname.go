@@ -0,0 +1,22 @@
+package runtime
+
+import "strings"
+
+// JSONName converts a proto (snake_case) field name to its default protojson
+// lowerCamelCase name; names already in lowerCamelCase are unchanged.
+func JSONName(name string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range name {
+		if r == '_' {
+			upper = true
+			continue
+		}
+		if upper && 'a' <= r && r <= 'z' {
+			r -= 'a' - 'A'
+		}
+		upper = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,67 @@
+package latency
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// z99 is the standard normal quantile of the 99th percentile.
+const z99 = 2.3263478740408408
+
+// LogNormal is a log-normal latency distribution, the usual shape of service latencies.
+type LogNormal struct {
+	Median time.Duration
+	Sigma  float64 // Standard deviation of the underlying normal distribution
+}
+
+// FromPercentiles derives the log-normal distribution with the given median (p50)
+// and 99th percentile. If p99 does not exceed p50 the distribution is constant.
+func FromPercentiles(p50, p99 time.Duration) LogNormal {
+	if p50 <= 0 || p99 <= p50 {
+		return LogNormal{Median: p50}
+	}
+	return LogNormal{Median: p50, Sigma: math.Log(float64(p99)/float64(p50)) / z99}
+}
+
+// Sample draws a latency from the distribution.
+func (d LogNormal) Sample(r *rand.Rand) time.Duration {
+	if d.Median <= 0 {
+		return 0
+	}
+	return time.Duration(float64(d.Median) * math.Exp(d.Sigma*r.NormFloat64()))
+}
+
+// Percentile is a point of a Table: the latency below which P percent of calls complete.
+type Percentile struct {
+	P       float64 // From 0 to 100
+	Latency time.Duration
+}
+
+// Table is an empirical latency distribution given by percentiles, sorted by P
+// with non-decreasing latencies. Latencies between percentiles are interpolated
+// linearly; below the first and above the last one, they are theirs.
+type Table []Percentile
+
+// Quantile returns the latency of percentile p.
+func (t Table) Quantile(p float64) time.Duration {
+	if len(t) == 0 {
+		return 0
+	}
+	if p <= t[0].P {
+		return t[0].Latency
+	}
+	for i := 1; i < len(t); i++ {
+		if p <= t[i].P {
+			lo, hi := t[i-1], t[i]
+			frac := (p - lo.P) / (hi.P - lo.P)
+			return lo.Latency + time.Duration(frac*float64(hi.Latency-lo.Latency))
+		}
+	}
+	return t[len(t)-1].Latency
+}
+
+// Sample draws a latency from the distribution.
+func (t Table) Sample(r *rand.Rand) time.Duration {
+	return t.Quantile(100 * r.Float64())
+}
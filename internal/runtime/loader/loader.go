@@ -0,0 +1,157 @@
+// Package loader loads the expectations of a directory of stub files into the
+// store, and reloads them when the files change.
+package loader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rbroggi/grpcmock/internal/runtime"
+)
+
+// debounce is how long the watcher waits for file changes to settle before
+// reloading, as editors often write a file in several steps.
+const debounce = 200 * time.Millisecond
+
+// storeInterface defines the storage methods needed to load expectations.
+type storeInterface interface {
+	SwapExpectations(remove []string, exps []runtime.GRPCCallExpectation) ([]string, error)
+}
+
+// Loader loads the stub files of a directory: its .json, .yaml and .yml files,
+// each holding expectations in the format of POST /expectations/import.
+// Expectations added otherwise, e.g. through the control API, are kept on reload.
+type Loader struct {
+	store storeInterface
+	dir   string
+	mu    sync.Mutex
+	ids   []string // IDs of the expectations loaded from the directory
+}
+
+// New creates a Loader of the stub files of dir.
+func New(store storeInterface, dir string) *Loader {
+	return &Loader{store: store, dir: dir}
+}
+
+// Reload replaces the expectations loaded from the directory with those of its
+// current files, atomically: if a file cannot be read or holds an invalid
+// expectation, the loaded expectations are kept. It returns the loaded IDs.
+func (l *Loader) Reload() ([]string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	files, err := stubFiles(l.dir)
+	if err != nil {
+		return nil, err
+	}
+	var exps []runtime.GRPCCallExpectation
+	var origins []runtime.ImportError // File and index in the file of each expectation
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		fileExps, err := runtime.ParseExpectations(data)
+		var itemErrs runtime.ImportErrors
+		if errors.As(err, &itemErrs) {
+			for i := range itemErrs {
+				itemErrs[i].File = file
+			}
+			return nil, itemErrs
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		exps = append(exps, fileExps...)
+		for i := range fileExps {
+			origins = append(origins, runtime.ImportError{File: file, Index: i})
+		}
+	}
+	ids, err := l.store.SwapExpectations(l.ids, exps)
+	var itemErrs runtime.ImportErrors
+	if errors.As(err, &itemErrs) {
+		for i, ie := range itemErrs {
+			itemErrs[i].File, itemErrs[i].Index = origins[ie.Index].File, origins[ie.Index].Index
+		}
+		return nil, itemErrs
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", l.dir, err)
+	}
+	l.ids = ids
+	log.Printf("grpcmockruntime: Loaded %d expectations from %d files in %s", len(ids), len(files), l.dir)
+	return ids, nil
+}
+
+// stubFiles returns the stub files of dir, sorted by name; subdirectories and
+// hidden files, such as editors' swap files, are ignored.
+func stubFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read expectations directory: %w", err)
+	}
+	var files []string
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") || !isStubFile(e.Name()) {
+			continue
+		}
+		files = append(files, filepath.Join(dir, e.Name()))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// isStubFile reports whether a file name has the extension of a stub file.
+func isStubFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// Watch reloads the expectations whenever a stub file of the directory is
+// created, written, renamed or removed, until ctx is done. Failed reloads are
+// logged and keep the loaded expectations.
+func (l *Loader) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch %s: %w", l.dir, err)
+	}
+	defer watcher.Close()
+	if err := watcher.Add(l.dir); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", l.dir, err)
+	}
+	var settle <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if isStubFile(ev.Name) && !strings.HasPrefix(filepath.Base(ev.Name), ".") && ev.Op != fsnotify.Chmod {
+				settle = time.After(debounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Printf("grpcmockruntime: error watching %s: %v", l.dir, err)
+		case <-settle:
+			settle = nil
+			if _, err := l.Reload(); err != nil {
+				log.Printf("grpcmockruntime: failed to reload expectations, keeping the loaded ones: %v", err)
+			}
+		}
+	}
+}
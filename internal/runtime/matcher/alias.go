@@ -0,0 +1,187 @@
+package matcher
+
+import (
+	"encoding/json"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+)
+
+// candidate is an expectation that may serve a call, directly or through an alias.
+type candidate struct {
+	method string // Method the expectation is registered for
+	idx    int    // Index of the expectation among those of method
+	exp    runtime.GRPCCallExpectation
+	alias  *runtime.MethodAlias // Set if the expectation serves the call through an alias
+}
+
+// candidatesFor returns the expectations registered for a method followed by
+// those serving it through an alias, in a deterministic order.
+func candidatesFor(expectations map[string][]runtime.GRPCCallExpectation, fullMethodName string) []candidate {
+	var candidates []candidate
+	for idx, exp := range expectations[fullMethodName] {
+		if !isMethodRegex(exp) { // A pattern may be written like the method name
+			candidates = append(candidates, candidate{method: fullMethodName, idx: idx, exp: exp})
+		}
+	}
+	methods := make([]string, 0, len(expectations))
+	for method := range expectations {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	for _, method := range methods {
+		for idx, exp := range expectations[method] {
+			for i := range exp.Aliases {
+				if exp.Aliases[i].FullMethodName == fullMethodName {
+					candidates = append(candidates, candidate{method: method, idx: idx, exp: exp, alias: &exp.Aliases[i]})
+				}
+			}
+		}
+	}
+	return candidates
+}
+
+// wildcardCandidates returns the expectations registered for every method of
+// the service of a method, followed by those whose FullMethodNameRegex matches
+// it, in a deterministic order.
+func wildcardCandidates(expectations map[string][]runtime.GRPCCallExpectation, fullMethodName string) []candidate {
+	var candidates []candidate
+	if wildcard := runtime.ServiceWildcard(fullMethodName); wildcard != "" && wildcard != fullMethodName {
+		for idx, exp := range expectations[wildcard] {
+			if !isMethodRegex(exp) {
+				candidates = append(candidates, candidate{method: wildcard, idx: idx, exp: exp})
+			}
+		}
+	}
+	keys := make([]string, 0)
+	for key, exps := range expectations {
+		if slices.ContainsFunc(exps, isMethodRegex) && matchesRegex(key, fullMethodName) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for idx, exp := range expectations[key] {
+			if isMethodRegex(exp) {
+				candidates = append(candidates, candidate{method: key, idx: idx, exp: exp})
+			}
+		}
+	}
+	return candidates
+}
+
+// isMethodRegex reports whether an expectation applies to the methods matching
+// its FullMethodNameRegex, stored under the pattern.
+func isMethodRegex(exp runtime.GRPCCallExpectation) bool {
+	return exp.FullMethodNameRegex != ""
+}
+
+// mapRequest returns the match context with the request body mapped back to
+// the field names of the expectation's method.
+func (c candidate) mapRequest(mc *matchContext) *matchContext {
+	if c.alias == nil || len(c.alias.FieldMapping) == 0 {
+		return mc
+	}
+	mapped := *mc
+	mapped.body = remap(mc.body, c.alias.FieldMapping, true)
+	mapped.msg, mapped.protoMessages = nil, nil // Field names no longer match the messages
+	if mc.messages != nil {
+		mapped.messages = make([]map[string]interface{}, len(mc.messages))
+		for i, msg := range mc.messages {
+			mapped.messages[i] = remap(msg, c.alias.FieldMapping, true)
+		}
+	}
+	return &mapped
+}
+
+// mapResponse returns the matched expectation with its response bodies mapped
+// to the field names of the alias' method.
+func (c candidate) mapResponse(exp *runtime.GRPCCallExpectation) *runtime.GRPCCallExpectation {
+	if c.alias == nil || len(c.alias.FieldMapping) == 0 || exp.Response == nil {
+		return exp
+	}
+	resp := *exp.Response
+	resp.Body = remapJSON(resp.Body, c.alias.FieldMapping)
+	if len(resp.Bodies) > 0 {
+		resp.Bodies = make([]json.RawMessage, len(exp.Response.Bodies))
+		for i, body := range exp.Response.Bodies {
+			resp.Bodies[i] = remapJSON(body, c.alias.FieldMapping)
+		}
+	}
+	exp.Response = &resp
+	return exp
+}
+
+// remap moves the fields of a copy of obj according to the mapping, from keys
+// to values, or from values to keys if reverse is set.
+func remap(obj map[string]interface{}, mapping map[string]string, reverse bool) map[string]interface{} {
+	mapped, _ := deepCopyJSON(obj).(map[string]interface{})
+	if mapped == nil {
+		return nil
+	}
+	froms := make([]string, 0, len(mapping))
+	for from := range mapping {
+		froms = append(froms, from)
+	}
+	sort.Strings(froms) // Deterministic when paths overlap
+	for _, from := range froms {
+		to := mapping[from]
+		if reverse {
+			from, to = to, from
+		}
+		if v, ok := takePath(mapped, strings.Split(from, ".")); ok {
+			putPath(mapped, strings.Split(to, "."), v)
+		}
+	}
+	return mapped
+}
+
+// remapJSON applies the mapping to a JSON object, leaving other values unchanged.
+func remapJSON(body json.RawMessage, mapping map[string]string) json.RawMessage {
+	var obj map[string]interface{}
+	if len(body) == 0 || json.Unmarshal(body, &obj) != nil {
+		return body
+	}
+	mapped, err := json.Marshal(remap(obj, mapping, false))
+	if err != nil {
+		log.Printf("grpcmockruntime: failed to map aliased response body: %v", err)
+		return body
+	}
+	return mapped
+}
+
+// takePath removes and returns the value at the given path.
+func takePath(obj map[string]interface{}, segments []string) (interface{}, bool) {
+	for k, v := range obj {
+		if !sameField(k, segments[0]) {
+			continue
+		}
+		if len(segments) == 1 {
+			delete(obj, k)
+			return v, true
+		}
+		if nested, ok := v.(map[string]interface{}); ok {
+			return takePath(nested, segments[1:])
+		}
+		return nil, false
+	}
+	return nil, false
+}
+
+// putPath sets the value at the given path, creating intermediate objects.
+func putPath(obj map[string]interface{}, segments []string, v interface{}) {
+	key := runtime.JSONName(segments[0])
+	if len(segments) == 1 {
+		obj[key] = v
+		return
+	}
+	nested, ok := obj[key].(map[string]interface{})
+	if !ok {
+		nested = make(map[string]interface{})
+		obj[key] = nested
+	}
+	putPath(nested, segments[1:], v)
+}
@@ -0,0 +1,99 @@
+package matcher
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+)
+
+// elementMatcherKey marks an element of an expected array as a field matcher,
+// e.g. {"${match}": {"regex": "^SKU-"}}; other elements are compared literally.
+const elementMatcherKey = "${match}"
+
+// elementMatcher returns the field matcher of an element of an expected array,
+// if it is an object whose only key is elementMatcherKey.
+func elementMatcher(expected interface{}) (json.RawMessage, bool) {
+	obj, ok := expected.(map[string]interface{})
+	if !ok || len(obj) != 1 {
+		return nil, false
+	}
+	m, ok := obj[elementMatcherKey]
+	if !ok {
+		return nil, false
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+// decodeElementMatcher decodes the field matcher of a marked element, rejecting unknown keys.
+func decodeElementMatcher(raw json.RawMessage) (runtime.FieldMatcher, error) {
+	var matcher runtime.FieldMatcher
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&matcher)
+	return matcher, err
+}
+
+// matchElement applies the field matcher of a marked element of an expected
+// array to the actual element. Matchers with unknown keys match nothing.
+func matchElement(mc *matchContext, raw json.RawMessage, actual interface{}) bool {
+	matcher, err := decodeElementMatcher(raw)
+	if err != nil {
+		return false
+	}
+	return matchField(mc, matcher, actual)
+}
+
+// matchFields applies field matchers to a message value, as matchBody does to the request.
+func matchFields(mc *matchContext, fields map[string]runtime.FieldMatcher, value interface{}) bool {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	return matchBody(mc, fields, obj, mc.value.message())
+}
+
+// matchArrayContaining reports whether some element of a repeated field matches the matcher.
+func matchArrayContaining(mc *matchContext, matcher runtime.FieldMatcher, value interface{}) bool {
+	elems, ok := value.([]interface{})
+	if !ok {
+		return false
+	}
+	for i, elem := range elems {
+		if matchField(mc.withValue(mc.value.element(i)), matcher, elem) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchCount applies the Count, MinCount and MaxCount matchers to the number of
+// elements of a repeated field or entries of a map field.
+func matchCount(matcher runtime.FieldMatcher, value interface{}) bool {
+	if matcher.Count == nil && matcher.MinCount == nil && matcher.MaxCount == nil {
+		return true
+	}
+	var n int
+	switch v := value.(type) {
+	case []interface{}:
+		n = len(v)
+	case map[string]interface{}:
+		n = len(v)
+	default:
+		return false
+	}
+	if matcher.Count != nil && n != *matcher.Count {
+		return false
+	}
+	if matcher.MinCount != nil && n < *matcher.MinCount {
+		return false
+	}
+	if matcher.MaxCount != nil && n > *matcher.MaxCount {
+		return false
+	}
+	return true
+}
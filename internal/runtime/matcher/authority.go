@@ -0,0 +1,27 @@
+package matcher
+
+import (
+	"net"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// matchAuthority reports whether the call's :authority designates the expected
+// host, ignoring case. The port is only compared if the expected authority has one.
+func matchAuthority(expected string, headers metadata.MD) bool {
+	if expected == "" {
+		return true
+	}
+	values := headers.Get(":authority")
+	if len(values) == 0 {
+		return false
+	}
+	actual := values[0]
+	if _, _, err := net.SplitHostPort(expected); err != nil {
+		if host, _, err := net.SplitHostPort(actual); err == nil {
+			actual = host
+		}
+	}
+	return strings.EqualFold(expected, actual)
+}
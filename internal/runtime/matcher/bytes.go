@@ -0,0 +1,53 @@
+package matcher
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+)
+
+// matchBytes applies the bytes matchers to the base64 string protojson renders for a bytes field.
+func matchBytes(mc *matchContext, matcher runtime.FieldMatcher, value interface{}) bool {
+	if matcher.EqualsBase64 == "" && matcher.EqualsHex == "" && matcher.Length == nil {
+		return true
+	}
+	str, ok := value.(string)
+	if !ok {
+		return false
+	}
+	actual, ok := decodeBase64(str)
+	if !ok {
+		return false
+	}
+	if matcher.EqualsBase64 != "" {
+		expected, ok := decodeBase64(matcher.EqualsBase64)
+		if !ok || !bytes.Equal(expected, actual) {
+			return false
+		}
+	}
+	if matcher.EqualsHex != "" {
+		expected, err := hex.DecodeString(matcher.EqualsHex)
+		if err != nil || !bytes.Equal(expected, actual) {
+			return false
+		}
+	}
+	if matcher.Length != nil && !matchField(mc.withValue(nil), *matcher.Length, float64(len(actual))) {
+		return false
+	}
+	return true
+}
+
+// decodeBase64 decodes standard or URL-safe base64, with or without padding,
+// as protojson accepts both.
+func decodeBase64(s string) ([]byte, bool) {
+	s = strings.TrimRight(s, "=")
+	if strings.ContainsAny(s, "-_") {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		return b, err == nil
+	}
+	b, err := base64.RawStdEncoding.DecodeString(s)
+	return b, err == nil
+}
@@ -0,0 +1,55 @@
+package matcher
+
+import (
+	"regexp"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+)
+
+// captures collects the named groups of the header and body regexes of a
+// RequestMatcher, including those it requires through AllOf, as matched by the call.
+// Groups of the alternatives of AnyOf and of Not are not collected, as they need not match.
+func captures(mc *matchContext, rm *runtime.RequestMatcher, into map[string]string) {
+	if rm == nil {
+		return
+	}
+	mc, rm = withoutIgnoredFields(mc, rm)
+	for key, hm := range rm.Headers {
+		if hm.Regex == "" {
+			continue
+		}
+		for _, v := range mc.headers.Get(key) {
+			if captureGroups(hm.Regex, v, into) {
+				break
+			}
+		}
+	}
+	for k, fm := range rm.Body {
+		v, _ := lookupField(mc.body, k, mc.msg)
+		if s, ok := v.(string); ok && fm.Regex != "" {
+			captureGroups(fm.Regex, s, into)
+		}
+	}
+	for i := range rm.AllOf {
+		captures(mc, &rm.AllOf[i], into)
+	}
+}
+
+// captureGroups stores the named groups of pattern matched against text, and
+// reports whether the pattern matched.
+func captureGroups(pattern, text string, into map[string]string) bool {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false
+	}
+	m := re.FindStringSubmatch(text)
+	if m == nil {
+		return false
+	}
+	for i, name := range re.SubexpNames() {
+		if name != "" {
+			into[name] = m[i]
+		}
+	}
+	return true
+}
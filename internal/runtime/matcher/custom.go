@@ -0,0 +1,44 @@
+package matcher
+
+import (
+	"log"
+	"sync"
+)
+
+// CustomMatcherFunc reports whether a field value matches. The value is the
+// field's protojson form decoded into Go values: nil, bool, float64, string
+// (including 64-bit integers, bytes and enums), []interface{} or map[string]interface{}.
+type CustomMatcherFunc func(value interface{}) bool
+
+var (
+	customMatchersMu sync.RWMutex
+	customMatchers   = map[string]CustomMatcherFunc{}
+)
+
+// RegisterMatcher makes fn available to field matchers as {"custom": name},
+// replacing any function registered under the same name.
+func RegisterMatcher(name string, fn CustomMatcherFunc) {
+	customMatchersMu.Lock()
+	defer customMatchersMu.Unlock()
+	customMatchers[name] = fn
+}
+
+// customMatcherRegistered reports whether a custom matcher is registered under name.
+func customMatcherRegistered(name string) bool {
+	customMatchersMu.RLock()
+	defer customMatchersMu.RUnlock()
+	_, ok := customMatchers[name]
+	return ok
+}
+
+// matchCustom applies the named custom matcher; unknown names never match.
+func matchCustom(name string, value interface{}) bool {
+	customMatchersMu.RLock()
+	fn, ok := customMatchers[name]
+	customMatchersMu.RUnlock()
+	if !ok {
+		log.Printf("grpcmockruntime: unknown custom matcher %q", name)
+		return false
+	}
+	return fn(value)
+}
@@ -0,0 +1,198 @@
+package matcher
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+	"google.golang.org/protobuf/proto"
+)
+
+// Near-miss reasons, in the order the matcher evaluates them.
+const (
+	reasonSchedule   = "schedule"
+	reasonScenario   = "scenario"
+	reasonActiveWhen = "activeWhen"
+	reasonAfter      = "after"
+	reasonAuthority  = "authority"
+	reasonHeaders    = "headers"
+	reasonBody       = "body"
+	reasonBodySchema = "bodySchema"
+	reasonBodySha256 = "bodySha256"
+	reasonDeadline   = "deadline"
+	reasonStream     = "stream"
+	reasonComposite  = "composite"
+	reasonTimes      = "times"
+)
+
+// Field diff reasons.
+const (
+	diffMissing      = "missing"
+	diffUnknownField = "unknownField"
+	diffExtra        = "extra"
+	diffTypeMismatch = "typeMismatch"
+	diffMismatch     = "mismatch"
+)
+
+// diffBody returns the body fields rejected by the expected matchers, sorted by field name.
+func diffBody(mc *matchContext, expected map[string]runtime.FieldMatcher, actual map[string]interface{}, msg proto.Message) []runtime.FieldDiff {
+	var diffs []runtime.FieldDiff
+	for k, matcher := range expected {
+		v, ok := lookupField(actual, k, msg)
+		switch {
+		case (matcher.IsSet || matcher.IsUnset) && !matchPresence(msg, k, matcher.IsSet):
+			reason := diffMissing
+			if matcher.IsUnset {
+				reason = diffExtra
+			}
+			diffs = append(diffs, runtime.FieldDiff{Field: k, Reason: reason, Expected: matcher, Actual: v})
+		case matcher.IsUnset:
+		case matcher.Absent:
+			if !isAbsent(msg, k, v, ok) {
+				diffs = append(diffs, runtime.FieldDiff{Field: k, Reason: diffExtra, Expected: matcher, Actual: v})
+			}
+		case !ok && msg != nil && fieldByName(msg.ProtoReflect().Descriptor(), k) == nil:
+			diffs = append(diffs, runtime.FieldDiff{Field: k, Reason: diffUnknownField, Expected: matcher})
+		case !ok:
+			diffs = append(diffs, runtime.FieldDiff{Field: k, Reason: diffMissing, Expected: matcher})
+		case !matchField(mc.withValue(fieldValue(msg, k)), matcher, v):
+			reason := diffMismatch
+			if kind := expectedKind(matcher); kind != "" && kind != jsonKind(v) {
+				reason = diffTypeMismatch
+			}
+			diffs = append(diffs, runtime.FieldDiff{Field: k, Reason: reason, Expected: matcher, Actual: v})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Field < diffs[j].Field })
+	return diffs
+}
+
+// expectedKind returns the JSON kind of value a matcher can accept, or "" if it accepts several.
+func expectedKind(matcher runtime.FieldMatcher) string {
+	switch {
+	case matcher.Equals != nil:
+		if p, ok := matcher.Equals.(string); ok {
+			if _, ok := placeholders[p]; ok {
+				return placeholderKinds[p]
+			}
+			if _, ok := varPlaceholder(p); ok {
+				return ""
+			}
+		}
+		return jsonKind(matcher.Equals)
+	case matcher.Any != nil, matcher.Fields != nil, matcher.HasKeys != nil, matcher.MapContaining != nil:
+		return "object"
+	case matcher.ArrayContaining != nil:
+		return "array"
+	case matcher.Range != nil:
+		return "number"
+	case matcher.Money != nil && matcher.Money.Currency != "":
+		return "object"
+	case matcher.Regex != "", matcher.NotRegex != "", matcher.Contains != nil,
+		matcher.Before != "", matcher.After != "", matcher.Within != "",
+		matcher.LessThan != "", matcher.GreaterThan != "",
+		matcher.EqualsBase64 != "", matcher.EqualsHex != "", matcher.Length != nil,
+		matcher.Format != "":
+		return "string"
+	default:
+		return ""
+	}
+}
+
+// jsonKind returns the JSON kind of a value decoded by encoding/json.
+func jsonKind(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	default:
+		if _, ok := toFloat64(v); ok {
+			return "number"
+		}
+		return "unknown"
+	}
+}
+
+// explain returns why a candidate expectation rejects the call, with an empty
+// Reason if its schedule, activation (if checked) and matchers all accept it.
+func (m *Matcher) explain(mc *matchContext, c candidate, checkActivation bool) runtime.NearMiss {
+	exp := c.exp
+	nearMiss := runtime.NearMiss{ExpectationIndex: c.idx}
+	if c.alias != nil {
+		nearMiss.AliasOf = c.method
+	}
+	mc, rm := c.mapRequest(mc), exp.RequestMatcher
+	if rm != nil {
+		mc, rm = withoutIgnoredFields(mc, rm)
+	}
+	switch {
+	case !exp.Schedule.Active(mc.now):
+		nearMiss.Reason = reasonSchedule
+	case checkActivation && !m.inScenarioState(&exp):
+		nearMiss.Reason = reasonScenario
+	case checkActivation && !m.activated(exp.ActiveWhen):
+		nearMiss.Reason = reasonActiveWhen
+	case checkActivation && !m.observed(exp.After):
+		nearMiss.Reason = reasonAfter
+	case !matchAuthority(exp.Authority, mc.headers):
+		nearMiss.Reason = reasonAuthority
+	case rm != nil && rm.Headers != nil && !matchHeaders(rm.Headers, mc.headers):
+		nearMiss.Reason = reasonHeaders
+	case rm != nil && mc.marshalError != "" && rm.Body != nil:
+		nearMiss.Reason = reasonBody
+	case rm != nil && mc.marshalError != "" && len(rm.BodySchema) > 0:
+		nearMiss.Reason = reasonBodySchema
+	case rm != nil && rm.Body != nil && !matchBody(mc, rm.Body, mc.body, mc.msg):
+		nearMiss.Reason = reasonBody
+		nearMiss.BodyDiff = diffBody(mc, rm.Body, mc.body, mc.msg)
+	case rm != nil && len(rm.BodySchema) > 0 && len(validateSchema(rm.BodySchema, mc.body)) > 0:
+		nearMiss.Reason = reasonBodySchema
+		nearMiss.SchemaErrors = validateSchema(rm.BodySchema, mc.body)
+	case rm != nil && !matchBodySha256(mc, rm.BodySha256):
+		nearMiss.Reason = reasonBodySha256
+	case rm != nil && !matchDeadline(mc, rm):
+		nearMiss.Reason = reasonDeadline
+	case rm != nil && !matchComposite(mc, rm):
+		nearMiss.Reason = reasonComposite
+	case !matchStream(mc, exp.Stream):
+		nearMiss.Reason = reasonStream
+	}
+	return nearMiss
+}
+
+// recordNearMisses explains why each expectation of the method rejected the call,
+// logs the explanation and stores the call as unmatched.
+func (m *Matcher) recordNearMisses(
+	mc *matchContext,
+	fullMethodName string,
+	body json.RawMessage,
+	candidates []candidate,
+) {
+	nearMisses := make([]runtime.NearMiss, 0, len(candidates))
+	for _, c := range candidates {
+		nearMiss := m.explain(mc, c, true)
+		if nearMiss.Reason == "" {
+			nearMiss.Reason = reasonTimes
+		}
+		nearMisses = append(nearMisses, nearMiss)
+		for _, d := range nearMiss.BodyDiff {
+			log.Printf("grpcmockruntime: %s expectation #%d: field '%s' %s (actual: %v)", fullMethodName, c.idx, d.Field, d.Reason, d.Actual)
+		}
+	}
+	m.Store.RecordUnmatched(runtime.UnmatchedGRPCCall{
+		FullMethodName: fullMethodName,
+		Headers:        mc.headers,
+		Body:           body,
+		Timestamp:      mc.now.UnixNano(),
+		NearMisses:     nearMisses,
+		MarshalError:   mc.marshalError,
+	})
+}
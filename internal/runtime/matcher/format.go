@@ -0,0 +1,56 @@
+package matcher
+
+import (
+	"log"
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// iso8601Pattern accepts ISO 8601 calendar dates, optionally followed by a time
+// (seconds and fractions optional) and an offset, which RFC 3339 requires.
+var iso8601Pattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}(T\d{2}:\d{2}(:\d{2}(\.\d+)?)?(Z|[+-]\d{2}(:?\d{2})?)?)?$`)
+
+// formats maps the supported Format values to the strings they accept.
+var formats = map[string]func(s string) bool{
+	"uuid": uuidPattern.MatchString,
+	"email": func(s string) bool {
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s
+	},
+	"date-time": func(s string) bool {
+		_, err := time.Parse(time.RFC3339Nano, s)
+		return err == nil
+	},
+	"date": func(s string) bool {
+		_, err := time.Parse(time.DateOnly, s)
+		return err == nil
+	},
+	"iso8601": iso8601Pattern.MatchString,
+	"url": func(s string) bool {
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != "" && u.Host != ""
+	},
+	"ipv4": func(s string) bool {
+		ip := net.ParseIP(s)
+		return ip != nil && ip.To4() != nil && !strings.Contains(s, ":")
+	},
+	"ipv6": func(s string) bool {
+		ip := net.ParseIP(s)
+		return ip != nil && strings.Contains(s, ":")
+	},
+}
+
+// matchFormat reports whether value is a string of the named format; unknown formats never match.
+func matchFormat(format string, value interface{}) bool {
+	valid, ok := formats[strings.ToLower(format)]
+	if !ok {
+		log.Printf("grpcmockruntime: unknown format %q", format)
+		return false
+	}
+	s, ok := value.(string)
+	return ok && valid(s)
+}
@@ -0,0 +1,132 @@
+package matcher
+
+import (
+	"strings"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// withoutIgnoredFields returns the match context and request matcher with the
+// IgnoreFields paths stripped from both the request body and the body matchers.
+// The originals are left untouched.
+func withoutIgnoredFields(mc *matchContext, rm *runtime.RequestMatcher) (*matchContext, *runtime.RequestMatcher) {
+	if len(rm.IgnoreFields) == 0 {
+		return mc, rm
+	}
+	stripped := *mc
+	body, _ := deepCopyJSON(mc.body).(map[string]interface{})
+	var matchers map[string]runtime.FieldMatcher
+	if rm.Body != nil {
+		matchers = make(map[string]runtime.FieldMatcher, len(rm.Body))
+		for k, v := range rm.Body {
+			matchers[k] = v
+		}
+	}
+	for _, path := range rm.IgnoreFields {
+		segments := strings.Split(path, ".")
+		stripPath(body, segments)
+		for k, fm := range matchers {
+			if !sameField(k, segments[0]) {
+				continue
+			}
+			if len(segments) == 1 {
+				delete(matchers, k)
+				continue
+			}
+			if obj, ok := fm.Equals.(map[string]interface{}); ok {
+				fm.Equals = deepCopyJSON(obj)
+				stripPath(fm.Equals.(map[string]interface{}), segments[1:])
+			}
+			if obj, ok := fm.NotEquals.(map[string]interface{}); ok {
+				fm.NotEquals = deepCopyJSON(obj)
+				stripPath(fm.NotEquals.(map[string]interface{}), segments[1:])
+			}
+			matchers[k] = fm
+		}
+	}
+	stripped.body = body
+	if mc.msg != nil {
+		msg := proto.Clone(mc.msg) // Ignored fields must not be seen by presence checks
+		for _, path := range rm.IgnoreFields {
+			clearPath(msg.ProtoReflect(), strings.Split(path, "."))
+		}
+		stripped.msg = msg
+	}
+	strippedMatcher := *rm
+	strippedMatcher.Body = matchers
+	strippedMatcher.IgnoreFields = nil
+	return &stripped, &strippedMatcher
+}
+
+// clearPath clears the field of m at the given path, descending into nested
+// messages and into every element of repeated message fields, as stripPath does.
+func clearPath(m protoreflect.Message, segments []string) {
+	fd := fieldByName(m.Descriptor(), segments[0])
+	if fd == nil || !m.Has(fd) {
+		return
+	}
+	switch {
+	case len(segments) == 1:
+		m.Clear(fd)
+	case fd.Message() == nil || fd.IsMap():
+	case fd.IsList():
+		list := m.Mutable(fd).List()
+		for i := 0; i < list.Len(); i++ {
+			clearPath(list.Get(i).Message(), segments[1:])
+		}
+	default:
+		clearPath(m.Mutable(fd).Message(), segments[1:])
+	}
+}
+
+// stripPath removes the field at the given path, descending into nested
+// objects and into every element of repeated fields.
+func stripPath(obj map[string]interface{}, segments []string) {
+	for k, v := range obj {
+		if !sameField(k, segments[0]) {
+			continue
+		}
+		if len(segments) == 1 {
+			delete(obj, k)
+			continue
+		}
+		switch val := v.(type) {
+		case map[string]interface{}:
+			stripPath(val, segments[1:])
+		case []interface{}:
+			for _, elem := range val {
+				if nested, ok := elem.(map[string]interface{}); ok {
+					stripPath(nested, segments[1:])
+				}
+			}
+		}
+	}
+}
+
+// sameField reports whether a JSON key names the field of a FieldMask path
+// segment, accepting both the proto (snake_case) and JSON (lowerCamelCase) names.
+func sameField(key, segment string) bool {
+	return key == segment || key == runtime.JSONName(segment)
+}
+
+// deepCopyJSON copies a value decoded by encoding/json.
+func deepCopyJSON(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(val))
+		for k, e := range val {
+			c[k] = deepCopyJSON(e)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(val))
+		for i, e := range val {
+			c[i] = deepCopyJSON(e)
+		}
+		return c
+	default:
+		return v
+	}
+}
@@ -0,0 +1,36 @@
+package matcher
+
+import "github.com/rbroggi/grpcmock/internal/runtime"
+
+// matchHasKeys reports whether a map field has every listed key.
+func matchHasKeys(keys []string, value interface{}) bool {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	for _, key := range keys {
+		if _, ok := obj[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// matchMapContaining reports whether some entry of a map field matches the entry matcher.
+// Keys are matched in their protojson form, which is always a string.
+func matchMapContaining(mc *matchContext, matcher runtime.MapEntryMatcher, value interface{}) bool {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	for key, val := range obj {
+		if matcher.Key != nil && !matchField(mc.withValue(nil), *matcher.Key, key) {
+			continue
+		}
+		if matcher.Value != nil && !matchField(mc.withValue(mc.value.child(key)), *matcher.Value, val) {
+			continue
+		}
+		return true
+	}
+	return false
+}
@@ -1,25 +1,39 @@
 package matcher
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"log"
 	"reflect"
 	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/rbroggi/grpcmock/internal/runtime"
 	"github.com/rbroggi/grpcmock/internal/runtime/storage"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/proto"
 )
 
 // storeInterface defines the methods for expectation and call storage.
 type storeInterface interface {
-	AddExpectation(exp runtime.GRPCCallExpectation) error
+	AddExpectation(exp runtime.GRPCCallExpectation) (string, error)
 	GetExpectations() map[string][]runtime.GRPCCallExpectation
 	ClearAll()
-	RecordCall(fullMethodName string, headers map[string][]string, reqBodyProto proto.Message)
+	RecordCall(fullMethodName string, headers map[string][]string, reqBodyProto proto.Message) (uint64, error)
 	GetRecordedCalls() []runtime.RecordedGRPCCall
+	FindAnsweredCall(fullMethodName, expectationID string, accept func(runtime.RecordedGRPCCall) bool) bool
+	RecordUnmatched(call runtime.UnmatchedGRPCCall)
+	ClaimMatch(exp *runtime.GRPCCallExpectation) (int, bool)
+	MatchCount(id string) int
+	MatchCountByID(id string) (int, bool)
+	GetMatchCounts() map[string]int
+	GetVars() map[string]string
+	ScenarioState(name string) string
+	Clock() runtime.Clock
 }
 
 func matchesRegex(pattern, text string) bool {
@@ -34,9 +48,35 @@ func matchesRegex(pattern, text string) bool {
 	return matched
 }
 
+// matchContext carries the per-call state shared by the matchers.
+type matchContext struct {
+	now      time.Time
+	headers  metadata.MD
+	body     map[string]interface{}
+	deadline time.Time                // Zero if the call has no deadline
+	messages []map[string]interface{} // All messages of a client stream; nil for other calls
+	// msg and protoMessages are the proto forms of body and messages, used for
+	// presence checks; msg is nil when body is not the request's exact rendering.
+	msg           proto.Message
+	protoMessages []proto.Message
+	value         *protoValue // Proto form of the value a field matcher is applied to, if known
+	bodySha256    string      // Hash of the request (the first message of streams); empty if unknown
+	// marshalError is set if the request could not be rendered as protojson; body
+	// matchers then never match, leaving header and bodySha256 matchers usable.
+	marshalError string
+	vars         map[string]string // Variables set by responses, for "${var:name}" placeholders
+}
+
 // matchField applies a FieldMatcher to a value.
-func matchField(matcher runtime.FieldMatcher, value interface{}) bool {
-	if matcher.Equals != nil && !reflect.DeepEqual(matcher.Equals, value) {
+func matchField(mc *matchContext, matcher runtime.FieldMatcher, value interface{}) bool {
+	matcher, value, ok := normalizeStrings(matcher, value)
+	if !ok {
+		return false
+	}
+	if matcher.Equals != nil && !deepCompare(mc, matcher.Equals, value) {
+		return false
+	}
+	if matcher.NotEquals != nil && deepCompare(mc, matcher.NotEquals, value) {
 		return false
 	}
 	if matcher.Regex != "" {
@@ -45,6 +85,12 @@ func matchField(matcher runtime.FieldMatcher, value interface{}) bool {
 			return false
 		}
 	}
+	if matcher.NotRegex != "" {
+		strVal, ok := value.(string)
+		if !ok || matchesRegex(matcher.NotRegex, strVal) {
+			return false
+		}
+	}
 	if matcher.Contains != nil {
 		strVal, ok := value.(string)
 		substr, ok2 := matcher.Contains.(string)
@@ -58,6 +104,64 @@ func matchField(matcher runtime.FieldMatcher, value interface{}) bool {
 			return false
 		}
 	}
+	if matcher.Any != nil && !matchAny(mc, *matcher.Any, value) {
+		return false
+	}
+	if !matchTimestamp(matcher, value, mc.now) || !matchDuration(matcher, value) {
+		return false
+	}
+	if !matchBytes(mc, matcher, value) {
+		return false
+	}
+	if matcher.Fields != nil && !matchFields(mc, matcher.Fields, value) {
+		return false
+	}
+	if matcher.ArrayContaining != nil && !matchArrayContaining(mc, *matcher.ArrayContaining, value) {
+		return false
+	}
+	if !matchCount(matcher, value) {
+		return false
+	}
+	if matcher.HasKeys != nil && !matchHasKeys(matcher.HasKeys, value) {
+		return false
+	}
+	if matcher.MapContaining != nil && !matchMapContaining(mc, *matcher.MapContaining, value) {
+		return false
+	}
+	if matcher.Format != "" && !matchFormat(matcher.Format, value) {
+		return false
+	}
+	if matcher.Money != nil && !matchMoney(*matcher.Money, value) {
+		return false
+	}
+	if matcher.Custom != "" && !matchCustom(matcher.Custom, value) {
+		return false
+	}
+	return true
+}
+
+// matchAny applies an AnyMatcher to the protojson form of a google.protobuf.Any,
+// i.e. an object holding the "@type" URL alongside the unpacked payload fields.
+func matchAny(mc *matchContext, matcher runtime.AnyMatcher, value interface{}) bool {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	typeURL, _ := obj["@type"].(string)
+	if matcher.TypeURL != "" && matcher.TypeURL != typeURL && matcher.TypeURL != typeURL[strings.LastIndex(typeURL, "/")+1:] {
+		return false
+	}
+	if matcher.Body != nil {
+		payload := make(map[string]interface{}, len(obj))
+		for k, v := range obj {
+			if k != "@type" {
+				payload[k] = v
+			}
+		}
+		if !matchBody(mc, matcher.Body, payload, mc.value.anyPayload()) {
+			return false
+		}
+	}
 	return true
 }
 
@@ -84,6 +188,9 @@ func toFloat64(val interface{}) (float64, bool) {
 func matchHeaders(expected map[string]runtime.HeaderMatcher, actual metadata.MD) bool {
 	for key, matcher := range expected {
 		vals := actual.Get(key)
+		if matcher.Absent && len(vals) > 0 {
+			return false
+		}
 		if matcher.Exists != nil {
 			exists := len(vals) > 0
 			if *matcher.Exists != exists {
@@ -102,6 +209,13 @@ func matchHeaders(expected map[string]runtime.HeaderMatcher, actual metadata.MD)
 				return false
 			}
 		}
+		if matcher.NotEquals != "" {
+			for _, v := range vals {
+				if v == matcher.NotEquals {
+					return false
+				}
+			}
+		}
 		if matcher.Regex != "" {
 			found := false
 			for _, v := range vals {
@@ -114,33 +228,158 @@ func matchHeaders(expected map[string]runtime.HeaderMatcher, actual metadata.MD)
 				return false
 			}
 		}
+		if matcher.NotRegex != "" {
+			for _, v := range vals {
+				if matchesRegex(matcher.NotRegex, v) {
+					return false
+				}
+			}
+		}
 	}
 	return true
 }
 
+// lookupField returns the value of a field of a protojson object given its JSON or
+// proto name. With msg, the name is resolved through the message descriptor, which
+// knows custom json_name options; otherwise a snake_case name is converted to lowerCamelCase.
+func lookupField(actual map[string]interface{}, name string, msg proto.Message) (interface{}, bool) {
+	if v, ok := actual[name]; ok {
+		return v, true
+	}
+	jsonName := runtime.JSONName(name)
+	if msg != nil {
+		fd := fieldByName(msg.ProtoReflect().Descriptor(), name)
+		if fd == nil {
+			return nil, false
+		}
+		jsonName = fd.JSONName()
+	}
+	v, ok := actual[jsonName]
+	return v, ok
+}
+
 // matchBody applies FieldMatcher logic to the request body.
-func matchBody(expected map[string]runtime.FieldMatcher, actual map[string]interface{}) bool {
+// msg is the proto form of actual, or nil if unavailable.
+func matchBody(mc *matchContext, expected map[string]runtime.FieldMatcher, actual map[string]interface{}, msg proto.Message) bool {
 	for k, matcher := range expected {
-		v, ok := actual[k]
+		v, ok := lookupField(actual, k, msg)
+		if (matcher.IsSet || matcher.IsUnset) && !matchPresence(msg, k, matcher.IsSet) {
+			return false
+		}
+		if matcher.Absent || matcher.IsUnset {
+			if matcher.Absent && !isAbsent(msg, k, v, ok) {
+				return false
+			}
+			continue
+		}
 		if !ok {
 			return false
 		}
-		if !matchField(matcher, v) {
+		if !matchField(mc.withValue(fieldValue(msg, k)), matcher, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// withValue returns the match context for a value whose proto form is p.
+func (mc *matchContext) withValue(p *protoValue) *matchContext {
+	if p == nil && mc.value == nil {
+		return mc
+	}
+	c := *mc
+	c.value = p
+	return &c
+}
+
+// matchRequest applies a RequestMatcher, including its nested combinations, to the call.
+func matchRequest(mc *matchContext, rm *runtime.RequestMatcher) bool {
+	mc, rm = withoutIgnoredFields(mc, rm)
+	if rm.Headers != nil && !matchHeaders(rm.Headers, mc.headers) {
+		return false
+	}
+	if mc.marshalError != "" && (rm.Body != nil || len(rm.BodySchema) > 0) {
+		return false
+	}
+	if rm.Body != nil && !matchBody(mc, rm.Body, mc.body, mc.msg) {
+		return false
+	}
+	if len(rm.BodySchema) > 0 && len(validateSchema(rm.BodySchema, mc.body)) > 0 {
+		return false
+	}
+	if !matchBodySha256(mc, rm.BodySha256) {
+		return false
+	}
+	if !matchDeadline(mc, rm) {
+		return false
+	}
+	return matchComposite(mc, rm)
+}
+
+// matchBodySha256 compares the request's hash with the expected one, ignoring case.
+func matchBodySha256(mc *matchContext, expected string) bool {
+	return expected == "" || (mc.bodySha256 != "" && strings.EqualFold(mc.bodySha256, expected))
+}
+
+// matchDeadline checks the caller's remaining deadline against MinDeadlineMs and MaxDeadlineMs.
+func matchDeadline(mc *matchContext, rm *runtime.RequestMatcher) bool {
+	if rm.MinDeadlineMs == 0 && rm.MaxDeadlineMs == 0 {
+		return true
+	}
+	if mc.deadline.IsZero() {
+		return rm.MaxDeadlineMs == 0
+	}
+	remaining := time.Until(mc.deadline).Milliseconds()
+	if rm.MinDeadlineMs != 0 && remaining < rm.MinDeadlineMs {
+		return false
+	}
+	if rm.MaxDeadlineMs != 0 && remaining > rm.MaxDeadlineMs {
+		return false
+	}
+	return true
+}
+
+// matchComposite applies the AllOf, AnyOf and Not combinations of a RequestMatcher.
+func matchComposite(mc *matchContext, rm *runtime.RequestMatcher) bool {
+	for i := range rm.AllOf {
+		if !matchRequest(mc, &rm.AllOf[i]) {
+			return false
+		}
+	}
+	if len(rm.AnyOf) > 0 {
+		matched := false
+		for i := range rm.AnyOf {
+			if matchRequest(mc, &rm.AnyOf[i]) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
 			return false
 		}
 	}
+	if rm.Not != nil && matchRequest(mc, rm.Not) {
+		return false
+	}
 	return true
 }
 
 // Matcher provides expectation matching using a storeInterface.
 type Matcher struct {
-	Store       storeInterface
-	matchCounts map[string]int // key: expectation hash or index
+	Store storeInterface
 }
 
-// New creates a new Matcher with the given store.
+// New creates a new Matcher with the given store. If the store accepts
+// expectation validators, expectations with invalid matchers are rejected as
+// they are registered.
 func New(store storeInterface) *Matcher {
-	return &Matcher{Store: store, matchCounts: make(map[string]int)}
+	m := &Matcher{Store: store}
+	if v, ok := store.(interface {
+		AddValidator(validate func(*runtime.GRPCCallExpectation) error)
+	}); ok {
+		v.AddValidator(m.Validate)
+	}
+	return m
 }
 
 // FindMatchingExpectation finds an expectation that matches the given gRPC call details.
@@ -149,66 +388,304 @@ func (m *Matcher) FindMatchingExpectation(
 	headers metadata.MD,
 	reqBodyProto proto.Message,
 ) *runtime.GRPCCallExpectation {
-	expectations := m.Store.GetExpectations()
+	exp, _ := m.FindMatchingExpectationContext(context.Background(), fullMethodName, headers, reqBodyProto)
+	return exp
+}
+
+// FindMatchingExpectationContext is like FindMatchingExpectation, additionally
+// matching attributes of the call's context such as its deadline. It also returns
+// the named capture groups of the matching expectation's regexes.
+func (m *Matcher) FindMatchingExpectationContext(
+	ctx context.Context,
+	fullMethodName string,
+	headers metadata.MD,
+	reqBodyProto proto.Message,
+) (*runtime.GRPCCallExpectation, map[string]string) {
+	reqBodyJSONBytes, actualBodyMap, marshalErr := marshalBody(fullMethodName, reqBodyProto)
+	mc := &matchContext{now: m.Store.Clock().Now(), headers: headers, body: actualBodyMap, msg: reqBodyProto, bodySha256: runtime.BodySha256(reqBodyProto), marshalError: marshalErr, vars: m.Store.GetVars()}
+	mc.deadline, _ = ctx.Deadline()
+	return m.find(mc, fullMethodName, reqBodyJSONBytes)
+}
 
+// FindMatchingStreamExpectation finds an expectation matching the messages received
+// on a client stream. RequestMatcher applies to the first message and the
+// expectation's StreamMock matchers to the whole sequence. Capture groups are
+// returned as by FindMatchingExpectationContext.
+func (m *Matcher) FindMatchingStreamExpectation(
+	ctx context.Context,
+	fullMethodName string,
+	headers metadata.MD,
+	reqs []proto.Message,
+) (*runtime.GRPCCallExpectation, map[string]string) {
+	var first proto.Message
+	if len(reqs) > 0 {
+		first = reqs[0]
+	}
+	reqBodyJSONBytes, actualBodyMap, marshalErr := marshalBody(fullMethodName, first)
+	mc := &matchContext{now: m.Store.Clock().Now(), headers: headers, body: actualBodyMap, msg: first, protoMessages: reqs, bodySha256: runtime.BodySha256(first), marshalError: marshalErr, vars: m.Store.GetVars()}
+	mc.deadline, _ = ctx.Deadline()
+	mc.messages = make([]map[string]interface{}, 0, len(reqs))
+	for _, req := range reqs {
+		_, msg, _ := marshalBody(fullMethodName, req)
+		mc.messages = append(mc.messages, msg)
+	}
+	return m.find(mc, fullMethodName, reqBodyJSONBytes)
+}
+
+// FindMatchingSendFirstExpectation finds, as a bidirectional call starts and
+// before any client message, an expectation whose stream script starts by
+// sending, so that it does not wait for a message the client may only send once
+// answered. Only the expectations matching without a message are considered,
+// see sendsFirst. It returns nil if none matches, without recording the call as
+// a near miss; the call is then matched on its first message as usual.
+func (m *Matcher) FindMatchingSendFirstExpectation(
+	ctx context.Context,
+	fullMethodName string,
+	headers metadata.MD,
+) (*runtime.GRPCCallExpectation, map[string]string) {
+	mc := &matchContext{now: m.Store.Clock().Now(), headers: headers, vars: m.Store.GetVars()}
+	mc.deadline, _ = ctx.Deadline()
+	exp, groups, _ := m.findAmong(mc, fullMethodName, sendsFirst)
+	return exp, groups
+}
+
+// MatchMessage reports whether a single message, received with the given
+// headers on the call with the given context, satisfies a RequestMatcher.
+func (m *Matcher) MatchMessage(ctx context.Context, headers metadata.MD, rm *runtime.RequestMatcher, msg proto.Message) bool {
+	_, body, marshalErr := marshalBody("", msg)
+	mc := &matchContext{now: m.Store.Clock().Now(), headers: headers, body: body, msg: msg, bodySha256: runtime.BodySha256(msg), marshalError: marshalErr, vars: m.Store.GetVars()}
+	mc.deadline, _ = ctx.Deadline()
+	return matchRequest(mc, rm)
+}
+
+// marshalBody returns the protojson form of a request, raw and decoded. If it
+// cannot be marshalled, the body is empty and the error is returned.
+func marshalBody(fullMethodName string, reqBodyProto proto.Message) ([]byte, map[string]interface{}, string) {
 	reqBodyJSONBytes := []byte("{}") // Default to empty JSON if reqBodyProto is nil or marshalling fails
+	var marshalErr string
 	if reqBodyProto != nil {
-		var err error
-		reqBodyJSONBytes, err = storage.DefaultMarshaler.Marshal(reqBodyProto) // Directly use reqBodyProto
+		bytes, err := storage.DefaultMarshaler.Marshal(reqBodyProto) // Directly use reqBodyProto
 		if err != nil {
 			log.Printf("grpcmockruntime: error marshalling request body to JSON for matching call '%s': %v", fullMethodName, err)
-			// Proceed with an empty JSON representation of the body on error.
-			reqBodyJSONBytes = []byte(`{"error_marshalling_request_body": "true"}`)
+			marshalErr = err.Error()
+		} else {
+			reqBodyJSONBytes = bytes
 		}
 	}
 
 	var actualBodyMap map[string]interface{}
 	_ = json.Unmarshal(reqBodyJSONBytes, &actualBodyMap)
+	return reqBodyJSONBytes, actualBodyMap, marshalErr
+}
 
-	for idx, exp := range expectations[fullMethodName] {
-		if exp.RequestMatcher == nil {
-			if m.checkTimes(fullMethodName, idx, &exp) {
-				m.incrementMatch(fullMethodName, idx)
-				return &exp
-			}
-			continue
+// find returns the first expectation of the method, in match order, accepting
+// the call, along with the named capture groups of its regexes. Service
+// wildcard expectations are only tried if no expectation of the method accepts
+// it, and default expectations if no other does.
+func (m *Matcher) find(mc *matchContext, fullMethodName string, reqBodyJSONBytes []byte) (*runtime.GRPCCallExpectation, map[string]string) {
+	exp, groups, tried := m.findAmong(mc, fullMethodName, nil)
+	if exp == nil {
+		m.recordNearMisses(mc, fullMethodName, reqBodyJSONBytes, tried)
+	}
+	return exp, groups
+}
+
+// findAmong is find restricted to the expectations accepted by keep, or all of
+// them if keep is nil, without recording near misses. It also returns the
+// candidates it tried.
+func (m *Matcher) findAmong(mc *matchContext, fullMethodName string, keep func(*runtime.GRPCCallExpectation) bool) (*runtime.GRPCCallExpectation, map[string]string, []candidate) {
+	expectations := m.Store.GetExpectations()
+	candidates := candidatesFor(expectations, fullMethodName)
+	wildcards := wildcardCandidates(expectations, fullMethodName)
+	if keep != nil {
+		drop := func(c candidate) bool { return !keep(&c.exp) }
+		candidates = slices.DeleteFunc(candidates, drop)
+		wildcards = slices.DeleteFunc(wildcards, drop)
+	}
+	regular, defaults := splitDefaults(candidates)
+	regularWildcards, wildcardDefaults := splitDefaults(wildcards)
+	for _, group := range [][]candidate{regular, regularWildcards, defaults, wildcardDefaults} {
+		if c, n, ok := m.claimCandidate(mc, group); ok {
+			c.exp.Response = c.exp.ResponseFor(n)
+			groups := map[string]string{}
+			captures(c.mapRequest(mc), c.exp.RequestMatcher, groups)
+			return c.mapResponse(&c.exp), groups, nil
 		}
-		if exp.RequestMatcher.Headers != nil && !matchHeaders(exp.RequestMatcher.Headers, headers) {
-			continue
+	}
+	return nil, nil, append(candidates, wildcards...)
+}
+
+// claimCandidate returns the first candidate accepting the call whose match the
+// store confirms, along with its new match count. A candidate refused by the
+// store, because a concurrent call took its last match for example, is skipped.
+func (m *Matcher) claimCandidate(mc *matchContext, candidates []candidate) (candidate, int, bool) {
+	for len(candidates) > 0 {
+		c, ok := m.firstCandidate(mc, candidates)
+		if !ok {
+			break
 		}
-		if exp.RequestMatcher.Body != nil && !matchBody(exp.RequestMatcher.Body, actualBodyMap) {
-			continue
+		if n, ok := m.Store.ClaimMatch(&c.exp); ok {
+			return c, n, true
 		}
-		if m.checkTimes(fullMethodName, idx, &exp) {
-			m.incrementMatch(fullMethodName, idx)
-			return &exp
+		candidates = slices.DeleteFunc(slices.Clone(candidates), func(o candidate) bool {
+			return o.exp.ID == c.exp.ID
+		})
+	}
+	return candidate{}, 0, false
+}
+
+// splitDefaults separates the regular candidates from the default ones.
+func splitDefaults(candidates []candidate) (regular, defaults []candidate) {
+	for _, c := range candidates {
+		if c.exp.Default {
+			defaults = append(defaults, c)
+		} else {
+			regular = append(regular, c)
 		}
 	}
-	return nil
+	return regular, defaults
 }
 
-// checkTimes checks if the expectation can be matched again based on its Times field.
-func (m *Matcher) checkTimes(fullMethod string, idx int, exp *runtime.GRPCCallExpectation) bool {
-	key := fmt.Sprintf("%s#%d", fullMethod, idx)
-	count := m.matchCounts[key]
-	if exp.Times == nil {
-		return true
+// firstCandidate returns the first candidate, in match order, accepting the call.
+func (m *Matcher) firstCandidate(mc *matchContext, candidates []candidate) (candidate, bool) {
+	exps := make([]runtime.GRPCCallExpectation, len(candidates))
+	for i, c := range candidates {
+		exps[i] = c.exp
 	}
-	if exp.Times.Exact > 0 && count >= exp.Times.Exact {
+	order := matchOrder(exps)
+	pos := firstAccepting(len(order), func(pos int) bool { return m.accepts(mc, &candidates[order[pos]]) })
+	if pos < 0 {
+		return candidate{}, false
+	}
+	return candidates[order[pos]], true
+}
+
+// accepts reports whether a candidate expectation can serve the call.
+func (m *Matcher) accepts(mc *matchContext, c *candidate) bool {
+	exp := &c.exp
+	cmc := c.mapRequest(mc)
+	if !exp.Schedule.Active(cmc.now) || !m.inScenarioState(exp) || !m.activated(exp.ActiveWhen) || !m.observed(exp.After) {
 		return false
 	}
-	if exp.Times.Max > 0 && count >= exp.Times.Max {
+	if !matchAuthority(exp.Authority, cmc.headers) {
 		return false
 	}
-	return true
+	if exp.RequestMatcher != nil && !matchRequest(cmc, exp.RequestMatcher) {
+		return false
+	}
+	if !matchStream(cmc, exp.Stream) {
+		return false
+	}
+	return m.checkTimes(exp)
+}
+
+// matchOrder returns the indexes of the expectations in the order they should be tried:
+// by descending priority, then descending specificity, then registration order.
+func matchOrder(expectations []runtime.GRPCCallExpectation) []int {
+	order := make([]int, len(expectations))
+	scores := make([]int, len(expectations))
+	for i := range expectations {
+		order[i] = i
+		scores[i] = specificity(&expectations[i])
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		ea, eb := &expectations[order[a]], &expectations[order[b]]
+		if ea.Priority != eb.Priority {
+			return ea.Priority > eb.Priority
+		}
+		return scores[order[a]] > scores[order[b]]
+	})
+	return order
 }
 
-func (m *Matcher) incrementMatch(fullMethod string, idx int) {
-	key := fmt.Sprintf("%s#%d", fullMethod, idx)
-	m.matchCounts[key]++
+// specificity scores an expectation by the number of constraints its authority and request matcher set.
+func specificity(exp *runtime.GRPCCallExpectation) int {
+	n := constraintCount(exp.RequestMatcher) + streamConstraintCount(exp.Stream)
+	if exp.Authority != "" {
+		n++
+	}
+	return n
+}
+
+// constraintCount counts the header, body, schema and deadline constraints of a RequestMatcher, including nested ones.
+func constraintCount(rm *runtime.RequestMatcher) int {
+	if rm == nil {
+		return 0
+	}
+	n := len(rm.Headers) + len(rm.Body) + constraintCount(rm.Not)
+	if len(rm.BodySchema) > 0 {
+		n++
+	}
+	if rm.BodySha256 != "" {
+		n++
+	}
+	if rm.MinDeadlineMs != 0 || rm.MaxDeadlineMs != 0 {
+		n++
+	}
+	for i := range rm.AllOf {
+		n += constraintCount(&rm.AllOf[i])
+	}
+	for i := range rm.AnyOf {
+		n += constraintCount(&rm.AnyOf[i])
+	}
+	return n
+}
+
+// inScenarioState reports whether the expectation's scenario is in its required state.
+func (m *Matcher) inScenarioState(exp *runtime.GRPCCallExpectation) bool {
+	return exp.Scenario == "" || exp.InScenarioState(m.Store.ScenarioState(exp.Scenario))
+}
+
+// activated reports whether the expectation an ActiveWhen refers to has matched enough calls.
+func (m *Matcher) activated(aw *runtime.ActiveWhen) bool {
+	if aw == nil {
+		return true
+	}
+	count, _ := m.Store.MatchCountByID(aw.ExpectationID)
+	return aw.Satisfied(count)
+}
+
+// observed reports whether a recorded call satisfying an After precondition has
+// been served, see servedBefore. The call being matched has no response yet, so
+// it never satisfies its own precondition.
+func (m *Matcher) observed(after *runtime.After) bool {
+	if after == nil {
+		return true
+	}
+	if after.RequestMatcher == nil {
+		return m.Store.FindAnsweredCall(after.FullMethodName, after.ExpectationID, func(call runtime.RecordedGRPCCall) bool {
+			return servedBefore(after, &call)
+		})
+	}
+	vars := m.Store.GetVars()
+	return m.Store.FindAnsweredCall(after.FullMethodName, after.ExpectationID, func(call runtime.RecordedGRPCCall) bool {
+		if !servedBefore(after, &call) {
+			return false
+		}
+		var body map[string]interface{}
+		_ = json.Unmarshal(call.Body, &body)
+		mc := &matchContext{now: time.Unix(0, call.Timestamp), headers: call.Headers, body: body, bodySha256: call.BodySha256, marshalError: call.MarshalError, vars: vars}
+		return matchRequest(mc, after.RequestMatcher)
+	})
+}
+
+// servedBefore reports whether an answered call counts for an After
+// precondition: it must have been served by an expectation, so unmatched and
+// overridden calls do not count, and, unless the precondition names the
+// expectation, have succeeded, as a failed call did not change the backend's state.
+func servedBefore(after *runtime.After, call *runtime.RecordedGRPCCall) bool {
+	if !call.Response.Matched {
+		return false
+	}
+	return after.ExpectationID != "" || call.Response.StatusCode == codes.OK
+}
+
+// checkTimes checks if the expectation can be matched again based on its Times field.
+func (m *Matcher) checkTimes(exp *runtime.GRPCCallExpectation) bool {
+	return exp.Times.Allows(m.Store.MatchCount(exp.ID))
 }
 
 // GetMatchCounts returns the current match counts for all expectations.
 func (m *Matcher) GetMatchCounts() map[string]int {
-	return m.matchCounts
+	return m.Store.GetMatchCounts()
 }
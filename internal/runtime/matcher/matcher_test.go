@@ -0,0 +1,70 @@
+package matcher
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+	"github.com/rbroggi/grpcmock/internal/runtime/storage"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const testMethod = "/pkg.v1.Svc/Get"
+
+// TestFindClaimsConcurrently checks that concurrent calls matching through the
+// store's ClaimMatch never exceed an expectation's Times, the calls beyond it
+// falling through to the next expectation.
+func TestFindClaimsConcurrently(t *testing.T) {
+	tests := []struct {
+		name    string
+		limited runtime.ExpectationTimes
+		calls   int
+		want    int
+	}{
+		{name: "exact", limited: runtime.ExpectationTimes{Exact: 5}, calls: 50, want: 5},
+		{name: "max", limited: runtime.ExpectationTimes{Max: 1}, calls: 50, want: 1},
+		{name: "fewer calls than allowed", limited: runtime.ExpectationTimes{Max: 100}, calls: 20, want: 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := storage.New()
+			m := New(s)
+			limited, err := s.AddExpectation(runtime.GRPCCallExpectation{
+				FullMethodName: testMethod, Priority: 1, Times: &tt.limited, Response: &runtime.MockResponse{},
+			})
+			if err != nil {
+				t.Fatalf("AddExpectation: %v", err)
+			}
+			fallback, err := s.AddExpectation(runtime.GRPCCallExpectation{FullMethodName: testMethod, Response: &runtime.MockResponse{}})
+			if err != nil {
+				t.Fatalf("AddExpectation: %v", err)
+			}
+
+			served := make(map[string]int)
+			var mu sync.Mutex
+			var wg sync.WaitGroup
+			for i := 0; i < tt.calls; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					exp, _ := m.FindMatchingExpectationContext(context.Background(), testMethod, nil, wrapperspb.String("x"))
+					if exp == nil {
+						t.Error("no expectation matched")
+						return
+					}
+					mu.Lock()
+					served[exp.ID]++
+					mu.Unlock()
+				}()
+			}
+			wg.Wait()
+			if served[limited] != tt.want || served[fallback] != tt.calls-tt.want {
+				t.Errorf("served %v, want %d by %s and %d by %s", served, tt.want, limited, tt.calls-tt.want, fallback)
+			}
+			if n := s.MatchCount(limited); n != tt.want {
+				t.Errorf("MatchCount = %d, want %d", n, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package matcher
+
+import (
+	"log"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+)
+
+// matchMoney compares an amount with a MoneyMatcher. Invalid expected amounts are
+// logged and never match.
+func matchMoney(m runtime.MoneyMatcher, value interface{}) bool {
+	amount, currency, err := runtime.ParseDecimal(value)
+	if err != nil {
+		return false
+	}
+	if m.Currency != "" && currency != m.Currency {
+		return false
+	}
+	for _, bound := range []struct {
+		expected string
+		ok       func(cmp int) bool
+	}{
+		{m.Equals, func(cmp int) bool { return cmp == 0 }},
+		{m.Min, func(cmp int) bool { return cmp >= 0 }},
+		{m.Max, func(cmp int) bool { return cmp <= 0 }},
+	} {
+		if bound.expected == "" {
+			continue
+		}
+		expected, _, err := runtime.ParseDecimal(bound.expected)
+		if err != nil {
+			log.Printf("grpcmockruntime: invalid money matcher amount: %v", err)
+			return false
+		}
+		if !bound.ok(amount.Cmp(expected)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,82 @@
+package matcher
+
+import (
+	"log"
+	"strings"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// normalizationForms maps the NormalizeUnicode values to their forms.
+var normalizationForms = map[string]norm.Form{
+	"NFC":  norm.NFC,
+	"NFD":  norm.NFD,
+	"NFKC": norm.NFKC,
+	"NFKD": norm.NFKD,
+}
+
+// normalizeStrings applies the CaseInsensitive and NormalizeUnicode options of a
+// matcher to the strings of its expected values and of the actual value, so the
+// string matchers compare them as the options require. ok is false if the
+// normalization form is unknown.
+func normalizeStrings(matcher runtime.FieldMatcher, value interface{}) (_ runtime.FieldMatcher, _ interface{}, ok bool) {
+	if !matcher.CaseInsensitive && matcher.NormalizeUnicode == "" {
+		return matcher, value, true
+	}
+	var transforms []func(string) string
+	if matcher.NormalizeUnicode != "" {
+		form, ok := normalizationForms[strings.ToUpper(matcher.NormalizeUnicode)]
+		if !ok {
+			log.Printf("grpcmockruntime: unknown normalizeUnicode form %q", matcher.NormalizeUnicode)
+			return matcher, value, false
+		}
+		transforms = append(transforms, form.String)
+	}
+	if matcher.CaseInsensitive {
+		transforms = append(transforms, cases.Fold().String)
+		if matcher.Regex != "" {
+			matcher.Regex = "(?i)" + matcher.Regex
+		}
+		if matcher.NotRegex != "" {
+			matcher.NotRegex = "(?i)" + matcher.NotRegex
+		}
+	}
+	transform := func(s string) string {
+		for _, t := range transforms {
+			s = t(s)
+		}
+		return s
+	}
+	matcher.Equals = mapStrings(matcher.Equals, transform)
+	matcher.NotEquals = mapStrings(matcher.NotEquals, transform)
+	matcher.Contains = mapStrings(matcher.Contains, transform)
+	return matcher, mapStrings(value, transform), true
+}
+
+// mapStrings returns a copy of a decoded JSON value with f applied to its
+// strings, at any nesting depth. Placeholders are left untouched.
+func mapStrings(v interface{}, f func(string) string) interface{} {
+	switch t := v.(type) {
+	case string:
+		if _, ok := placeholders[t]; ok {
+			return t
+		}
+		return f(t)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, elem := range t {
+			out[k] = mapStrings(elem, f)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, elem := range t {
+			out[i] = mapStrings(elem, f)
+		}
+		return out
+	default:
+		return v
+	}
+}
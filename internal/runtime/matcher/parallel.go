@@ -0,0 +1,65 @@
+package matcher
+
+import (
+	goruntime "runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// parallelThreshold is the number of candidates from which they are evaluated in parallel.
+const parallelThreshold = 256
+
+// parallelChunk is the number of consecutive candidates a worker evaluates at a time.
+const parallelChunk = 32
+
+// firstAccepting returns the lowest position in [0, n) that accepts the call, or -1.
+// Large candidate sets are split among GOMAXPROCS workers taking chunks in match
+// order; positions after the best one found so far are skipped, so the result is
+// the one sequential evaluation would give.
+func firstAccepting(n int, accepts func(pos int) bool) int {
+	workers := goruntime.GOMAXPROCS(0)
+	if n < parallelThreshold || workers < 2 {
+		for pos := 0; pos < n; pos++ {
+			if accepts(pos) {
+				return pos
+			}
+		}
+		return -1
+	}
+
+	var best atomic.Int64
+	best.Store(int64(n))
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				start := int(next.Add(parallelChunk)) - parallelChunk
+				if start >= n || int64(start) >= best.Load() {
+					return
+				}
+				for pos := start; pos < start+parallelChunk && pos < n; pos++ {
+					if int64(pos) >= best.Load() {
+						return
+					}
+					if accepts(pos) {
+						for {
+							cur := best.Load()
+							if int64(pos) >= cur || best.CompareAndSwap(cur, int64(pos)) {
+								break
+							}
+						}
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if found := best.Load(); found < int64(n) {
+		return int(found)
+	}
+	return -1
+}
@@ -0,0 +1,135 @@
+package matcher
+
+import (
+	"context"
+	"fmt"
+	goruntime "runtime"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+	"github.com/rbroggi/grpcmock/internal/runtime/storage"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestFirstAccepting(t *testing.T) {
+	if goruntime.GOMAXPROCS(0) < 2 {
+		prev := goruntime.GOMAXPROCS(4)
+		t.Cleanup(func() { goruntime.GOMAXPROCS(prev) })
+	}
+	tests := []struct {
+		name      string
+		n         int
+		accepting []int // Positions accepting the call
+		want      int
+	}{
+		{name: "sequential none", n: 10, want: -1},
+		{name: "sequential first", n: 10, accepting: []int{3, 7}, want: 3},
+		{name: "parallel none", n: parallelThreshold, want: -1},
+		{name: "parallel first position", n: parallelThreshold, accepting: []int{0, 200}, want: 0},
+		{name: "parallel last position", n: parallelThreshold, accepting: []int{parallelThreshold - 1}, want: parallelThreshold - 1},
+		{name: "parallel earliest of several chunks", n: 4096, accepting: []int{4000, 1500, 700, 701, 3000}, want: 700},
+		{name: "parallel chunk boundary", n: 1024, accepting: []int{parallelChunk, parallelChunk - 1}, want: parallelChunk - 1},
+		{name: "parallel all", n: 1024, accepting: allPositions(1024), want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accepting := make(map[int]bool, len(tt.accepting))
+			for _, pos := range tt.accepting {
+				accepting[pos] = true
+			}
+			var calls atomic.Int64
+			got := firstAccepting(tt.n, func(pos int) bool {
+				if pos < 0 || pos >= tt.n {
+					t.Errorf("accepts called with position %d out of [0, %d)", pos, tt.n)
+				}
+				calls.Add(1)
+				return accepting[pos]
+			})
+			if got != tt.want {
+				t.Errorf("firstAccepting = %d, want %d", got, tt.want)
+			}
+			if tt.want < 0 && calls.Load() != int64(tt.n) {
+				t.Errorf("accepts called %d times, want every position once (%d)", calls.Load(), tt.n)
+			}
+		})
+	}
+}
+
+// TestFirstAcceptingRepeated runs the parallel evaluation many times so that
+// races between workers finding candidates show up.
+func TestFirstAcceptingRepeated(t *testing.T) {
+	const n = 2048
+	for i := 0; i < 200; i++ {
+		want := (i * 37) % n
+		got := firstAccepting(n, func(pos int) bool { return pos >= want && pos%3 == want%3 })
+		if got != want {
+			t.Fatalf("run %d: firstAccepting = %d, want %d", i, got, want)
+		}
+	}
+}
+
+// TestFindAmongManyCandidates checks that a method with more expectations than
+// parallelThreshold is matched in the same order as sequential evaluation would.
+func TestFindAmongManyCandidates(t *testing.T) {
+	if goruntime.GOMAXPROCS(0) < 2 {
+		prev := goruntime.GOMAXPROCS(4)
+		t.Cleanup(func() { goruntime.GOMAXPROCS(prev) })
+	}
+	const n = parallelThreshold + 44
+	s := storage.New()
+	m := New(s)
+	add := func(value string, priority int) string {
+		id, err := s.AddExpectation(runtime.GRPCCallExpectation{
+			FullMethodName: testMethod,
+			Priority:       priority,
+			RequestMatcher: &runtime.RequestMatcher{Body: map[string]runtime.FieldMatcher{"value": {Equals: value}}},
+			Response:       &runtime.MockResponse{},
+		})
+		if err != nil {
+			t.Fatalf("AddExpectation: %v", err)
+		}
+		return id
+	}
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = add(fmt.Sprintf("v%d", i), 0)
+	}
+	add("v10", 0)                // Registered after the first one for v10
+	prioritized := add("v20", 1) // Tried before the first one for v20
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "v0", want: ids[0]},
+		{value: "v10", want: ids[10]},
+		{value: "v20", want: prioritized},
+		{value: "v255", want: ids[255]},
+		{value: fmt.Sprintf("v%d", n-1), want: ids[n-1]},
+		{value: "none", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			req, err := structpb.NewStruct(map[string]interface{}{"value": tt.value})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got string
+			if exp, _ := m.FindMatchingExpectationContext(context.Background(), testMethod, nil, req); exp != nil {
+				got = exp.ID
+			}
+			if got != tt.want {
+				t.Errorf("matched %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func allPositions(n int) []int {
+	positions := make([]int, n)
+	for i := range positions {
+		positions[i] = i
+	}
+	return positions
+}
@@ -0,0 +1,110 @@
+package matcher
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// placeholders maps the supported "${any-...}" placeholders to the values they accept.
+var placeholders = map[string]func(actual interface{}) bool{
+	"${any}": func(actual interface{}) bool { return actual != nil },
+	"${any-string}": func(actual interface{}) bool {
+		_, ok := actual.(string)
+		return ok
+	},
+	"${any-number}": func(actual interface{}) bool {
+		_, ok := toFloat64(actual)
+		return ok
+	},
+	"${any-boolean}": func(actual interface{}) bool {
+		_, ok := actual.(bool)
+		return ok
+	},
+	"${any-uuid}": func(actual interface{}) bool {
+		s, ok := actual.(string)
+		return ok && uuidPattern.MatchString(s)
+	},
+}
+
+// placeholderKinds maps the placeholders to the JSON kind they accept; "${any}" accepts several.
+var placeholderKinds = map[string]string{
+	"${any-string}":  "string",
+	"${any-number}":  "number",
+	"${any-boolean}": "boolean",
+	"${any-uuid}":    "string",
+}
+
+// deepCompare reports whether actual equals expected, where string values in
+// expected that are placeholders such as "${any-string}" match any value of
+// the corresponding type, and "${var:name}" the current value of a variable,
+// at any nesting depth. Array elements of expected that
+// are field matcher objects, e.g. {"regex": "^SKU-"}, match the elements they accept.
+func deepCompare(mc *matchContext, expected, actual interface{}) bool {
+	switch e := expected.(type) {
+	case string:
+		if accepts, ok := placeholders[e]; ok {
+			return accepts(actual)
+		}
+		if name, ok := varPlaceholder(e); ok {
+			return matchVar(mc, name, actual)
+		}
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok || len(a) != len(e) {
+			return false
+		}
+		for k, ev := range e {
+			av, ok := a[k]
+			if !ok || !deepCompare(mc.withValue(mc.value.child(k)), ev, av) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok || len(a) != len(e) {
+			return false
+		}
+		for i := range e {
+			elemContext := mc.withValue(mc.value.element(i))
+			if raw, ok := elementMatcher(e[i]); ok {
+				if !matchElement(elemContext, raw, a[i]) {
+					return false
+				}
+				continue
+			}
+			if !deepCompare(elemContext, e[i], a[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(expected, actual)
+}
+
+// varPlaceholder returns the variable name of a "${var:name}" placeholder.
+func varPlaceholder(s string) (string, bool) {
+	if !strings.HasPrefix(s, "${var:") || !strings.HasSuffix(s, "}") {
+		return "", false
+	}
+	return s[len("${var:") : len(s)-1], true
+}
+
+// matchVar reports whether actual, a string or a number, equals the value of a
+// variable. Unset variables match nothing.
+func matchVar(mc *matchContext, name string, actual interface{}) bool {
+	v, ok := mc.vars[name]
+	if !ok {
+		return false
+	}
+	if s, ok := actual.(string); ok {
+		return s == v
+	}
+	f, ok := toFloat64(actual)
+	expected, err := strconv.ParseFloat(v, 64)
+	return ok && err == nil && f == expected
+}
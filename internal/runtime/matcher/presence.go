@@ -0,0 +1,152 @@
+package matcher
+
+import (
+	"strconv"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// isSet reports whether the field with the given JSON or proto name is set on
+// msg, using proto presence semantics: explicit presence for proto3 optional,
+// message and oneof fields, non-default value for other scalars, non-empty for
+// repeated and map fields. Without msg, presence is unknown and it returns false.
+func isSet(msg proto.Message, name string) bool {
+	if msg == nil {
+		return false
+	}
+	m := msg.ProtoReflect()
+	fd := fieldByName(m.Descriptor(), name)
+	if fd == nil {
+		return false
+	}
+	return m.Has(fd)
+}
+
+// matchPresence applies the IsSet and IsUnset matchers to a field of msg; they
+// never match without msg, as the JSON form renders unset fields with defaults.
+func matchPresence(msg proto.Message, name string, wantSet bool) bool {
+	return msg != nil && isSet(msg, name) == wantSet
+}
+
+// isAbsent reports whether a field is missing from the request: unset on msg
+// as isSet judges it, or, without msg or for unknown names, missing or null in
+// the JSON form, where scalars are always rendered.
+func isAbsent(msg proto.Message, name string, jsonValue interface{}, found bool) bool {
+	if msg != nil && fieldByName(msg.ProtoReflect().Descriptor(), name) != nil {
+		return !isSet(msg, name)
+	}
+	return !found || jsonValue == nil
+}
+
+// fieldByName finds a field by its JSON name or its proto name.
+func fieldByName(md protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	if fd := md.Fields().ByJSONName(name); fd != nil {
+		return fd
+	}
+	return md.Fields().ByName(protoreflect.Name(name))
+}
+
+// protoValue is the proto form of a JSON value the matchers are applied to: a
+// field of a message, or an element of a repeated field if elem is set. It lets
+// presence checks follow nested messages.
+type protoValue struct {
+	fd   protoreflect.FieldDescriptor
+	v    protoreflect.Value
+	elem bool
+}
+
+// fieldValue returns the proto form of a field of msg, or nil if msg is nil or
+// has no such field.
+func fieldValue(msg proto.Message, name string) *protoValue {
+	if msg == nil {
+		return nil
+	}
+	m := msg.ProtoReflect()
+	fd := fieldByName(m.Descriptor(), name)
+	if fd == nil {
+		return nil
+	}
+	return &protoValue{fd: fd, v: m.Get(fd)}
+}
+
+// message returns the value as a message, or nil if it is not one.
+func (p *protoValue) message() proto.Message {
+	if p == nil || p.fd.Message() == nil || (!p.elem && (p.fd.IsList() || p.fd.IsMap())) {
+		return nil
+	}
+	return p.v.Message().Interface()
+}
+
+// element returns the i-th element of a repeated field, or nil.
+func (p *protoValue) element(i int) *protoValue {
+	if p == nil || p.elem || !p.fd.IsList() || i >= p.v.List().Len() {
+		return nil
+	}
+	return &protoValue{fd: p.fd, v: p.v.List().Get(i), elem: true}
+}
+
+// child returns the value of a JSON key of the value: a field of a message,
+// or the value of an entry of a map field. It returns nil if there is none.
+func (p *protoValue) child(key string) *protoValue {
+	if p == nil {
+		return nil
+	}
+	if !p.fd.IsMap() {
+		return fieldValue(p.message(), key)
+	}
+	mk, ok := mapKey(p.fd.MapKey(), key)
+	if !ok {
+		return nil
+	}
+	v := p.v.Map().Get(mk)
+	if !v.IsValid() {
+		return nil
+	}
+	return &protoValue{fd: p.fd.MapValue(), v: v}
+}
+
+// anyPayload returns the unpacked payload of a google.protobuf.Any value, or
+// nil if it is not one or its message type is not registered.
+func (p *protoValue) anyPayload() proto.Message {
+	msg := p.message()
+	if msg == nil || msg.ProtoReflect().Descriptor().FullName() != "google.protobuf.Any" {
+		return nil
+	}
+	m := msg.ProtoReflect()
+	fields := m.Descriptor().Fields()
+	mt, err := protoregistry.GlobalTypes.FindMessageByURL(m.Get(fields.ByName("type_url")).String())
+	if err != nil {
+		return nil
+	}
+	payload := mt.New().Interface()
+	if err := proto.Unmarshal(m.Get(fields.ByName("value")).Bytes(), payload); err != nil {
+		return nil
+	}
+	return payload
+}
+
+// mapKey converts the protojson form of a map key, always a string, to the key's kind.
+func mapKey(fd protoreflect.FieldDescriptor, key string) (protoreflect.MapKey, bool) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(key).MapKey(), true
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(key)
+		return protoreflect.ValueOfBool(b).MapKey(), err == nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := strconv.ParseInt(key, 10, 32)
+		return protoreflect.ValueOfInt32(int32(n)).MapKey(), err == nil
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := strconv.ParseInt(key, 10, 64)
+		return protoreflect.ValueOfInt64(n).MapKey(), err == nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := strconv.ParseUint(key, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(n)).MapKey(), err == nil
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := strconv.ParseUint(key, 10, 64)
+		return protoreflect.ValueOfUint64(n).MapKey(), err == nil
+	}
+	return protoreflect.MapKey{}, false
+}
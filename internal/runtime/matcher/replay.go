@@ -0,0 +1,53 @@
+package matcher
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+)
+
+// ReplayCheck evaluates a proposed expectation against the recorded calls to
+// its method (or the methods its wildcard or regex covers) and aliases, without registering it. Schedules are evaluated at
+// the time of each call; times, activeWhen and after constraints are not evaluated.
+func (m *Matcher) ReplayCheck(exp runtime.GRPCCallExpectation) runtime.ReplayCheckResult {
+	result := runtime.ReplayCheckResult{Calls: []runtime.ReplayedCall{}}
+	vars := m.Store.GetVars()
+	for _, call := range m.Store.GetRecordedCalls() {
+		c, ok := replayCandidate(exp, call.FullMethodName)
+		if !ok {
+			continue
+		}
+		var body map[string]interface{}
+		_ = json.Unmarshal(call.Body, &body)
+		mc := &matchContext{now: time.Unix(0, call.Timestamp), headers: call.Headers, body: body, bodySha256: call.BodySha256, marshalError: call.MarshalError, vars: vars}
+		replayed := runtime.ReplayedCall{CallID: call.ID, FullMethodName: call.FullMethodName}
+		if nearMiss := m.explain(mc, c, false); nearMiss.Reason != "" {
+			replayed.NearMiss = &nearMiss
+		} else {
+			replayed.Matched = true
+			result.MatchedCount++
+		}
+		result.Calls = append(result.Calls, replayed)
+	}
+	return result
+}
+
+// replayCandidate returns the proposed expectation as a candidate for a call to the
+// given method, directly, through its service wildcard or method regex, or through one of its aliases.
+func replayCandidate(exp runtime.GRPCCallExpectation, fullMethodName string) (candidate, bool) {
+	c := candidate{method: runtime.ExpectationKey(exp), exp: exp}
+	if exp.FullMethodNameRegex != "" {
+		return c, matchesRegex(exp.FullMethodNameRegex, fullMethodName)
+	}
+	if exp.FullMethodName == fullMethodName || exp.FullMethodName == runtime.ServiceWildcard(fullMethodName) {
+		return c, true
+	}
+	for i := range exp.Aliases {
+		if exp.Aliases[i].FullMethodName == fullMethodName {
+			c.alias = &exp.Aliases[i]
+			return c, true
+		}
+	}
+	return c, false
+}
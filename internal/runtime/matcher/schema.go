@@ -0,0 +1,289 @@
+package matcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"unicode/utf8"
+)
+
+// validateSchema validates a JSON value against a JSON Schema document and
+// returns the violations found. It supports the commonly used validation
+// keywords: type, enum, const, properties, required, additionalProperties,
+// items, minItems, maxItems, minLength, maxLength, pattern, minimum, maximum,
+// exclusiveMinimum, exclusiveMaximum, allOf, anyOf, oneOf and not.
+// References ($ref) and formats are not supported.
+func validateSchema(schema json.RawMessage, value interface{}) []string {
+	var s interface{}
+	if err := json.Unmarshal(schema, &s); err != nil {
+		return []string{fmt.Sprintf("invalid schema: %v", err)}
+	}
+	return validateValue(s, value, "$")
+}
+
+// schemaTypes are the type names of JSON Schema.
+var schemaTypes = map[string]bool{"null": true, "boolean": true, "object": true, "array": true, "number": true, "string": true, "integer": true}
+
+// checkSchema checks that a JSON Schema document only uses the keywords
+// validateSchema supports with values of the expected types, so a mistyped
+// schema is reported instead of accepting or rejecting every body.
+func checkSchema(schema json.RawMessage) error {
+	var s interface{}
+	if err := json.Unmarshal(schema, &s); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return checkSchemaValue(s, "$")
+}
+
+func checkSchemaValue(schema interface{}, path string) error {
+	if _, ok := schema.(bool); ok {
+		return nil
+	}
+	s, ok := schema.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s: a schema must be an object or a boolean", path)
+	}
+	if _, ok := s["$ref"]; ok {
+		return fmt.Errorf("%s: $ref is not supported", path)
+	}
+	if t, ok := s["type"]; ok {
+		names, isList := t.([]interface{})
+		if !isList {
+			names = []interface{}{t}
+		}
+		for _, name := range names {
+			if n, _ := name.(string); !schemaTypes[n] {
+				return fmt.Errorf("%s.type: unknown type %v", path, name)
+			}
+		}
+	}
+	if e, ok := s["enum"]; ok {
+		if _, ok := e.([]interface{}); !ok {
+			return fmt.Errorf("%s.enum: must be an array", path)
+		}
+	}
+	for _, keyword := range []string{"minItems", "maxItems", "minLength", "maxLength", "minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum"} {
+		if n, ok := s[keyword]; ok {
+			if _, ok := n.(float64); !ok {
+				return fmt.Errorf("%s.%s: must be a number", path, keyword)
+			}
+		}
+	}
+	if p, ok := s["pattern"]; ok {
+		pattern, ok := p.(string)
+		if !ok {
+			return fmt.Errorf("%s.pattern: must be a string", path)
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("%s.pattern: %w", path, err)
+		}
+	}
+	if r, ok := s["required"]; ok {
+		required, ok := r.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s.required: must be an array of property names", path)
+		}
+		for _, name := range required {
+			if _, ok := name.(string); !ok {
+				return fmt.Errorf("%s.required: must be an array of property names", path)
+			}
+		}
+	}
+	if p, ok := s["properties"]; ok {
+		props, ok := p.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s.properties: must be an object", path)
+		}
+		for name, sub := range props {
+			if err := checkSchemaValue(sub, path+".properties."+name); err != nil {
+				return err
+			}
+		}
+	}
+	for _, keyword := range []string{"additionalProperties", "items", "not"} {
+		if sub, ok := s[keyword]; ok {
+			if err := checkSchemaValue(sub, path+"."+keyword); err != nil {
+				return err
+			}
+		}
+	}
+	for _, keyword := range []string{"allOf", "anyOf", "oneOf"} {
+		if l, ok := s[keyword]; ok {
+			subs, ok := l.([]interface{})
+			if !ok {
+				return fmt.Errorf("%s.%s: must be an array of schemas", path, keyword)
+			}
+			for i, sub := range subs {
+				if err := checkSchemaValue(sub, fmt.Sprintf("%s.%s[%d]", path, keyword, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func validateValue(schema interface{}, value interface{}, path string) []string {
+	switch s := schema.(type) {
+	case bool:
+		if !s {
+			return []string{fmt.Sprintf("%s: not allowed by schema", path)}
+		}
+		return nil
+	case map[string]interface{}:
+		return validateObjectSchema(s, value, path)
+	default:
+		return []string{fmt.Sprintf("%s: invalid schema", path)}
+	}
+}
+
+func validateObjectSchema(s map[string]interface{}, value interface{}, path string) []string {
+	var errs []string
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	if t, ok := s["type"]; ok && !matchesSchemaType(t, value) {
+		fail("expected type %v, got %s", t, jsonKind(value))
+		return errs
+	}
+	if enum, ok := s["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if reflect.DeepEqual(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("value %v not in enum %v", value, enum)
+		}
+	}
+	if c, ok := s["const"]; ok && !reflect.DeepEqual(c, value) {
+		fail("value %v does not equal const %v", value, c)
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		errs = append(errs, validateProperties(s, v, path)...)
+	case []interface{}:
+		if n, ok := s["minItems"].(float64); ok && float64(len(v)) < n {
+			fail("expected at least %v items, got %d", n, len(v))
+		}
+		if n, ok := s["maxItems"].(float64); ok && float64(len(v)) > n {
+			fail("expected at most %v items, got %d", n, len(v))
+		}
+		if items, ok := s["items"]; ok {
+			for i, item := range v {
+				errs = append(errs, validateValue(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(v))
+		if n, ok := s["minLength"].(float64); ok && length < n {
+			fail("expected at least %v characters, got %v", n, length)
+		}
+		if n, ok := s["maxLength"].(float64); ok && length > n {
+			fail("expected at most %v characters, got %v", n, length)
+		}
+		if p, ok := s["pattern"].(string); ok {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				fail("invalid pattern %q: %v", p, err)
+			} else if !re.MatchString(v) {
+				fail("value %q does not match pattern %q", v, p)
+			}
+		}
+	case float64:
+		if n, ok := s["minimum"].(float64); ok && v < n {
+			fail("value %v is less than minimum %v", v, n)
+		}
+		if n, ok := s["maximum"].(float64); ok && v > n {
+			fail("value %v is greater than maximum %v", v, n)
+		}
+		if n, ok := s["exclusiveMinimum"].(float64); ok && v <= n {
+			fail("value %v is not greater than %v", v, n)
+		}
+		if n, ok := s["exclusiveMaximum"].(float64); ok && v >= n {
+			fail("value %v is not less than %v", v, n)
+		}
+	}
+
+	if all, ok := s["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			errs = append(errs, validateValue(sub, value, path)...)
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]interface{}); ok && countValid(anyOf, value, path) == 0 {
+		fail("value does not match any schema in anyOf")
+	}
+	if oneOf, ok := s["oneOf"].([]interface{}); ok {
+		if n := countValid(oneOf, value, path); n != 1 {
+			fail("value matches %d schemas in oneOf, expected exactly 1", n)
+		}
+	}
+	if not, ok := s["not"]; ok && len(validateValue(not, value, path)) == 0 {
+		fail("value must not match the schema in not")
+	}
+	return errs
+}
+
+// validateProperties applies the object keywords of a schema to an object value.
+func validateProperties(s map[string]interface{}, v map[string]interface{}, path string) []string {
+	var errs []string
+	if required, ok := s["required"].([]interface{}); ok {
+		for _, r := range required {
+			name, _ := r.(string)
+			if _, present := v[name]; !present {
+				errs = append(errs, fmt.Sprintf("%s: missing required property %q", path, name))
+			}
+		}
+	}
+	props, _ := s["properties"].(map[string]interface{})
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if sub, ok := props[k]; ok {
+			errs = append(errs, validateValue(sub, v[k], path+"."+k)...)
+		} else if additional, ok := s["additionalProperties"]; ok {
+			errs = append(errs, validateValue(additional, v[k], path+"."+k)...)
+		}
+	}
+	return errs
+}
+
+func countValid(schemas []interface{}, value interface{}, path string) int {
+	n := 0
+	for _, sub := range schemas {
+		if len(validateValue(sub, value, path)) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// matchesSchemaType checks a value against a schema "type" (a name or a list of names).
+func matchesSchemaType(t interface{}, value interface{}) bool {
+	switch tt := t.(type) {
+	case string:
+		kind := jsonKind(value)
+		if tt == "integer" {
+			f, ok := value.(float64)
+			return ok && f == math.Trunc(f)
+		}
+		return kind == tt
+	case []interface{}:
+		for _, one := range tt {
+			if matchesSchemaType(one, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package matcher
+
+import "github.com/rbroggi/grpcmock/internal/runtime"
+
+// matchStream applies the StreamMock matchers to the messages of a client stream.
+// Calls that are not client streams are not constrained.
+func matchStream(mc *matchContext, sm *runtime.StreamMock) bool {
+	if sm == nil || mc.messages == nil {
+		return true
+	}
+	if len(sm.Script) > 0 && sm.Script[0].ExpectRequest != nil && len(mc.messages) > 0 &&
+		!matchMessage(mc, sm.Script[0].ExpectRequest, 0) {
+		return false
+	}
+	if !matchMessageCount(sm.MessageCount, len(mc.messages)) {
+		return false
+	}
+	if len(mc.messages) < len(sm.ExpectedRequests) {
+		return false
+	}
+	for i := range sm.ExpectedRequests {
+		if !matchMessage(mc, &sm.ExpectedRequests[i], i) {
+			return false
+		}
+	}
+	if sm.EveryMessage != nil {
+		for i := range mc.messages {
+			if !matchMessage(mc, sm.EveryMessage, i) {
+				return false
+			}
+		}
+	}
+	if sm.SomeMessage != nil {
+		matched := false
+		for i := range mc.messages {
+			if matchMessage(mc, sm.SomeMessage, i) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// matchMessage applies a RequestMatcher to the i-th message of a stream.
+func matchMessage(mc *matchContext, rm *runtime.RequestMatcher, i int) bool {
+	msgContext := *mc
+	msgContext.body = mc.messages[i]
+	msgContext.msg = nil
+	if i < len(mc.protoMessages) {
+		msgContext.msg = mc.protoMessages[i]
+	}
+	return matchRequest(&msgContext, rm)
+}
+
+// matchMessageCount checks the number of messages against Exact, Min and Max bounds.
+func matchMessageCount(bounds *runtime.ExpectationTimes, n int) bool {
+	if bounds == nil {
+		return true
+	}
+	if bounds.Exact > 0 && n != bounds.Exact {
+		return false
+	}
+	if bounds.Min > 0 && n < bounds.Min {
+		return false
+	}
+	if bounds.Max > 0 && n > bounds.Max {
+		return false
+	}
+	return true
+}
+
+// streamConstraintCount counts the message constraints of a StreamMock.
+func streamConstraintCount(sm *runtime.StreamMock) int {
+	if sm == nil {
+		return 0
+	}
+	n := constraintCount(sm.EveryMessage) + constraintCount(sm.SomeMessage)
+	for i := range sm.ExpectedRequests {
+		n += constraintCount(&sm.ExpectedRequests[i])
+	}
+	if sm.MessageCount != nil {
+		n++
+	}
+	if len(sm.Script) > 0 {
+		n += constraintCount(sm.Script[0].ExpectRequest)
+	}
+	return n
+}
+
+// sendsFirst reports whether an expectation's stream script starts by sending
+// and the expectation can be selected without a client message: neither its
+// request matcher nor its stream matchers read the messages.
+func sendsFirst(exp *runtime.GRPCCallExpectation) bool {
+	sm := exp.Stream
+	if sm == nil || len(sm.Script) == 0 || sm.Script[0].ExpectRequest != nil {
+		return false
+	}
+	if sm.MessageCount != nil || len(sm.ExpectedRequests) > 0 || sm.EveryMessage != nil || sm.SomeMessage != nil {
+		return false
+	}
+	return !readsBody(exp.RequestMatcher)
+}
+
+// readsBody reports whether a request matcher, or one nested in it, reads the request message.
+func readsBody(rm *runtime.RequestMatcher) bool {
+	if rm == nil {
+		return false
+	}
+	if len(rm.Body) > 0 || len(rm.BodySchema) > 0 || rm.BodySha256 != "" {
+		return true
+	}
+	for i := range rm.AllOf {
+		if readsBody(&rm.AllOf[i]) {
+			return true
+		}
+	}
+	for i := range rm.AnyOf {
+		if readsBody(&rm.AnyOf[i]) {
+			return true
+		}
+	}
+	return readsBody(rm.Not)
+}
@@ -0,0 +1,191 @@
+package matcher
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+)
+
+// Validate checks the request matchers of an expectation as it is registered,
+// rejecting the ones that could never apply as written.
+func (m *Matcher) Validate(exp *runtime.GRPCCallExpectation) error {
+	var v validator
+	for _, alias := range exp.Aliases {
+		if len(alias.FieldMapping) > 0 {
+			v.noMessage = "the calls to aliases with a fieldMapping are matched on their remapped JSON form"
+		}
+	}
+	if exp.RequestMatcher != nil {
+		if err := v.validateRequestMatcher("requestMatcher", exp.RequestMatcher); err != nil {
+			return err
+		}
+	}
+	if exp.After != nil && exp.After.RequestMatcher != nil {
+		recorded := validator{noMessage: "recorded calls are matched on their JSON form"}
+		if err := recorded.validateRequestMatcher("after.requestMatcher", exp.After.RequestMatcher); err != nil {
+			return err
+		}
+	}
+	if s := exp.Stream; s != nil {
+		for i := range s.ExpectedRequests {
+			if err := v.validateRequestMatcher(fmt.Sprintf("stream.expectedRequests[%d]", i), &s.ExpectedRequests[i]); err != nil {
+				return err
+			}
+		}
+		if s.EveryMessage != nil {
+			if err := v.validateRequestMatcher("stream.everyMessage", s.EveryMessage); err != nil {
+				return err
+			}
+		}
+		if s.SomeMessage != nil {
+			if err := v.validateRequestMatcher("stream.someMessage", s.SomeMessage); err != nil {
+				return err
+			}
+		}
+		for i, step := range s.Script {
+			if step.ExpectRequest != nil {
+				if err := v.validateRequestMatcher(fmt.Sprintf("stream.script[%d].expectRequest", i), step.ExpectRequest); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validator checks matchers; noMessage, if set, says why the request message
+// they are applied to is unavailable, which presence matchers need.
+type validator struct {
+	noMessage string
+}
+
+// validateRequestMatcher checks a request matcher and the matchers nested in it.
+func (v validator) validateRequestMatcher(path string, rm *runtime.RequestMatcher) error {
+	if err := v.validateFields(path+".body", rm.Body); err != nil {
+		return err
+	}
+	if len(rm.BodySchema) > 0 {
+		if err := checkSchema(rm.BodySchema); err != nil {
+			return fmt.Errorf("%s.bodySchema: %w", path, err)
+		}
+	}
+	for i := range rm.AllOf {
+		if err := v.validateRequestMatcher(fmt.Sprintf("%s.allOf[%d]", path, i), &rm.AllOf[i]); err != nil {
+			return err
+		}
+	}
+	for i := range rm.AnyOf {
+		if err := v.validateRequestMatcher(fmt.Sprintf("%s.anyOf[%d]", path, i), &rm.AnyOf[i]); err != nil {
+			return err
+		}
+	}
+	if rm.Not != nil {
+		return v.validateRequestMatcher(path+".not", rm.Not)
+	}
+	return nil
+}
+
+// validateFields checks the field matchers of a message.
+func (v validator) validateFields(path string, fields map[string]runtime.FieldMatcher) error {
+	for name, fm := range fields {
+		if err := v.validateField(path+"."+name, fm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateField checks a field matcher and the matchers nested in it.
+func (v validator) validateField(path string, fm runtime.FieldMatcher) error {
+	if (fm.IsSet || fm.IsUnset) && v.noMessage != "" {
+		return fmt.Errorf("%s: isSet and isUnset cannot be used as %s", path, v.noMessage)
+	}
+	if _, ok := normalizationForms[strings.ToUpper(fm.NormalizeUnicode)]; fm.NormalizeUnicode != "" && !ok {
+		return fmt.Errorf("%s: unknown normalizeUnicode form %q, expected NFC, NFD, NFKC or NFKD", path, fm.NormalizeUnicode)
+	}
+	if _, ok := formats[strings.ToLower(fm.Format)]; fm.Format != "" && !ok {
+		return fmt.Errorf("%s: unknown format %q", path, fm.Format)
+	}
+	if fm.Custom != "" && !customMatcherRegistered(fm.Custom) {
+		return fmt.Errorf("%s: unknown custom matcher %q, register it with RegisterMatcher first", path, fm.Custom)
+	}
+	if m := fm.Money; m != nil {
+		for _, bound := range []struct{ name, amount string }{{"equals", m.Equals}, {"min", m.Min}, {"max", m.Max}} {
+			if _, _, err := runtime.ParseDecimal(bound.amount); bound.amount != "" && err != nil {
+				return fmt.Errorf("%s.money.%s: %w", path, bound.name, err)
+			}
+		}
+	}
+	if err := v.validateElements(path+".equals", fm.Equals); err != nil {
+		return err
+	}
+	if err := v.validateElements(path+".notEquals", fm.NotEquals); err != nil {
+		return err
+	}
+	if err := v.validateFields(path+".fields", fm.Fields); err != nil {
+		return err
+	}
+	for _, nested := range []struct {
+		name string
+		fm   *runtime.FieldMatcher
+	}{
+		{"arrayContaining", fm.ArrayContaining},
+		{"length", fm.Length},
+	} {
+		if nested.fm != nil {
+			if err := v.validateField(path+"."+nested.name, *nested.fm); err != nil {
+				return err
+			}
+		}
+	}
+	if mc := fm.MapContaining; mc != nil {
+		if mc.Key != nil {
+			if err := v.validateField(path+".mapContaining.key", *mc.Key); err != nil {
+				return err
+			}
+		}
+		if mc.Value != nil {
+			if err := v.validateField(path+".mapContaining.value", *mc.Value); err != nil {
+				return err
+			}
+		}
+	}
+	if fm.Any != nil {
+		return v.validateFields(path+".any.body", fm.Any.Body)
+	}
+	return nil
+}
+
+// validateElements checks the elements of the arrays in an equals value marked
+// as field matchers, at any nesting depth.
+func (v validator) validateElements(path string, expected interface{}) error {
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		for k, elem := range e {
+			if err := v.validateElements(path+"."+k, elem); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, elem := range e {
+			elemPath := path + "[" + strconv.Itoa(i) + "]"
+			raw, ok := elementMatcher(elem)
+			if !ok {
+				if err := v.validateElements(elemPath, elem); err != nil {
+					return err
+				}
+				continue
+			}
+			fm, err := decodeElementMatcher(raw)
+			if err != nil {
+				return fmt.Errorf("%s: invalid %s matcher: %w", elemPath, elementMatcherKey, err)
+			}
+			if err := v.validateField(elemPath, fm); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
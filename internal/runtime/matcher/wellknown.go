@@ -0,0 +1,98 @@
+package matcher
+
+import (
+	"log"
+	"time"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+)
+
+// matchTimestamp applies the Timestamp matchers to the RFC3339 string protojson
+// renders for a google.protobuf.Timestamp.
+func matchTimestamp(matcher runtime.FieldMatcher, value interface{}, now time.Time) bool {
+	if matcher.Before == "" && matcher.After == "" && matcher.Within == "" {
+		return true
+	}
+	str, ok := value.(string)
+	if !ok {
+		return false
+	}
+	ts, err := time.Parse(time.RFC3339Nano, str)
+	if err != nil {
+		return false
+	}
+	if matcher.Before != "" {
+		bound, ok := parseTimeBound(matcher.Before, now)
+		if !ok || !ts.Before(bound) {
+			return false
+		}
+	}
+	if matcher.After != "" {
+		bound, ok := parseTimeBound(matcher.After, now)
+		if !ok || !ts.After(bound) {
+			return false
+		}
+	}
+	if matcher.Within != "" {
+		within, ok := parseDuration(matcher.Within)
+		if !ok {
+			return false
+		}
+		delta := ts.Sub(now)
+		if delta < -within || delta > within {
+			return false
+		}
+	}
+	return true
+}
+
+// matchDuration applies the Duration matchers to the string protojson renders
+// for a google.protobuf.Duration, e.g. "1.500s".
+func matchDuration(matcher runtime.FieldMatcher, value interface{}) bool {
+	if matcher.LessThan == "" && matcher.GreaterThan == "" {
+		return true
+	}
+	str, ok := value.(string)
+	if !ok {
+		return false
+	}
+	d, err := time.ParseDuration(str)
+	if err != nil {
+		return false
+	}
+	if matcher.LessThan != "" {
+		bound, ok := parseDuration(matcher.LessThan)
+		if !ok || d >= bound {
+			return false
+		}
+	}
+	if matcher.GreaterThan != "" {
+		bound, ok := parseDuration(matcher.GreaterThan)
+		if !ok || d <= bound {
+			return false
+		}
+	}
+	return true
+}
+
+// parseTimeBound parses an RFC3339 time or the literal "now".
+func parseTimeBound(s string, now time.Time) (time.Time, bool) {
+	if s == "now" {
+		return now, true
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		log.Printf("grpcmockruntime: invalid time '%s' in matcher: %v", s, err)
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func parseDuration(s string) (time.Duration, bool) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		log.Printf("grpcmockruntime: invalid duration '%s' in matcher: %v", s, err)
+		return 0, false
+	}
+	return d, true
+}
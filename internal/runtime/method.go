@@ -0,0 +1,29 @@
+package runtime
+
+import "strings"
+
+// ServiceWildcard returns the wildcard method name matching every method of
+// the service of fullMethodName, e.g. "/pkg.Svc/*" for "/pkg.Svc/Get".
+func ServiceWildcard(fullMethodName string) string {
+	i := strings.LastIndex(fullMethodName, "/")
+	if i <= 0 {
+		return ""
+	}
+	return fullMethodName[:i+1] + "*"
+}
+
+// IsServiceWildcard reports whether a method name is a service wildcard such as "/pkg.Svc/*".
+func IsServiceWildcard(fullMethodName string) bool {
+	service, ok := strings.CutSuffix(fullMethodName, "/*")
+	return ok && strings.HasPrefix(service, "/") && len(service) > 1 && !strings.ContainsAny(service[1:], "/*")
+}
+
+// ExpectationKey returns the key an expectation is stored and listed under:
+// its method name, or its FullMethodNameRegex pattern. Whether an expectation
+// applies to a pattern is told by its FullMethodNameRegex, not by its key.
+func ExpectationKey(exp GRPCCallExpectation) string {
+	if exp.FullMethodNameRegex != "" {
+		return exp.FullMethodNameRegex
+	}
+	return exp.FullMethodName
+}
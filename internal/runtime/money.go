@@ -0,0 +1,99 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// nanosPerUnit is the number of google.type.Money nanos in one unit.
+const nanosPerUnit = 1_000_000_000
+
+// ParseDecimal parses an amount exactly, avoiding floating-point rounding. v is
+// a decimal string such as "-12.34", a JSON number, or the JSON form of a
+// google.type.Money, whose currency code is returned as well.
+func ParseDecimal(v interface{}) (amount *big.Rat, currency string, err error) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		return parseMoney(t)
+	case string:
+		return parseDecimalString(t)
+	case json.Number:
+		return parseDecimalString(t.String())
+	case float64:
+		return parseDecimalString(strconv.FormatFloat(t, 'f', -1, 64))
+	case int:
+		return new(big.Rat).SetInt64(int64(t)), "", nil
+	case int64:
+		return new(big.Rat).SetInt64(t), "", nil
+	default:
+		return nil, "", fmt.Errorf("%v is not a decimal amount", v)
+	}
+}
+
+// parseDecimalString parses a decimal string; fractions such as "1/3" are refused.
+func parseDecimalString(s string) (*big.Rat, string, error) {
+	s = strings.TrimSpace(s)
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || strings.Contains(s, "/") {
+		return nil, "", fmt.Errorf("%q is not a decimal amount", s)
+	}
+	return r, "", nil
+}
+
+// parseMoney parses the JSON form of a google.type.Money, under its JSON or proto field names.
+func parseMoney(m map[string]interface{}) (*big.Rat, string, error) {
+	currency, _ := moneyField(m, "currencyCode", "currency_code").(string)
+	amount := new(big.Rat)
+	for _, part := range []struct {
+		value interface{}
+		scale int64
+	}{{moneyField(m, "units"), 1}, {moneyField(m, "nanos"), nanosPerUnit}} {
+		if part.value == nil {
+			continue
+		}
+		r, _, err := ParseDecimal(part.value)
+		if err != nil || !r.IsInt() {
+			return nil, "", fmt.Errorf("invalid google.type.Money %v", m)
+		}
+		amount.Add(amount, r.Quo(r, new(big.Rat).SetInt64(part.scale)))
+	}
+	return amount, currency, nil
+}
+
+// moneyField returns the first of the named fields present in m.
+func moneyField(m map[string]interface{}, names ...string) interface{} {
+	for _, name := range names {
+		if v, ok := m[name]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// FormatDecimal formats an amount as a decimal string without trailing zeros,
+// rounded to 18 decimal places.
+func FormatDecimal(r *big.Rat) string {
+	s := r.FloatString(18)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// MoneyParts splits an amount into the units and nanos of a google.type.Money,
+// rounded to the nearest nano, halves away from zero. Both have the amount's sign.
+func MoneyParts(r *big.Rat) (units int64, nanos int32, err error) {
+	scaled, ok := new(big.Int).SetString(new(big.Rat).Mul(r, big.NewRat(nanosPerUnit, 1)).FloatString(0), 10)
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid amount %v", r)
+	}
+	u, n := new(big.Int).QuoRem(scaled, big.NewInt(nanosPerUnit), new(big.Int))
+	if !u.IsInt64() {
+		return 0, 0, fmt.Errorf("amount %s overflows google.type.Money units", FormatDecimal(r))
+	}
+	return u.Int64(), int32(n.Int64()), nil
+}
@@ -0,0 +1,60 @@
+package runtime
+
+import (
+	"errors"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// ErrQuotaExceeded is returned when storing more data would exceed the store's quotas.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// ErrExpectationNotFound is returned when no expectation has the given ID.
+var ErrExpectationNotFound = errors.New("expectation not found")
+
+// NamespaceHeader is the metadata key naming the namespace of a call.
+const NamespaceHeader = "grpcmock-namespace"
+
+// DefaultNamespace is the namespace of calls and expectations that name none.
+const DefaultNamespace = "default"
+
+// Quotas caps what the store keeps in memory for each namespace, so one
+// misbehaving test suite cannot exhaust a shared mock instance. Zero means unlimited.
+type Quotas struct {
+	MaxExpectations  int `json:"maxExpectations,omitempty"`
+	MaxRecordedCalls int `json:"maxRecordedCalls,omitempty"` // Applies to recorded and unmatched calls separately
+}
+
+// QuotaUsage reports the store's quotas, current usage and rejections, in
+// total and for each namespace.
+type QuotaUsage struct {
+	Quotas Quotas `json:"quotas"`
+	NamespaceUsage
+	Namespaces map[string]NamespaceUsage `json:"namespaces,omitempty"`
+}
+
+// NamespaceUsage is the usage and rejections of a namespace, or of all of them.
+type NamespaceUsage struct {
+	Expectations         int `json:"expectations"`
+	RecordedCalls        int `json:"recordedCalls"`
+	UnmatchedCalls       int `json:"unmatchedCalls"`
+	RejectedExpectations int `json:"rejectedExpectations"` // Expectations refused since startup
+	DroppedCalls         int `json:"droppedCalls"`         // Calls rejected or not recorded since startup
+	DiscardedCalls       int `json:"discardedCalls"`       // Calls discarded by the retention policy since startup
+}
+
+// Namespace returns the namespace named by the NamespaceHeader of a call.
+func Namespace(headers metadata.MD) string {
+	if v := firstHeader(headers, NamespaceHeader); v != "" {
+		return v
+	}
+	return DefaultNamespace
+}
+
+// QuotaNamespace returns the namespace whose quotas count the expectation.
+func (e *GRPCCallExpectation) QuotaNamespace() string {
+	if e.Namespace != "" {
+		return e.Namespace
+	}
+	return DefaultNamespace
+}
@@ -0,0 +1,46 @@
+package runtime
+
+import (
+	"encoding/json"
+	"time"
+
+	"google.golang.org/grpc/status"
+)
+
+// NewRecordedResponse describes the answer to a call: the matched expectation
+// (nil if none), its rendered response (nil if not rendered), the error returned
+// to the client and the time taken to answer.
+func NewRecordedResponse(exp *GRPCCallExpectation, resp *MockResponse, err error, latency time.Duration) *RecordedResponse {
+	st := status.Convert(err)
+	recorded := &RecordedResponse{
+		Matched:       exp != nil,
+		StatusCode:    st.Code(),
+		StatusMessage: st.Message(),
+		LatencyMs:     float64(latency) / float64(time.Millisecond),
+	}
+	if exp != nil {
+		recorded.ExpectationID = exp.ID
+	}
+	if resp != nil {
+		recorded.Headers = resp.Headers
+		if err == nil {
+			if len(resp.Bodies) > 0 {
+				recorded.Bodies = resp.Bodies
+			} else {
+				recorded.Body = resp.Body
+			}
+		}
+	}
+	return recorded
+}
+
+// NewOverriddenResponse describes the answer of an override function to a call:
+// body is the JSON form of the unary response, nil for streams.
+func NewOverriddenResponse(body json.RawMessage, err error, latency time.Duration) *RecordedResponse {
+	recorded := NewRecordedResponse(nil, nil, err, latency)
+	recorded.Overridden = true
+	if err == nil {
+		recorded.Body = body
+	}
+	return recorded
+}
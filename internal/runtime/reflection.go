@@ -0,0 +1,38 @@
+package runtime
+
+import "strings"
+
+// Reflection selects the methods advertised by the gRPC reflection service, so
+// clients that feature-detect via reflection can be tested against the capability
+// sets of older server versions. Hidden methods are still served.
+type Reflection struct {
+	Methods []string `json:"methods"` // Advertised full method names; nil advertises all
+}
+
+// Advertises reports whether a method is advertised.
+func (r Reflection) Advertises(fullMethodName string) bool {
+	if r.Methods == nil {
+		return true
+	}
+	for _, m := range r.Methods {
+		if m == fullMethodName {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseReflectionMethods parses a comma-separated list of advertised full method
+// names; an empty list advertises all methods.
+func ParseReflectionMethods(s string) []string {
+	if s == "" {
+		return nil
+	}
+	methods := []string{}
+	for _, m := range strings.Split(s, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			methods = append(methods, m)
+		}
+	}
+	return methods
+}
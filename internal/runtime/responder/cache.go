@@ -0,0 +1,71 @@
+package responder
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// maxCachedResponses bounds the rendered response cache; it is reset when full.
+const maxCachedResponses = 4096
+
+// renderCache holds rendered responses keyed by expectation and request content.
+type renderCache struct {
+	mu      sync.Mutex
+	entries map[string]runtime.MockResponse
+}
+
+func newRenderCache() *renderCache {
+	return &renderCache{entries: make(map[string]runtime.MockResponse)}
+}
+
+func (c *renderCache) get(key string) (runtime.MockResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	resp, ok := c.entries[key]
+	return resp, ok
+}
+
+func (c *renderCache) put(key string, resp runtime.MockResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= maxCachedResponses {
+		c.entries = make(map[string]runtime.MockResponse)
+	}
+	c.entries[key] = resp
+}
+
+// cacheKey identifies a rendering by the expectation's content and the request's
+// deterministic wire encoding, so changed expectations never hit stale entries.
+// Capture groups are included as they may come from headers, and headers, nil
+// unless the templates read them, as they are.
+func cacheKey(fullMethodName string, exp *runtime.GRPCCallExpectation, reqBodyProto proto.Message, headers metadata.MD, matches map[string]string) (string, error) {
+	h := sha256.New()
+	h.Write([]byte(fullMethodName))
+	expJSON, err := json.Marshal(exp)
+	if err != nil {
+		return "", err
+	}
+	h.Write(expJSON)
+	callJSON, err := json.Marshal(struct {
+		Headers metadata.MD
+		Matches map[string]string
+	}{headers, matches})
+	if err != nil {
+		return "", err
+	}
+	h.Write(callJSON)
+	if reqBodyProto != nil {
+		reqBytes, err := proto.MarshalOptions{Deterministic: true}.Marshal(reqBodyProto)
+		if err != nil {
+			return "", err
+		}
+		h.Write(reqBytes)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,189 @@
+package responder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+	"github.com/rbroggi/grpcmock/internal/runtime/storage"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const testMethod = "/pkg.v1.Svc/Get"
+
+// testCall is a call rendered against an expectation.
+type testCall struct {
+	exp     int // Index of the expectation in the test's expectations
+	value   string
+	headers metadata.MD
+	matches map[string]string
+}
+
+func TestRenderCachedKeying(t *testing.T) {
+	tests := []struct {
+		name        string
+		bodies      []string // Response bodies of the expectations, registered in order
+		noCache     bool
+		calls       []testCall
+		wantEntries int
+		wantBodies  []string
+	}{
+		{
+			name:        "identical requests share an entry",
+			bodies:      []string{`{"value": "{{.Request.value}}"}`},
+			calls:       []testCall{{value: "a"}, {value: "a"}},
+			wantEntries: 1,
+			wantBodies:  []string{`{"value":"a"}`, `{"value":"a"}`},
+		},
+		{
+			name:        "different requests",
+			bodies:      []string{`{"value": "{{.Request.value}}"}`},
+			calls:       []testCall{{value: "a"}, {value: "b"}},
+			wantEntries: 2,
+			wantBodies:  []string{`{"value":"a"}`, `{"value":"b"}`},
+		},
+		{
+			name:   "unread headers are not part of the key",
+			bodies: []string{`{"value": "{{.Request.value}}"}`},
+			calls: []testCall{
+				{value: "a", headers: metadata.Pairs("x-user", "1")},
+				{value: "a", headers: metadata.Pairs("x-user", "2")},
+			},
+			wantEntries: 1,
+			wantBodies:  []string{`{"value":"a"}`, `{"value":"a"}`},
+		},
+		{
+			name:   "read headers are part of the key",
+			bodies: []string{`{"value": "{{index .Headers \"x-user\" 0}}"}`},
+			calls: []testCall{
+				{value: "a", headers: metadata.Pairs("x-user", "1")},
+				{value: "a", headers: metadata.Pairs("x-user", "2")},
+			},
+			wantEntries: 2,
+			wantBodies:  []string{`{"value":"1"}`, `{"value":"2"}`},
+		},
+		{
+			name:   "capture groups are part of the key",
+			bodies: []string{`{"value": "{{.Matches.id}}"}`},
+			calls: []testCall{
+				{value: "a", matches: map[string]string{"id": "1"}},
+				{value: "a", matches: map[string]string{"id": "2"}},
+			},
+			wantEntries: 2,
+			wantBodies:  []string{`{"value":"1"}`, `{"value":"2"}`},
+		},
+		{
+			name:        "expectations have their own entries",
+			bodies:      []string{`{"value": "first {{.Request.value}}"}`, `{"value": "second {{.Request.value}}"}`},
+			calls:       []testCall{{exp: 0, value: "a"}, {exp: 1, value: "a"}},
+			wantEntries: 2,
+			wantBodies:  []string{`{"value":"first a"}`, `{"value":"second a"}`},
+		},
+		{
+			name:        "volatile templates are not cached",
+			bodies:      []string{`{"value": "{{fakeUUID}}"}`},
+			calls:       []testCall{{value: "a"}, {value: "a"}},
+			wantEntries: 0,
+		},
+		{
+			name:        "not cached unless asked",
+			bodies:      []string{`{"value": "{{.Request.value}}"}`},
+			noCache:     true,
+			calls:       []testCall{{value: "a"}, {value: "a"}},
+			wantEntries: 0,
+			wantBodies:  []string{`{"value":"a"}`, `{"value":"a"}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := storage.New()
+			r := New(s)
+			exps := make([]*runtime.GRPCCallExpectation, len(tt.bodies))
+			for i, body := range tt.bodies {
+				id, err := s.AddExpectation(runtime.GRPCCallExpectation{
+					FullMethodName: testMethod,
+					Response:       &runtime.MockResponse{Body: json.RawMessage(body), CacheRendered: !tt.noCache},
+				})
+				if err != nil {
+					t.Fatalf("AddExpectation: %v", err)
+				}
+				exps[i] = storedExpectation(t, s, id)
+			}
+			for i, call := range tt.calls {
+				req, err := structpb.NewStruct(map[string]interface{}{"value": call.value})
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp, err := r.Render(context.Background(), testMethod, exps[call.exp], req, call.headers, call.matches)
+				if err != nil {
+					t.Fatalf("Render: %v", err)
+				}
+				if tt.wantBodies != nil && string(resp.Body) != tt.wantBodies[i] {
+					t.Errorf("call %d: body = %s, want %s", i, resp.Body, tt.wantBodies[i])
+				}
+			}
+			if n := len(r.cache.entries); n != tt.wantEntries {
+				t.Errorf("cache holds %d entries, want %d", n, tt.wantEntries)
+			}
+		})
+	}
+}
+
+// TestRenderCachedConcurrent checks that concurrent calls get the response
+// rendered for their own request, whether cached or not.
+func TestRenderCachedConcurrent(t *testing.T) {
+	const values, callers = 4, 64
+	s := storage.New()
+	r := New(s)
+	id, err := s.AddExpectation(runtime.GRPCCallExpectation{
+		FullMethodName: testMethod,
+		Response:       &runtime.MockResponse{Body: json.RawMessage(`{"value": "{{.Request.value}}"}`), CacheRendered: true},
+	})
+	if err != nil {
+		t.Fatalf("AddExpectation: %v", err)
+	}
+	exp := storedExpectation(t, s, id)
+
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value := fmt.Sprintf("v%d", i%values)
+			req, err := structpb.NewStruct(map[string]interface{}{"value": value})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp, err := r.Render(context.Background(), testMethod, exp, req, nil, nil)
+			if err != nil {
+				t.Errorf("Render: %v", err)
+				return
+			}
+			if want := fmt.Sprintf(`{"value":%q}`, value); string(resp.Body) != want {
+				t.Errorf("body = %s, want %s", resp.Body, want)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := len(r.cache.entries); n != values {
+		t.Errorf("cache holds %d entries, want %d", n, values)
+	}
+}
+
+// storedExpectation returns the expectation with the given ID as stored,
+// holding its compiled response.
+func storedExpectation(t *testing.T, s *storage.Store, id string) *runtime.GRPCCallExpectation {
+	t.Helper()
+	for _, exp := range s.GetExpectations()[testMethod] {
+		if exp.ID == id {
+			return &exp
+		}
+	}
+	t.Fatalf("expectation %s not found", id)
+	return nil
+}
@@ -0,0 +1,52 @@
+package responder
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// streamData aggregates the messages received on a client stream, available to
+// response templates as .Stream.
+type streamData struct {
+	Count    int                      // Number of messages received
+	Messages []map[string]interface{} // protojson form of the messages, in order
+	Last     map[string]interface{}   // protojson form of the last message
+}
+
+// newStreamData aggregates the messages of a client stream.
+func newStreamData(reqs []proto.Message) (streamData, error) {
+	data := streamData{Count: len(reqs), Messages: make([]map[string]interface{}, 0, len(reqs))}
+	for i, req := range reqs {
+		msg, err := requestJSON(req)
+		if err != nil {
+			return streamData{}, fmt.Errorf("message %d: %w", i, err)
+		}
+		data.Messages = append(data.Messages, msg)
+	}
+	if len(data.Messages) > 0 {
+		data.Last = data.Messages[len(data.Messages)-1]
+	}
+	return data, nil
+}
+
+// RenderStream is Render for client-streaming calls: .Request is the first
+// message received, and .Stream aggregates all of them (.Stream.Count,
+// .Stream.Messages and .Stream.Last).
+func (r *Responder) RenderStream(
+	ctx context.Context,
+	fullMethodName string,
+	exp *runtime.GRPCCallExpectation,
+	reqs []proto.Message,
+	headers metadata.MD,
+	matches map[string]string,
+) (*runtime.MockResponse, error) {
+	var first proto.Message
+	if len(reqs) > 0 {
+		first = reqs[0]
+	}
+	return r.renderCall(ctx, fullMethodName, exp, first, reqs, headers, matches)
+}
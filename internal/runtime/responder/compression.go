@@ -0,0 +1,21 @@
+package responder
+
+import (
+	"context"
+	"log"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+	"google.golang.org/grpc"
+)
+
+// Compress makes the call's response messages use the response's Compression
+// instead of the compressor of the request. It must be called before the headers
+// are sent. If the client does not accept the compressor, the default is kept.
+func (r *Responder) Compress(ctx context.Context, fullMethodName string, resp *runtime.MockResponse) {
+	if resp.Compression == "" {
+		return
+	}
+	if err := grpc.SetSendCompressor(ctx, resp.Compression); err != nil {
+		log.Printf("grpcmockruntime: cannot compress the response of %s with %s: %v", fullMethodName, resp.Compression, err)
+	}
+}
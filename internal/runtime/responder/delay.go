@@ -0,0 +1,49 @@
+package responder
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// deadlineMargin is how long responses exceeding the client's deadline are held past it.
+const deadlineMargin = 50 * time.Millisecond
+
+// Delay waits for the response's delay on the store's clock. If the call ends
+// first, e.g. because its deadline expired, it returns the matching status error.
+// Responses exceeding the client's deadline wait for the deadline instead, plus
+// a margin, and return DEADLINE_EXCEEDED unless the client cancels the call first.
+func (r *Responder) Delay(ctx context.Context, resp *runtime.MockResponse) error {
+	if resp.ExceedClientDeadline {
+		if deadline, ok := ctx.Deadline(); ok {
+			clock := r.Store.Clock()
+			held := clock.After(deadline.Sub(clock.Now()) + deadlineMargin)
+			select {
+			case <-held:
+			case <-ctx.Done():
+				// Clients cancel calls at their own deadline, slightly ahead of
+				// the server's, so only earlier cancellations are reported as such.
+				if clock.Now().Before(deadline.Add(-deadlineMargin)) {
+					return status.FromContextError(ctx.Err()).Err()
+				}
+				<-held
+			}
+			return status.Error(codes.DeadlineExceeded, "grpcmock: response held past the client's deadline")
+		}
+		log.Printf("grpcmockruntime: call has no deadline to exceed, answering it")
+	}
+	d := resp.Delay.Sample()
+	if d <= 0 {
+		return nil
+	}
+	select {
+	case <-r.Store.Clock().After(d):
+		return nil
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	}
+}
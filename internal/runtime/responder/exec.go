@@ -0,0 +1,76 @@
+package responder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+	"github.com/rbroggi/grpcmock/internal/runtime/storage"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// defaultExecTimeout bounds the run of ExecMock executables when TimeoutMs is unset.
+const defaultExecTimeout = 5 * time.Second
+
+// execWaitDelay is how long the output of a timed out executable is waited for after killing it.
+const execWaitDelay = 100 * time.Millisecond
+
+// execPath is the PATH of ExecMock executables, which do not inherit the mock server's environment.
+const execPath = "/usr/local/bin:/usr/bin:/bin"
+
+// runExec runs the executable of an ExecMock with the request and returns its
+// output, which must be JSON. It is killed when ctx is done or its timeout expires.
+func (r *Responder) runExec(ctx context.Context, fullMethodName string, mock *runtime.ExecMock, reqBodyProto proto.Message, headers metadata.MD) (json.RawMessage, error) {
+	if !mock.AllowedBy(r.Store.GetExecCommands()) {
+		return nil, fmt.Errorf("exec: command %q is not allowed", mock.Command)
+	}
+	stdin := []byte("{}")
+	if reqBodyProto != nil {
+		var err error
+		if stdin, err = storage.DefaultMarshaler.Marshal(reqBodyProto); err != nil {
+			return nil, fmt.Errorf("failed to read request for %s: %w", fullMethodName, err)
+		}
+	}
+	headersJSON, err := json.Marshal(headers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode headers for %s: %w", fullMethodName, err)
+	}
+	timeout := defaultExecTimeout
+	if mock.TimeoutMs > 0 {
+		timeout = time.Duration(mock.TimeoutMs) * time.Millisecond
+	}
+	execCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(execCtx, mock.Command, mock.Args...)
+	cmd.Dir = os.TempDir()
+	cmd.Env = []string{"PATH=" + execPath, "GRPCMOCK_FULL_METHOD_NAME=" + fullMethodName, "GRPCMOCK_HEADERS=" + string(headersJSON)}
+	for k, v := range mock.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	cmd.WaitDelay = execWaitDelay // Children of a killed executable may hold its output open
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("exec: %s stopped as the call ended: %w", mock.Command, ctx.Err())
+		}
+		if execCtx.Err() != nil {
+			return nil, fmt.Errorf("exec: %s timed out after %v", mock.Command, timeout)
+		}
+		return nil, fmt.Errorf("exec: %s failed: %v: %s", mock.Command, err, strings.TrimSpace(stderr.String()))
+	}
+	body := bytes.TrimSpace(stdout.Bytes())
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("exec: %s did not write a JSON body to stdout", mock.Command)
+	}
+	return body, nil
+}
@@ -0,0 +1,79 @@
+package responder
+
+import (
+	cryptorand "crypto/rand"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"text/template"
+)
+
+// Word lists of the fake data template functions. Emails and URLs use the
+// domains reserved for documentation, so generated data never reaches real people.
+var (
+	fakeFirstNames = []string{"Ada", "Alan", "Barbara", "Claude", "Donald", "Edsger", "Frances", "Grace", "Hedy", "Ivan", "Joan", "Ken", "Linus", "Margaret", "Niklaus", "Radia", "Robin", "Sophie", "Tim", "Yukihiro"}
+	fakeLastNames  = []string{"Allen", "Backus", "Cerf", "Dijkstra", "Engelbart", "Floyd", "Goldberg", "Hamilton", "Hopper", "Knuth", "Lamarr", "Liskov", "Lovelace", "Perlman", "Ritchie", "Shannon", "Thompson", "Turing", "Wilson", "Wirth"}
+	fakeCompanies  = []string{"Acme Corp", "Globex", "Initech", "Umbrella Industries", "Hooli", "Vandelay Industries", "Stark Industries", "Wayne Enterprises", "Soylent", "Cyberdyne Systems"}
+	fakeCities     = []string{"Amsterdam", "Berlin", "Buenos Aires", "Cairo", "Lisbon", "Montreal", "Nairobi", "Osaka", "Seoul", "Sydney", "Toronto", "Zurich"}
+	fakeWords      = []string{"alpha", "amber", "bright", "cedar", "delta", "ember", "falcon", "granite", "harbor", "indigo", "juniper", "lumen", "maple", "nova", "orbit", "prism", "quartz", "river", "summit", "tundra"}
+	fakeDomains    = []string{"example.com", "example.org", "example.net"}
+)
+
+// fakeFuncs returns the template functions generating random, realistic-looking
+// data. Each call of a function draws a new value.
+func fakeFuncs() template.FuncMap {
+	return template.FuncMap{
+		"fakeFirstName": func() string { return pick(fakeFirstNames) },
+		"fakeLastName":  func() string { return pick(fakeLastNames) },
+		"fakeName":      func() string { return pick(fakeFirstNames) + " " + pick(fakeLastNames) },
+		"fakeEmail": func() string {
+			return strings.ToLower(pick(fakeFirstNames)+"."+pick(fakeLastNames)) + "@" + pick(fakeDomains)
+		},
+		"fakePhone":   func() string { return fmt.Sprintf("+1-555-%03d-%04d", rand.IntN(1000), rand.IntN(10000)) },
+		"fakeCompany": func() string { return pick(fakeCompanies) },
+		"fakeCity":    func() string { return pick(fakeCities) },
+		"fakeWord":    func() string { return pick(fakeWords) },
+		"fakeUUID":    fakeUUID,
+		"randomInt":   randomInt,
+		"randomFloat": randomFloat,
+		"randomChoice": func(choices ...interface{}) (interface{}, error) {
+			if len(choices) == 0 {
+				return nil, fmt.Errorf("randomChoice requires at least one choice")
+			}
+			return choices[rand.IntN(len(choices))], nil
+		},
+	}
+}
+
+// pick returns a random element of words.
+func pick(words []string) string {
+	return words[rand.IntN(len(words))]
+}
+
+// randomInt returns a random integer between min and max, inclusive.
+func randomInt(min, max int) (int, error) {
+	if max < min {
+		return 0, fmt.Errorf("max %d is less than min %d", max, min)
+	}
+	return min + rand.IntN(max-min+1), nil
+}
+
+// randomFloat returns a random number between min, inclusive, and max, exclusive,
+// formatted with the given number of decimals.
+func randomFloat(min, max float64, decimals int) (string, error) {
+	if max < min {
+		return "", fmt.Errorf("max %v is less than min %v", max, min)
+	}
+	return fmt.Sprintf("%.*f", decimals, min+rand.Float64()*(max-min)), nil
+}
+
+// fakeUUID returns a random (version 4) UUID.
+func fakeUUID() (string, error) {
+	var b [16]byte
+	if _, err := cryptorand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
@@ -0,0 +1,15 @@
+package responder
+
+import (
+	"math/rand/v2"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+)
+
+// injectFault removes the error of a response with a FaultPercentage from the
+// calls drawn outside that percentage, which then get its body.
+func injectFault(resp *runtime.MockResponse) {
+	if resp.FaultPercentage > 0 && resp.Error != nil && rand.Float64()*100 >= resp.FaultPercentage {
+		resp.Error = nil
+	}
+}
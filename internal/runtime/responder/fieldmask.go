@@ -0,0 +1,111 @@
+package responder
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+)
+
+const defaultMaskField = "updateMask"
+
+// applyFieldMask merges the masked fields of the request resource into the base body.
+func applyFieldMask(base json.RawMessage, fm *runtime.FieldMaskMock, req map[string]interface{}) (json.RawMessage, error) {
+	body := map[string]interface{}{}
+	if len(base) > 0 {
+		if err := json.Unmarshal(base, &body); err != nil {
+			return nil, fmt.Errorf("field mask requires an object body: %w", err)
+		}
+	}
+	target := body
+	if fm.BodyField != "" {
+		target, _ = removeField(body, runtime.JSONName(fm.BodyField)).(map[string]interface{})
+		if target == nil {
+			target = map[string]interface{}{}
+		}
+		body[runtime.JSONName(fm.BodyField)] = target
+	}
+	resource, _ := lookupField(req, fm.ResourceField).(map[string]interface{})
+	if resource == nil {
+		resource = map[string]interface{}{}
+	}
+
+	// protojson renders a FieldMask as a comma-separated list of lowerCamelCase paths.
+	mask, _ := lookupField(req, withDefault(fm.MaskField, defaultMaskField)).(string)
+	switch mask {
+	case "*":
+		for k := range target {
+			delete(target, k)
+		}
+		for k, v := range resource {
+			target[k] = v
+		}
+	case "":
+		for k, v := range resource {
+			if populated(v) {
+				removeField(target, k)
+				target[k] = v
+			}
+		}
+	default:
+		for _, path := range strings.Split(mask, ",") {
+			copyPath(target, resource, strings.Split(runtime.JSONName(strings.TrimSpace(path)), "."))
+		}
+	}
+	return json.Marshal(body)
+}
+
+// copyPath copies the value at path from src to dst, removing it from dst when src does not have it.
+func copyPath(dst, src map[string]interface{}, path []string) {
+	key := path[0]
+	if len(path) == 1 {
+		removeField(dst, key)
+		if v, ok := src[key]; ok {
+			dst[key] = v
+		}
+		return
+	}
+	srcChild, _ := src[key].(map[string]interface{})
+	if srcChild == nil {
+		srcChild = map[string]interface{}{}
+	}
+	dstChild, _ := removeField(dst, key).(map[string]interface{})
+	if dstChild == nil {
+		dstChild = map[string]interface{}{}
+	}
+	dst[key] = dstChild
+	copyPath(dstChild, srcChild, path[1:])
+}
+
+// removeField deletes the field with the given protojson name from obj, also
+// matching keys written as proto field names, and returns its previous value.
+func removeField(obj map[string]interface{}, name string) interface{} {
+	for k, v := range obj {
+		if k == name || runtime.JSONName(k) == name {
+			delete(obj, k)
+			return v
+		}
+	}
+	return nil
+}
+
+// populated reports whether a JSON value differs from its proto default.
+func populated(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return false
+	case string:
+		return val != ""
+	case bool:
+		return val
+	case float64:
+		return val != 0
+	case []interface{}:
+		return len(val) > 0
+	case map[string]interface{}:
+		return len(val) > 0
+	default:
+		return true
+	}
+}
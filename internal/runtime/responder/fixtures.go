@@ -0,0 +1,45 @@
+package responder
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+)
+
+// resolveBodyRef returns a copy of the expectation whose response body is the
+// referenced fixture, with the top-level fields of the response's own body applied over it.
+// As the resolved body is part of the expectation, cached renderings follow fixture updates.
+func (r *Responder) resolveBodyRef(exp *runtime.GRPCCallExpectation) (*runtime.GRPCCallExpectation, error) {
+	fixture, ok := r.Store.GetFixture(exp.Response.BodyRef)
+	if !ok {
+		return nil, fmt.Errorf("unknown fixture %q", exp.Response.BodyRef)
+	}
+	body := fixture
+	if len(exp.Response.Body) > 0 {
+		var merged, overrides map[string]json.RawMessage
+		if err := json.Unmarshal(fixture, &merged); err != nil {
+			return nil, fmt.Errorf("invalid fixture %q: %w", exp.Response.BodyRef, err)
+		}
+		if err := json.Unmarshal(exp.Response.Body, &overrides); err != nil {
+			return nil, fmt.Errorf("response body overriding fixture %q must be a JSON object: %w", exp.Response.BodyRef, err)
+		}
+		for k, v := range overrides {
+			merged[k] = v
+		}
+		var err error
+		if body, err = json.Marshal(merged); err != nil {
+			return nil, err
+		}
+	}
+	resolved := *exp
+	resp := *exp.Response
+	resp.Body, resp.BodyRef, resp.Compiled = body, "", nil
+	// The fixture's templates were not compiled with the response.
+	var err error
+	if resp.Compiled, err = r.compiledResponse(&resp); err != nil {
+		return nil, err
+	}
+	resolved.Response = &resp
+	return &resolved, nil
+}
@@ -0,0 +1,111 @@
+package responder
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"math/rand/v2"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+)
+
+// timeLayouts are the named layouts accepted by formatTime.
+var timeLayouts = map[string]string{
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"RFC1123":     time.RFC1123,
+	"DateOnly":    time.DateOnly,
+	"TimeOnly":    time.TimeOnly,
+	"DateTime":    time.DateTime,
+}
+
+// volatileHelpers are the helpers returning different results on each call.
+var volatileHelpers = map[string]bool{"uuid": true, "now": true, "pick": true}
+
+// helperFuncs returns the general-purpose template functions: identifiers,
+// time, arithmetic and string helpers.
+func (r *Responder) helperFuncs() template.FuncMap {
+	return template.FuncMap{
+		"uuid":       fakeUUID,
+		"now":        func() string { return r.Store.Clock().Now().UTC().Format(time.RFC3339Nano) },
+		"formatTime": formatTime,
+		"add":        func(a, b interface{}) (interface{}, error) { return arith(a, b, (*big.Rat).Add) },
+		"mul":        func(a, b interface{}) (interface{}, error) { return arith(a, b, (*big.Rat).Mul) },
+		"toUpper":    strings.ToUpper,
+		"toLower":    strings.ToLower,
+		"b64enc":     func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+		"b64dec": func(s string) (string, error) {
+			b, err := base64.StdEncoding.DecodeString(s)
+			return string(b), err
+		},
+		"pick": pickFrom,
+		"toJSON": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}
+}
+
+// formatTime formats t, an RFC 3339 timestamp such as the result of now, or a
+// number of seconds since the Unix epoch, with a Go layout or a named one
+// ("RFC3339", "DateOnly", ...).
+func formatTime(layout string, t interface{}) (string, error) {
+	if named, ok := timeLayouts[layout]; ok {
+		layout = named
+	}
+	var tm time.Time
+	switch v := t.(type) {
+	case time.Time:
+		tm = v
+	case string:
+		var err error
+		if tm, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			return "", fmt.Errorf("formatTime: %w", err)
+		}
+	default:
+		secs, _, err := runtime.ParseDecimal(v)
+		if err != nil {
+			return "", fmt.Errorf("formatTime: %v is not a time", t)
+		}
+		f, _ := secs.Float64()
+		tm = time.Unix(0, int64(f*float64(time.Second)))
+	}
+	return tm.UTC().Format(layout), nil
+}
+
+// arith applies op to the numbers a and b, given as numbers or decimal strings
+// such as protojson's int64 values. The result is an int64 if it is whole, a
+// float64 otherwise.
+func arith(a, b interface{}, op func(z, x, y *big.Rat) *big.Rat) (interface{}, error) {
+	x, _, err := runtime.ParseDecimal(a)
+	if err != nil {
+		return nil, err
+	}
+	y, _, err := runtime.ParseDecimal(b)
+	if err != nil {
+		return nil, err
+	}
+	z := op(new(big.Rat), x, y)
+	if z.IsInt() && z.Num().IsInt64() {
+		return z.Num().Int64(), nil
+	}
+	f, _ := z.Float64()
+	return f, nil
+}
+
+// pickFrom returns a random element of list, e.g. a repeated field of the request.
+func pickFrom(list interface{}) (interface{}, error) {
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("pick requires a list, got %T", list)
+	}
+	if v.Len() == 0 {
+		return nil, fmt.Errorf("pick requires a non-empty list")
+	}
+	return v.Index(rand.IntN(v.Len())).Interface(), nil
+}
@@ -0,0 +1,63 @@
+package responder
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+// checkDuplicate records the request's idempotency key and, if the expectation
+// rejects duplicates and the key was seen before, returns the ALREADY_EXISTS response.
+func (r *Responder) checkDuplicate(
+	fullMethodName string,
+	idem *runtime.IdempotencyMock,
+	reqBodyProto proto.Message,
+) (*runtime.MockResponse, error) {
+	key, ok, err := idempotencyKey(idem, reqBodyProto)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute idempotency key for %s: %w", fullMethodName, err)
+	}
+	if !ok {
+		return nil, nil
+	}
+	if r.Store.RecordRequestKey(fullMethodName, key) > 1 && idem.RejectDuplicates {
+		return &runtime.MockResponse{Error: &runtime.RPCError{
+			Code:    codes.AlreadyExists,
+			Message: fmt.Sprintf("duplicate request with idempotency key %q", key),
+		}}, nil
+	}
+	return nil, nil
+}
+
+// idempotencyKey returns the value of the key field of the request, or a hash of
+// its body if there is none. ok is false if the request lacks the key field.
+func idempotencyKey(idem *runtime.IdempotencyMock, reqBodyProto proto.Message) (key string, ok bool, err error) {
+	if idem.KeyField == "" {
+		var body []byte
+		if reqBodyProto != nil {
+			if body, err = (proto.MarshalOptions{Deterministic: true}).Marshal(reqBodyProto); err != nil {
+				return "", false, err
+			}
+		}
+		sum := sha256.Sum256(body)
+		return "sha256:" + hex.EncodeToString(sum[:]), true, nil
+	}
+	req, err := requestJSON(reqBodyProto)
+	if err != nil {
+		return "", false, err
+	}
+	switch v := lookupField(req, idem.KeyField).(type) {
+	case nil:
+		return "", false, nil
+	case string:
+		return v, v != "", nil
+	default:
+		b, err := json.Marshal(v)
+		return string(b), err == nil, err
+	}
+}
@@ -0,0 +1,72 @@
+package responder
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"text/template"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+)
+
+// moneyFuncs returns the template functions computing exactly on amounts given
+// as google.type.Money, decimal strings or numbers. They return decimal strings,
+// so that results can be combined and placed into string values.
+func moneyFuncs() template.FuncMap {
+	return template.FuncMap{
+		"money":    func(v interface{}) (string, error) { return decimalOp(v, 0, nil) },
+		"decAdd":   func(a, b interface{}) (string, error) { return decimalOp(a, b, (*big.Rat).Add) },
+		"decSub":   func(a, b interface{}) (string, error) { return decimalOp(a, b, (*big.Rat).Sub) },
+		"decMul":   func(a, b interface{}) (string, error) { return decimalOp(a, b, (*big.Rat).Mul) },
+		"decRound": decRound,
+		"currency": func(v interface{}) (string, error) {
+			_, currency, err := runtime.ParseDecimal(v)
+			return currency, err
+		},
+		"units": func(v interface{}) (string, error) {
+			units, _, err := moneyParts(v)
+			return strconv.FormatInt(units, 10), err
+		},
+		"nanos": func(v interface{}) (string, error) {
+			_, nanos, err := moneyParts(v)
+			return strconv.FormatInt(int64(nanos), 10), err
+		},
+	}
+}
+
+// decimalOp applies op to the amounts a and b, or formats a if op is nil.
+func decimalOp(a, b interface{}, op func(z, x, y *big.Rat) *big.Rat) (string, error) {
+	x, _, err := runtime.ParseDecimal(a)
+	if err != nil {
+		return "", err
+	}
+	if op == nil {
+		return runtime.FormatDecimal(x), nil
+	}
+	y, _, err := runtime.ParseDecimal(b)
+	if err != nil {
+		return "", err
+	}
+	return runtime.FormatDecimal(op(new(big.Rat), x, y)), nil
+}
+
+// decRound formats an amount with exactly places decimal places, rounding halves away from zero.
+func decRound(v interface{}, places int) (string, error) {
+	if places < 0 {
+		return "", fmt.Errorf("decRound: negative number of places %d", places)
+	}
+	r, _, err := runtime.ParseDecimal(v)
+	if err != nil {
+		return "", err
+	}
+	return r.FloatString(places), nil
+}
+
+// moneyParts splits an amount into the units and nanos of a google.type.Money.
+func moneyParts(v interface{}) (int64, int32, error) {
+	r, _, err := runtime.ParseDecimal(v)
+	if err != nil {
+		return 0, 0, err
+	}
+	return runtime.MoneyParts(r)
+}
@@ -0,0 +1,155 @@
+package responder
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+	"github.com/rbroggi/grpcmock/internal/runtime/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/apipb"
+)
+
+func TestOperationJSON(t *testing.T) {
+	doneAt := time.Unix(1000, 0)
+	tests := []struct {
+		name string
+		op   runtime.OperationMock
+		now  time.Time
+		want string
+	}{
+		{
+			name: "pending",
+			op:   runtime.OperationMock{Name: "operations/1", Response: json.RawMessage(`{"@type": "type.googleapis.com/pkg.v1.Order"}`)},
+			now:  doneAt.Add(-time.Nanosecond),
+			want: `{"name": "operations/1"}`,
+		},
+		{
+			name: "pending with metadata",
+			op:   runtime.OperationMock{Name: "operations/1", Metadata: json.RawMessage(`{"@type": "type.googleapis.com/pkg.v1.Progress", "percent": 50}`)},
+			now:  doneAt.Add(-time.Second),
+			want: `{"name": "operations/1", "metadata": {"@type": "type.googleapis.com/pkg.v1.Progress", "percent": 50}}`,
+		},
+		{
+			name: "done with response",
+			op:   runtime.OperationMock{Name: "operations/1", Response: json.RawMessage(`{"@type": "type.googleapis.com/pkg.v1.Order", "id": "o-1"}`)},
+			now:  doneAt,
+			want: `{"name": "operations/1", "done": true, "response": {"@type": "type.googleapis.com/pkg.v1.Order", "id": "o-1"}}`,
+		},
+		{
+			name: "done without response",
+			op:   runtime.OperationMock{Name: "operations/1"},
+			now:  doneAt.Add(time.Second),
+			want: `{"name": "operations/1", "done": true}`,
+		},
+		{
+			name: "done with error",
+			op: runtime.OperationMock{
+				Name:     "operations/1",
+				Response: json.RawMessage(`{"@type": "type.googleapis.com/pkg.v1.Order"}`),
+				Error:    &runtime.RPCError{Code: codes.FailedPrecondition, Message: "out of stock"},
+			},
+			now:  doneAt,
+			want: `{"name": "operations/1", "done": true, "error": {"code": 9, "message": "out of stock"}}`,
+		},
+		{
+			name: "done with error details",
+			op: runtime.OperationMock{
+				Name: "operations/1",
+				Error: &runtime.RPCError{Code: codes.ResourceExhausted, Message: "slow down", Details: []json.RawMessage{
+					json.RawMessage(`{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": "1s"}`),
+				}},
+			},
+			now:  doneAt,
+			want: `{"name": "operations/1", "done": true, "error": {"code": 8, "message": "slow down", "details": [{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": "1s"}]}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := operationJSON(runtime.OperationState{OperationMock: tt.op, DoneAt: doneAt.UnixNano()}, tt.now)
+			if err != nil {
+				t.Fatalf("operationJSON: %v", err)
+			}
+			assertJSONEqual(t, got, tt.want)
+		})
+	}
+}
+
+// TestGetOperationLifecycle follows an operation started by a response through
+// GetOperation polls as the clock moves past its completion.
+func TestGetOperationLifecycle(t *testing.T) {
+	clock := &testClock{now: time.Unix(1000, 0)}
+	s := storage.New()
+	s.SetClock(clock)
+	r := New(s)
+	if _, err := r.startOperation(runtime.OperationMock{
+		Name:        "operations/order",
+		DoneAfterMs: 5000,
+		Response:    json.RawMessage(`{"@type": "type.googleapis.com/pkg.v1.Order", "id": "o-1"}`),
+	}); err != nil {
+		t.Fatalf("startOperation: %v", err)
+	}
+
+	// Any request with a name field stands for a GetOperationRequest.
+	poll := func(name string) *runtime.GRPCCallExpectation {
+		t.Helper()
+		exp := r.BuiltinExpectation(getOperationMethod, &apipb.Method{Name: name})
+		if exp == nil || exp.Response == nil {
+			t.Fatalf("BuiltinExpectation(%q) = %+v, want a response", name, exp)
+		}
+		return exp
+	}
+	assertJSONEqual(t, poll("operations/order").Response.Body, `{"name": "operations/order"}`)
+	clock.advance(4999 * time.Millisecond)
+	assertJSONEqual(t, poll("operations/order").Response.Body, `{"name": "operations/order"}`)
+	clock.advance(time.Millisecond)
+	assertJSONEqual(t, poll("operations/order").Response.Body,
+		`{"name": "operations/order", "done": true, "response": {"@type": "type.googleapis.com/pkg.v1.Order", "id": "o-1"}}`)
+	if rpcErr := poll("operations/unknown").Response.Error; rpcErr == nil || rpcErr.Code != codes.NotFound {
+		t.Errorf("unknown operation answered with %+v, want NOT_FOUND", rpcErr)
+	}
+	if exp := r.BuiltinExpectation("/pkg.v1.Svc/Get", &apipb.Method{Name: "operations/order"}); exp != nil {
+		t.Errorf("other methods got a builtin expectation %+v, want none", exp)
+	}
+}
+
+func assertJSONEqual(t *testing.T, got json.RawMessage, want string) {
+	t.Helper()
+	var g, w interface{}
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("invalid JSON %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatalf("invalid JSON %s: %v", want, err)
+	}
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+// testClock is a Clock whose time only moves when set.
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	ch <- c.Now().Add(d)
+	return ch
+}
+
+func (c *testClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
@@ -0,0 +1,126 @@
+package responder
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	defaultPageSizeField      = "pageSize"
+	defaultPageTokenField     = "pageToken"
+	defaultNextPageTokenField = "nextPageToken"
+	pageTokenPrefix           = "offset:"
+)
+
+// renderPage merges the page selected by the request into the base body.
+// A malformed, expired or deliberately rejected page token yields an error response.
+func renderPage(base json.RawMessage, p *runtime.PaginationMock, req map[string]interface{}, now time.Time) (json.RawMessage, *runtime.RPCError, error) {
+	pageSizeField := withDefault(p.PageSizeField, defaultPageSizeField)
+	pageTokenField := withDefault(p.PageTokenField, defaultPageTokenField)
+	nextPageTokenField := withDefault(p.NextPageTokenField, defaultNextPageTokenField)
+
+	pageSize := p.PageSize
+	if size, ok := toInt(lookupField(req, pageSizeField)); ok && size > 0 {
+		pageSize = size
+	}
+	if pageSize <= 0 {
+		pageSize = len(p.Items)
+	}
+
+	offset := 0
+	if token, _ := lookupField(req, pageTokenField).(string); token != "" {
+		var issuedAt time.Time
+		var ok bool
+		if offset, issuedAt, ok = decodePageToken(token); !ok || offset > len(p.Items) {
+			return nil, &runtime.RPCError{Code: codes.InvalidArgument, Message: fmt.Sprintf("invalid page token %q", token)}, nil
+		}
+		if p.TokenTTLMs > 0 && now.Sub(issuedAt) > time.Duration(p.TokenTTLMs)*time.Millisecond {
+			if p.ExpiredTokenError != nil {
+				return nil, p.ExpiredTokenError, nil
+			}
+			return nil, &runtime.RPCError{Code: codes.InvalidArgument, Message: fmt.Sprintf("page token %q has expired", token)}, nil
+		}
+		if pageSize > 0 && slices.Contains(p.InvalidTokenPages, offset/pageSize+1) {
+			return nil, &runtime.RPCError{Code: codes.InvalidArgument, Message: fmt.Sprintf("invalid page token %q", token)}, nil
+		}
+	}
+
+	end := offset + pageSize
+	nextToken := ""
+	if end < len(p.Items) {
+		nextToken = encodePageToken(end, now)
+	} else {
+		end = len(p.Items)
+	}
+
+	body := map[string]interface{}{}
+	if len(base) > 0 {
+		if err := json.Unmarshal(base, &body); err != nil {
+			return nil, nil, fmt.Errorf("pagination requires an object body: %w", err)
+		}
+	}
+	body[p.ItemsField] = append([]json.RawMessage{}, p.Items[offset:end]...)
+	body[nextPageTokenField] = nextToken
+	out, err := json.Marshal(body)
+	return out, nil, err
+}
+
+// encodePageToken returns an opaque token for the page starting at offset, issued at the given time.
+func encodePageToken(offset int, issuedAt time.Time) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(pageTokenPrefix + strconv.Itoa(offset) + ":" + strconv.FormatInt(issuedAt.UnixMilli(), 10)))
+}
+
+// decodePageToken returns the offset and issue time of a page token.
+func decodePageToken(token string) (int, time.Time, bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || !strings.HasPrefix(string(raw), pageTokenPrefix) {
+		return 0, time.Time{}, false
+	}
+	offsetStr, issuedStr, _ := strings.Cut(strings.TrimPrefix(string(raw), pageTokenPrefix), ":")
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil || offset < 0 {
+		return 0, time.Time{}, false
+	}
+	issuedMs, err := strconv.ParseInt(issuedStr, 10, 64)
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+	return offset, time.UnixMilli(issuedMs), true
+}
+
+// lookupField returns a request field by its protojson name, falling back to
+// the lowerCamelCase form of a proto (snake_case) field name.
+func lookupField(req map[string]interface{}, name string) interface{} {
+	if v, ok := req[name]; ok {
+		return v
+	}
+	return req[runtime.JSONName(name)]
+}
+
+// toInt converts a JSON-decoded number (or protojson int64 string) to an int.
+func toInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case float64:
+		return int(n), true
+	case string:
+		i, err := strconv.Atoi(n)
+		return i, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func withDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
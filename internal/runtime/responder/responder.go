@@ -0,0 +1,322 @@
+package responder
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+	"github.com/rbroggi/grpcmock/internal/runtime/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// getOperationMethod is the method clients poll to follow a long-running operation.
+const getOperationMethod = "/google.longrunning.Operations/GetOperation"
+
+// storeInterface defines the storage methods needed to build responses.
+type storeInterface interface {
+	AddOperation(op runtime.OperationState)
+	GetOperation(name string) (runtime.OperationState, bool)
+	RecordRequestKey(fullMethodName, key string) int
+	FindAnsweredCall(fullMethodName, expectationID string, accept func(runtime.RecordedGRPCCall) bool) bool
+	GetFixture(name string) (json.RawMessage, bool)
+	GetVars() map[string]string
+	SetVars(vars map[string]string)
+	GetExecCommands() []string
+	Clock() runtime.Clock
+}
+
+// Responder turns matched expectations into the responses sent to clients.
+type Responder struct {
+	Store  storeInterface
+	cache  *renderCache
+	funcs  template.FuncMap // Available to response templates
+	client *http.Client     // Sends webhooks
+}
+
+// New creates a new Responder with the given store. If the store accepts
+// expectation validators, response templates are compiled as expectations
+// are registered, and expectations with invalid templates are rejected.
+func New(store storeInterface) *Responder {
+	r := &Responder{Store: store, cache: newRenderCache(), client: &http.Client{Timeout: 10 * time.Second}}
+	r.funcs = r.templateFuncs()
+	if v, ok := store.(interface {
+		AddValidator(validate func(*runtime.GRPCCallExpectation) error)
+	}); ok {
+		v.AddValidator(r.Compile)
+	}
+	return r
+}
+
+// Render returns the response to send for the matched expectation. Response
+// templates can refer to the request as .Request, its headers as .Headers, the
+// named capture groups of the matching regexes as .Matches and the variables
+// set by earlier responses as .Vars, and look up earlier calls with lastCall.
+// Responses with a FaultPercentage keep their error only for that share of the calls.
+// Executables are killed when ctx, the call's context, is done.
+// The returned MockResponse is a copy; the stored expectation is never modified.
+func (r *Responder) Render(
+	ctx context.Context,
+	fullMethodName string,
+	exp *runtime.GRPCCallExpectation,
+	reqBodyProto proto.Message,
+	headers metadata.MD,
+	matches map[string]string,
+) (*runtime.MockResponse, error) {
+	return r.renderCall(ctx, fullMethodName, exp, reqBodyProto, nil, headers, matches)
+}
+
+// renderCall renders the response to a call, whose client stream messages are
+// stream if it is client-streaming.
+func (r *Responder) renderCall(
+	ctx context.Context,
+	fullMethodName string,
+	exp *runtime.GRPCCallExpectation,
+	reqBodyProto proto.Message,
+	stream []proto.Message,
+	headers metadata.MD,
+	matches map[string]string,
+) (*runtime.MockResponse, error) {
+	resp, err := r.renderCached(ctx, fullMethodName, exp, reqBodyProto, stream, headers, matches)
+	if err != nil {
+		return nil, err
+	}
+	injectFault(resp)
+	if len(resp.SetVars) > 0 && resp.Error == nil {
+		if err := r.setVars(fullMethodName, resp, reqBodyProto, headers, matches); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// renderCached returns the rendered response, from the render cache if the response allows it.
+func (r *Responder) renderCached(
+	ctx context.Context,
+	fullMethodName string,
+	exp *runtime.GRPCCallExpectation,
+	reqBodyProto proto.Message,
+	stream []proto.Message,
+	headers metadata.MD,
+	matches map[string]string,
+) (*runtime.MockResponse, error) {
+	if exp.Idempotency != nil {
+		if rejected, err := r.checkDuplicate(fullMethodName, exp.Idempotency, reqBodyProto); err != nil || rejected != nil {
+			return rejected, err
+		}
+	}
+	if exp.Response == nil {
+		return &runtime.MockResponse{}, nil
+	}
+	if exp.Response.BodyRef != "" {
+		resolved, err := r.resolveBodyRef(exp)
+		if err != nil {
+			return nil, err
+		}
+		exp = resolved
+	}
+	compiled, err := r.compiledResponse(exp.Response)
+	if err != nil {
+		return nil, err
+	}
+	// Operations and expiring page tokens depend on the time of the call,
+	// lookups of recorded calls on the calls received so far, variables on the
+	// earlier responses, executables and scripts on anything, and the cache key
+	// only covers the first message of client streams.
+	reads := compiled.Reads
+	if !exp.Response.CacheRendered || exp.Response.Operation != nil || exp.Response.Exec != nil || exp.Response.Script != "" ||
+		(exp.Response.Pagination != nil && exp.Response.Pagination.TokenTTLMs > 0) ||
+		reads.RecordedCalls || reads.Vars || reads.Stream || reads.Volatile {
+		return r.render(ctx, fullMethodName, exp, reqBodyProto, stream, headers, matches)
+	}
+	var keyHeaders metadata.MD
+	if reads.Headers {
+		keyHeaders = headers
+	}
+	key, err := cacheKey(fullMethodName, exp, reqBodyProto, keyHeaders, matches)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute response cache key for %s: %w", fullMethodName, err)
+	}
+	if cached, ok := r.cache.get(key); ok {
+		return &cached, nil
+	}
+	resp, err := r.render(ctx, fullMethodName, exp, reqBodyProto, stream, headers, matches)
+	if err != nil {
+		return nil, err
+	}
+	r.cache.put(key, *resp)
+	return resp, nil
+}
+
+// render computes the response for the matched expectation.
+func (r *Responder) render(
+	ctx context.Context,
+	fullMethodName string,
+	exp *runtime.GRPCCallExpectation,
+	reqBodyProto proto.Message,
+	stream []proto.Message,
+	headers metadata.MD,
+	matches map[string]string,
+) (*runtime.MockResponse, error) {
+	resp := *exp.Response
+	compiled, err := r.compiledResponse(&resp)
+	if err != nil {
+		return nil, err
+	}
+	data := templateData{Matches: matches, Headers: headers}
+	if compiled.Reads.Request {
+		if data.Request, err = requestJSON(reqBodyProto); err != nil {
+			return nil, fmt.Errorf("failed to read request for %s: %w", fullMethodName, err)
+		}
+	}
+	if compiled.Reads.Vars {
+		data.Vars = r.Store.GetVars()
+	}
+	if compiled.Reads.Stream {
+		if data.Stream, err = newStreamData(stream); err != nil {
+			return nil, fmt.Errorf("failed to read client stream for %s: %w", fullMethodName, err)
+		}
+	}
+	if err := r.applyTemplates(&resp, compiled, data); err != nil {
+		return nil, err
+	}
+	if resp.Exec != nil {
+		body, err := r.runExec(ctx, fullMethodName, resp.Exec, reqBodyProto, headers)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = body
+	}
+	if resp.Script != "" {
+		if err := r.runScript(ctx, fullMethodName, &resp, compiled.Script, reqBodyProto, headers, matches); err != nil {
+			return nil, err
+		}
+	}
+	if resp.Pagination != nil || resp.FieldMask != nil {
+		req, err := requestJSON(reqBodyProto)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request for %s: %w", fullMethodName, err)
+		}
+		if resp.FieldMask != nil {
+			if resp.Body, err = applyFieldMask(resp.Body, resp.FieldMask, req); err != nil {
+				return nil, err
+			}
+		}
+		if resp.Pagination != nil {
+			body, rpcErr, err := renderPage(resp.Body, resp.Pagination, req, r.Store.Clock().Now())
+			if err != nil {
+				return nil, err
+			}
+			resp.Body, resp.Error = body, rpcErr
+		}
+	}
+	if resp.Operation != nil {
+		body, err := r.startOperation(*resp.Operation)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start operation for %s: %w", fullMethodName, err)
+		}
+		resp.Body = body
+	}
+	return &resp, nil
+}
+
+// BuiltinExpectation returns an expectation synthesized by the mock itself
+// for calls no registered expectation matched, or nil if there is none.
+// It currently serves google.longrunning.Operations/GetOperation for
+// operations started through OperationMock, provided the generated mock server
+// registers that service.
+func (r *Responder) BuiltinExpectation(fullMethodName string, reqBodyProto proto.Message) *runtime.GRPCCallExpectation {
+	if fullMethodName != getOperationMethod || reqBodyProto == nil {
+		return nil
+	}
+	name := stringField(reqBodyProto, "name")
+	op, ok := r.Store.GetOperation(name)
+	if !ok {
+		return &runtime.GRPCCallExpectation{
+			FullMethodName: fullMethodName,
+			Response: &runtime.MockResponse{
+				Error: &runtime.RPCError{Code: codes.NotFound, Message: fmt.Sprintf("operation %q not found", name)},
+			},
+		}
+	}
+	body, err := operationJSON(op, r.Store.Clock().Now())
+	if err != nil {
+		log.Printf("grpcmockruntime: failed to render operation %s: %v", name, err)
+		return nil
+	}
+	return &runtime.GRPCCallExpectation{
+		FullMethodName: fullMethodName,
+		Response:       &runtime.MockResponse{Body: body},
+	}
+}
+
+// startOperation registers a new operation and returns its initial, pending JSON form.
+func (r *Responder) startOperation(mock runtime.OperationMock) (json.RawMessage, error) {
+	if mock.Name == "" {
+		id := make([]byte, 8)
+		if _, err := rand.Read(id); err != nil {
+			return nil, err
+		}
+		mock.Name = "operations/" + hex.EncodeToString(id)
+	}
+	now := r.Store.Clock().Now()
+	op := runtime.OperationState{
+		OperationMock: mock,
+		DoneAt:        now.Add(time.Duration(mock.DoneAfterMs) * time.Millisecond).UnixNano(),
+	}
+	r.Store.AddOperation(op)
+	return operationJSON(op, now)
+}
+
+// operationJSON renders op as the protojson form of a google.longrunning.Operation at the given time.
+func operationJSON(op runtime.OperationState, now time.Time) (json.RawMessage, error) {
+	out := map[string]interface{}{"name": op.Name}
+	if len(op.Metadata) > 0 {
+		out["metadata"] = op.Metadata
+	}
+	if now.UnixNano() >= op.DoneAt {
+		out["done"] = true
+		if op.Error != nil {
+			opErr := map[string]interface{}{"code": op.Error.Code, "message": op.Error.Message}
+			if len(op.Error.Details) > 0 {
+				opErr["details"] = op.Error.Details
+			}
+			out["error"] = opErr
+		} else if len(op.Response) > 0 {
+			out["response"] = op.Response
+		}
+	}
+	return json.Marshal(out)
+}
+
+// requestJSON returns the protojson form of the request as a generic map.
+func requestJSON(reqBodyProto proto.Message) (map[string]interface{}, error) {
+	req := map[string]interface{}{}
+	if reqBodyProto == nil {
+		return req, nil
+	}
+	b, err := storage.DefaultMarshaler.Marshal(reqBodyProto)
+	if err != nil {
+		return nil, err
+	}
+	return req, json.Unmarshal(b, &req)
+}
+
+// stringField returns the value of the named string field of msg, or "" if there is none.
+func stringField(msg proto.Message, name protoreflect.Name) string {
+	m := msg.ProtoReflect()
+	fd := m.Descriptor().Fields().ByName(name)
+	if fd == nil || fd.Kind() != protoreflect.StringKind || fd.IsList() {
+		return ""
+	}
+	return m.Get(fd).String()
+}
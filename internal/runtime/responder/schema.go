@@ -0,0 +1,99 @@
+package responder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// strictUnmarshaler rejects unknown fields, which are most likely typos in the
+// registered bodies, unlike storage.DefaultUnmarshaler used at call time.
+var strictUnmarshaler = protojson.UnmarshalOptions{}
+
+// validateBodies checks that the response bodies of an expectation unmarshal
+// into the output message of its method, as they will be at call time, so that
+// mistakes are reported on registration rather than as INTERNAL errors. Bodies
+// containing templates, merged over fixtures, and those of expectations whose
+// method is a pattern or is not linked into the mock server are not checked.
+func validateBodies(exp runtime.GRPCCallExpectation) error {
+	output := outputDescriptor(exp.FullMethodName)
+	if exp.FullMethodNameRegex != "" || output == nil {
+		return nil
+	}
+	bodies := map[string]json.RawMessage{}
+	addResponse := func(field string, resp *runtime.MockResponse) {
+		if resp.BodyRef == "" {
+			bodies[field+".body"] = resp.Body
+		}
+		for i, b := range resp.Bodies {
+			bodies[fmt.Sprintf("%s.bodies[%d]", field, i)] = b
+		}
+	}
+	if exp.Response != nil {
+		addResponse("response", exp.Response)
+	}
+	for i := range exp.Responses {
+		addResponse(fmt.Sprintf("responses[%d]", i), &exp.Responses[i])
+	}
+	if exp.Stream != nil {
+		for i := range exp.Stream.Responses {
+			addResponse(fmt.Sprintf("stream.responses[%d]", i), &exp.Stream.Responses[i])
+		}
+		for i, step := range exp.Stream.Script {
+			for j, b := range step.SendResponses {
+				bodies[fmt.Sprintf("stream.script[%d].sendResponses[%d]", i, j)] = b
+			}
+		}
+	}
+	for field, body := range bodies {
+		if len(bytes.TrimSpace(body)) == 0 || bytes.Contains(body, []byte("{{")) {
+			continue
+		}
+		body, err := decodeJSONValues(field, body)
+		if err != nil {
+			return err
+		}
+		if err := strictUnmarshaler.Unmarshal(body, newMessage(output)); err != nil {
+			return fmt.Errorf("%s is not a valid %s: %w", field, output.FullName(), err)
+		}
+	}
+	return nil
+}
+
+// outputDescriptor returns the output message of a method given by its full
+// name, e.g. "/pkg.Svc/Method", or nil if the method is unknown.
+func outputDescriptor(fullMethodName string) protoreflect.MessageDescriptor {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethodName, "/"), "/")
+	if !ok {
+		return nil
+	}
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil
+	}
+	md := sd.Methods().ByName(protoreflect.Name(method))
+	if md == nil {
+		return nil
+	}
+	return md.Output()
+}
+
+// newMessage returns an empty message of the given type, of its generated Go
+// type if it is linked into the mock server.
+func newMessage(md protoreflect.MessageDescriptor) protoreflect.ProtoMessage {
+	if mt, err := protoregistry.GlobalTypes.FindMessageByName(md.FullName()); err == nil {
+		return mt.New().Interface()
+	}
+	return dynamicpb.NewMessage(md)
+}
@@ -0,0 +1,191 @@
+package responder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+	"github.com/rbroggi/grpcmock/internal/runtime/storage"
+	starjson "go.starlark.net/lib/json"
+	"go.starlark.net/lib/math"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// Limits of a response script run: scripts taking longer are cancelled.
+const (
+	scriptTimeout  = time.Second
+	scriptMaxSteps = 10_000_000
+)
+
+// scriptFileOptions enables the Starlark features response scripts may use.
+var scriptFileOptions = &syntax.FileOptions{Set: true, While: true, TopLevelControl: true, GlobalReassign: true, Recursion: true}
+
+// scriptPredeclared are the modules available to response scripts. Starlark
+// has no file, network or process access, so scripts are sandboxed.
+var scriptPredeclared = starlark.StringDict{
+	"json":   starjson.Module,
+	"math":   math.Module,
+	"struct": starlark.NewBuiltin("struct", starlarkstruct.Make),
+}
+
+// scriptResult is the dict returned by the respond function of response scripts.
+type scriptResult struct {
+	Body    json.RawMessage   `json:"body,omitempty"`
+	Bodies  []json.RawMessage `json:"bodies,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Error   *runtime.RPCError `json:"error,omitempty"`
+}
+
+// compileScript compiles a response script, checking that it defines respond.
+// Its top-level statements only run with the limits of a call, see runScript.
+func compileScript(src string) (*starlark.Program, error) {
+	f, prog, err := starlark.SourceProgramOptions(scriptFileOptions, "script", src, scriptPredeclared.Has)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile response script: %w", err)
+	}
+	if !definesRespond(f.Stmts) {
+		return nil, fmt.Errorf("response script must define a respond(call) function")
+	}
+	return prog, nil
+}
+
+// definesRespond reports whether top-level statements, including those nested
+// in top-level if, for and while statements, define or assign respond.
+func definesRespond(stmts []syntax.Stmt) bool {
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case *syntax.DefStmt:
+			if stmt.Name.Name == "respond" {
+				return true
+			}
+		case *syntax.AssignStmt:
+			if id, ok := stmt.LHS.(*syntax.Ident); ok && id.Name == "respond" {
+				return true
+			}
+		case *syntax.IfStmt:
+			if definesRespond(stmt.True) || definesRespond(stmt.False) {
+				return true
+			}
+		case *syntax.ForStmt:
+			if definesRespond(stmt.Body) {
+				return true
+			}
+		case *syntax.WhileStmt:
+			if definesRespond(stmt.Body) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// newScriptThread returns a thread running a script within the limits.
+func newScriptThread(name string) *starlark.Thread {
+	thread := &starlark.Thread{Name: name}
+	thread.SetMaxExecutionSteps(scriptMaxSteps)
+	return thread
+}
+
+// scriptRespond executes the script's top-level statements and returns its respond function.
+func scriptRespond(thread *starlark.Thread, prog *starlark.Program) (starlark.Callable, error) {
+	globals, err := prog.Init(thread, scriptPredeclared)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run response script: %w", err)
+	}
+	respond, ok := globals["respond"].(starlark.Callable)
+	if !ok {
+		return nil, fmt.Errorf("response script must define a respond(call) function")
+	}
+	return respond, nil
+}
+
+// runScript runs the compiled response script, calls its respond function with
+// the call and applies what it returns to the response: its body, bodies, headers (added to
+// the response's) and error. If it returns None, the response is left unchanged.
+func (r *Responder) runScript(
+	ctx context.Context,
+	fullMethodName string,
+	resp *runtime.MockResponse,
+	prog *starlark.Program,
+	reqBodyProto proto.Message,
+	headers metadata.MD,
+	matches map[string]string,
+) error {
+	thread := newScriptThread(fullMethodName)
+	timer := time.AfterFunc(scriptTimeout, func() { thread.Cancel("timed out") })
+	defer timer.Stop()
+	stop := context.AfterFunc(ctx, func() { thread.Cancel("the call ended") })
+	defer stop()
+	respond, err := scriptRespond(thread, prog)
+	if err != nil {
+		return err
+	}
+	reqJSON := []byte("{}")
+	if reqBodyProto != nil {
+		if reqJSON, err = storage.DefaultMarshaler.Marshal(reqBodyProto); err != nil {
+			return fmt.Errorf("failed to read request for %s: %w", fullMethodName, err)
+		}
+	}
+	headersJSON, err := json.Marshal(headers)
+	if err != nil {
+		return err
+	}
+	matchesJSON, err := json.Marshal(matches)
+	if err != nil {
+		return err
+	}
+	decode := starjson.Module.Members["decode"]
+	call := starlark.StringDict{"method": starlark.String(fullMethodName)}
+	for name, value := range map[string][]byte{"request": reqJSON, "headers": headersJSON, "matches": matchesJSON} {
+		if call[name], err = starlark.Call(thread, decode, starlark.Tuple{starlark.String(value)}, nil); err != nil {
+			return fmt.Errorf("failed to pass %s to response script: %w", name, err)
+		}
+	}
+	out, err := starlark.Call(thread, respond, starlark.Tuple{starlarkstruct.FromStringDict(starlarkstruct.Default, call)}, nil)
+	if err != nil {
+		return fmt.Errorf("response script failed: %w", err)
+	}
+	if out == starlark.None {
+		return nil
+	}
+	if _, ok := out.(*starlark.Dict); !ok {
+		return fmt.Errorf("response script returned %s, want a dict or None", out.Type())
+	}
+	encoded, err := starlark.Call(thread, starjson.Module.Members["encode"], starlark.Tuple{out}, nil)
+	if err != nil {
+		return fmt.Errorf("response script returned an invalid response: %w", err)
+	}
+	var result scriptResult
+	if err := json.Unmarshal([]byte(string(encoded.(starlark.String))), &result); err != nil {
+		return fmt.Errorf("response script returned an invalid response: %w", err)
+	}
+	if result.Error != nil {
+		if err := runtime.ValidateStatusCode(result.Error.Code); err != nil {
+			return fmt.Errorf("response script returned an invalid error: %w", err)
+		}
+		resp.Error = result.Error
+	}
+	if result.Body != nil {
+		resp.Body = result.Body
+	}
+	if result.Bodies != nil {
+		resp.Bodies = result.Bodies
+	}
+	if len(result.Headers) > 0 {
+		merged := make(map[string]string, len(resp.Headers)+len(result.Headers))
+		for k, v := range resp.Headers {
+			merged[k] = v
+		}
+		for k, v := range result.Headers {
+			merged[k] = v
+		}
+		resp.Headers = merged
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package responder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// StreamInfinite sends the messages of an infinite server stream with send,
+// following the response's InfiniteStreamMock on the store's clock. Message n
+// is the n-th body of the cycle through the response's Bodies (or its Body),
+// with its templates rendered anew and .Message set to n, so messages can differ
+// even with a single body. It returns nil once a limit is reached, the status
+// of the call if the client cancels it, and send's errors. Errors rendering a
+// message are returned as INTERNAL.
+func (r *Responder) StreamInfinite(
+	ctx context.Context,
+	fullMethodName string,
+	exp *runtime.GRPCCallExpectation,
+	reqBodyProto proto.Message,
+	headers metadata.MD,
+	matches map[string]string,
+	send func(body []byte) error,
+) error {
+	bodies := exp.Response.Bodies
+	if len(bodies) == 0 {
+		bodies = []json.RawMessage{exp.Response.Body}
+	}
+	compiled, err := r.compiledResponse(exp.Response)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to compile mock response: %v", err)
+	}
+	data := templateData{Matches: matches, Headers: headers}
+	if compiled.Reads.Request {
+		if data.Request, err = requestJSON(reqBodyProto); err != nil {
+			return status.Errorf(codes.Internal, "failed to read request for %s: %v", fullMethodName, err)
+		}
+	}
+	infinite := exp.Response.Infinite
+	clock := r.Store.Clock()
+	var expired <-chan time.Time
+	if infinite.MaxDurationMs > 0 {
+		expired = clock.After(time.Duration(infinite.MaxDurationMs) * time.Millisecond)
+	}
+	for n := 0; infinite.MaxMessages == 0 || n < infinite.MaxMessages; n++ {
+		if n > 0 {
+			select {
+			case <-clock.After(exp.Response.MessageInterval(infinite.Interval())):
+			case <-expired:
+				return nil
+			case <-ctx.Done():
+				return status.FromContextError(ctx.Err()).Err()
+			}
+		}
+		data.Message = n
+		i := n % len(bodies)
+		name := fmt.Sprintf("bodies[%d]", i)
+		body, err := walkJSONTemplates(name, bodies[i], r.executeTemplate(compiled, data))
+		if err == nil {
+			body, err = decodeJSONValues(name, body)
+		}
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to render mock response: %v", err)
+		}
+		if err := send(body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WaitMessage waits, on the store's clock, for the interval before the next
+// message of a server stream given by the response's MessageIntervalMs and
+// JitterMs. If the call ends first, it returns the matching status error.
+func (r *Responder) WaitMessage(ctx context.Context, resp *runtime.MockResponse) error {
+	d := resp.MessageInterval(time.Duration(resp.MessageIntervalMs) * time.Millisecond)
+	if d <= 0 {
+		return nil
+	}
+	select {
+	case <-r.Store.Clock().After(d):
+		return nil
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	}
+}
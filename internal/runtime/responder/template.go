@@ -0,0 +1,495 @@
+package responder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+	"google.golang.org/grpc/metadata"
+)
+
+// templateData is what response templates can refer to.
+type templateData struct {
+	Matches map[string]string      // Named capture groups of the matching expectation's regexes
+	Request map[string]interface{} // protojson form of the request (the first message of client streams)
+	Headers metadata.MD            // Request headers
+	Message int                    // Number of the message sent on infinite server streams, from 0
+	Vars    map[string]string      // Variables set by earlier responses
+	Stream  streamData             // Messages received on client streams
+	// Response is the rendered response body, only available to SetVars templates.
+	Response map[string]interface{}
+}
+
+// templateFuncs returns the functions available to response templates.
+func (r *Responder) templateFuncs() template.FuncMap {
+	funcs := template.FuncMap{"lastCall": r.lastCall}
+	for name, fn := range moneyFuncs() {
+		funcs[name] = fn
+	}
+	for name, fn := range fakeFuncs() {
+		funcs[name] = fn
+	}
+	for name, fn := range r.helperFuncs() {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
+// lastCall returns the request body of the most recent call to a method that
+// was already answered, or nil if there is none. The call being answered is
+// never returned, as its response is recorded only once sent.
+func (r *Responder) lastCall(fullMethodName string) map[string]interface{} {
+	var raw []byte
+	r.Store.FindAnsweredCall(fullMethodName, "", func(call runtime.RecordedGRPCCall) bool {
+		raw = call.Body
+		return true
+	})
+	if raw == nil {
+		return nil
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return nil
+	}
+	return body
+}
+
+// newTemplate parses a response template.
+func (r *Responder) newTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=zero").Funcs(r.funcs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse response template %s: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// templateCompiler collects compiled templates and what they read into reads;
+// its compile method is a walk function leaving the templates' text unchanged.
+type templateCompiler struct {
+	r        *Responder
+	compiled *runtime.CompiledTemplates
+	reads    *runtime.TemplateReads
+}
+
+func (r *Responder) newTemplateCompiler() *templateCompiler {
+	compiled := &runtime.CompiledTemplates{Templates: make(map[string]*template.Template)}
+	return &templateCompiler{r: r, compiled: compiled, reads: &compiled.Reads}
+}
+
+func (c *templateCompiler) compile(name, text string) (string, error) {
+	tmpl, ok := c.compiled.Templates[text]
+	if !ok {
+		var err error
+		if tmpl, err = c.r.newTemplate(name, text); err != nil {
+			return "", err
+		}
+		c.compiled.Templates[text] = tmpl
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			addReads(c.reads, t.Tree.Root)
+		}
+	}
+	return text, nil
+}
+
+// addReads adds what a template parse tree node reads to reads. Fields are
+// recognized by name wherever they appear, and dot or $ on their own read all
+// the data, so reads errs on the side of true.
+func addReads(reads *runtime.TemplateReads, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			addReads(reads, child)
+		}
+	case *parse.ActionNode:
+		addReads(reads, n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, v := range n.Decl {
+			addReads(reads, v)
+		}
+		for _, cmd := range n.Cmds {
+			addReads(reads, cmd)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			addReads(reads, arg)
+		}
+	case *parse.ChainNode:
+		addReads(reads, n.Node)
+	case *parse.IfNode:
+		addBranchReads(reads, &n.BranchNode)
+	case *parse.RangeNode:
+		addBranchReads(reads, &n.BranchNode)
+	case *parse.WithNode:
+		addBranchReads(reads, &n.BranchNode)
+	case *parse.TemplateNode:
+		addReads(reads, n.Pipe)
+	case *parse.FieldNode:
+		addFieldReads(reads, n.Ident[0])
+	case *parse.VariableNode:
+		if n.Ident[0] == "$" {
+			if len(n.Ident) == 1 {
+				addFieldReads(reads, "")
+			} else {
+				addFieldReads(reads, n.Ident[1])
+			}
+		}
+	case *parse.DotNode:
+		addFieldReads(reads, "")
+	case *parse.IdentifierNode:
+		switch {
+		case n.Ident == "lastCall":
+			reads.RecordedCalls = true
+		case isVolatile(n.Ident):
+			reads.Volatile = true
+		}
+	}
+}
+
+func addBranchReads(reads *runtime.TemplateReads, n *parse.BranchNode) {
+	addReads(reads, n.Pipe)
+	addReads(reads, n.List)
+	addReads(reads, n.ElseList)
+}
+
+// addFieldReads marks a field of templateData as read, or all of them if name is empty.
+func addFieldReads(reads *runtime.TemplateReads, name string) {
+	all := name == ""
+	reads.Request = reads.Request || all || name == "Request"
+	reads.Headers = reads.Headers || all || name == "Headers"
+	reads.Vars = reads.Vars || all || name == "Vars"
+	reads.Stream = reads.Stream || all || name == "Stream"
+	reads.Response = reads.Response || all || name == "Response"
+}
+
+// isVolatile reports whether a template function returns different results on each call.
+func isVolatile(name string) bool {
+	_, fake := fakeFuncs()[name]
+	return fake || volatileHelpers[name]
+}
+
+// Compile compiles the response and webhook templates of an expectation ahead
+// of its calls, attaching them to copies of its responses and webhooks, and
+// reports template syntax errors and invalid bodies at registration.
+func (r *Responder) Compile(exp *runtime.GRPCCallExpectation) error {
+	if err := validateBodies(*exp); err != nil {
+		return err
+	}
+	if exp.Response != nil {
+		resp := *exp.Response
+		if err := r.compileResponse(&resp); err != nil {
+			return err
+		}
+		exp.Response = &resp
+	}
+	if len(exp.Responses) > 0 {
+		exp.Responses = slices.Clone(exp.Responses)
+		for i := range exp.Responses {
+			if err := r.compileResponse(&exp.Responses[i]); err != nil {
+				return err
+			}
+		}
+	}
+	if len(exp.Webhooks) > 0 {
+		exp.Webhooks = slices.Clone(exp.Webhooks)
+		for i := range exp.Webhooks {
+			var err error
+			if exp.Webhooks[i].Compiled, err = r.compileWebhook(i, exp.Webhooks[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// compileResponse compiles the templates of a response, SetVars included, and
+// attaches them to it.
+func (r *Responder) compileResponse(resp *runtime.MockResponse) error {
+	c := r.newTemplateCompiler()
+	walked := *resp
+	if err := walkTemplates(&walked, c.compile); err != nil {
+		return err
+	}
+	if err := checkJSONValues("body", resp.Body); err != nil {
+		return err
+	}
+	for i, body := range resp.Bodies {
+		if err := checkJSONValues(fmt.Sprintf("bodies[%d]", i), body); err != nil {
+			return err
+		}
+	}
+	c.reads = &c.compiled.SetVarsReads
+	for k, v := range resp.SetVars {
+		if _, err := walkTemplate("setVars."+k, v, c.compile); err != nil {
+			return err
+		}
+	}
+	if resp.Script != "" {
+		var err error
+		if c.compiled.Script, err = compileScript(resp.Script); err != nil {
+			return err
+		}
+	}
+	resp.Compiled = c.compiled
+	return nil
+}
+
+// compileWebhook compiles the templates of the i-th webhook of an expectation.
+func (r *Responder) compileWebhook(i int, w runtime.WebhookMock) (*runtime.CompiledTemplates, error) {
+	c := r.newTemplateCompiler()
+	if err := walkWebhookTemplates(i, &w, c.compile); err != nil {
+		return nil, err
+	}
+	if err := checkJSONValues(fmt.Sprintf("webhooks[%d].body", i), w.Body); err != nil {
+		return nil, err
+	}
+	return c.compiled, nil
+}
+
+// compiledResponse returns the compiled templates of a response, compiling
+// them now if the response was not compiled at registration, e.g. because its
+// body comes from a fixture.
+func (r *Responder) compiledResponse(resp *runtime.MockResponse) (*runtime.CompiledTemplates, error) {
+	if resp.Compiled != nil {
+		return resp.Compiled, nil
+	}
+	compiled := *resp
+	if err := r.compileResponse(&compiled); err != nil {
+		return nil, err
+	}
+	return compiled.Compiled, nil
+}
+
+// applyTemplates renders the response templates with the call's data.
+func (r *Responder) applyTemplates(resp *runtime.MockResponse, compiled *runtime.CompiledTemplates, data templateData) error {
+	if err := walkTemplates(resp, r.executeTemplate(compiled, data)); err != nil {
+		return err
+	}
+	var err error
+	if resp.Body, err = decodeJSONValues("body", resp.Body); err != nil {
+		return err
+	}
+	for i := range resp.Bodies {
+		if resp.Bodies[i], err = decodeJSONValues(fmt.Sprintf("bodies[%d]", i), resp.Bodies[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// executeTemplate returns a function rendering compiled templates with the
+// call's data. Templates missing from compiled are compiled first.
+func (r *Responder) executeTemplate(compiled *runtime.CompiledTemplates, data templateData) func(name, text string) (string, error) {
+	return func(name, text string) (string, error) {
+		tmpl, ok := compiled.Templates[text]
+		if !ok {
+			var err error
+			if tmpl, err = r.newTemplate(name, text); err != nil {
+				return "", err
+			}
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("failed to execute response template %s: %w", name, err)
+		}
+		return buf.String(), nil
+	}
+}
+
+// walkTemplates replaces the templates of the response bodies' string values,
+// header values and error message, i.e. those containing actions, with the
+// result of fn. The response's bodies, headers and error are copied, not modified.
+func walkTemplates(resp *runtime.MockResponse, fn func(name, text string) (string, error)) error {
+	var err error
+	if resp.Body, err = walkJSONTemplates("body", resp.Body, fn); err != nil {
+		return err
+	}
+	if len(resp.Bodies) > 0 {
+		bodies := make([]json.RawMessage, len(resp.Bodies))
+		for i, body := range resp.Bodies {
+			if bodies[i], err = walkJSONTemplates(fmt.Sprintf("bodies[%d]", i), body, fn); err != nil {
+				return err
+			}
+		}
+		resp.Bodies = bodies
+	}
+	if len(resp.Headers) > 0 {
+		headers := make(map[string]string, len(resp.Headers))
+		for k, v := range resp.Headers {
+			if headers[k], err = walkTemplate("headers."+k, v, fn); err != nil {
+				return err
+			}
+		}
+		resp.Headers = headers
+	}
+	if resp.Error != nil {
+		rpcErr := *resp.Error
+		if rpcErr.Message, err = walkTemplate("error.message", rpcErr.Message, fn); err != nil {
+			return err
+		}
+		resp.Error = &rpcErr
+	}
+	return nil
+}
+
+// walkWebhookTemplates replaces the templates of the i-th webhook's URL, header
+// values and body string values with the result of fn. Its headers and body
+// are copied, not modified.
+func walkWebhookTemplates(i int, w *runtime.WebhookMock, fn func(name, text string) (string, error)) error {
+	prefix := fmt.Sprintf("webhooks[%d].", i)
+	var err error
+	if w.URL, err = walkTemplate(prefix+"url", w.URL, fn); err != nil {
+		return err
+	}
+	if len(w.Headers) > 0 {
+		headers := make(map[string]string, len(w.Headers))
+		for k, v := range w.Headers {
+			if headers[k], err = walkTemplate(prefix+"headers."+k, v, fn); err != nil {
+				return err
+			}
+		}
+		w.Headers = headers
+	}
+	w.Body, err = walkJSONTemplates(prefix+"body", w.Body, fn)
+	return err
+}
+
+// walkJSONTemplates applies fn to the templates among the string values of a JSON body.
+func walkJSONTemplates(name string, body json.RawMessage, fn func(name, text string) (string, error)) (json.RawMessage, error) {
+	if !bytes.Contains(body, []byte("{{")) {
+		return body, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("failed to decode response %s: %w", name, err)
+	}
+	v, err := walkJSONValue(name, v, fn)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// walkJSONValue applies fn to the templates among the string values of a decoded JSON value.
+func walkJSONValue(name string, v interface{}, fn func(name, text string) (string, error)) (interface{}, error) {
+	var err error
+	switch t := v.(type) {
+	case string:
+		return walkTemplate(name, t, fn)
+	case map[string]interface{}:
+		for k, elem := range t {
+			if t[k], err = walkJSONValue(name+"."+k, elem, fn); err != nil {
+				return nil, err
+			}
+		}
+	case []interface{}:
+		for i, elem := range t {
+			if t[i], err = walkJSONValue(fmt.Sprintf("%s[%d]", name, i), elem, fn); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return v, nil
+}
+
+// jsonValuePrefix marks a body string value holding JSON to insert in its place
+// once rendered, so templates can produce numbers, booleans, objects and
+// arrays, e.g. "${json}{{.Stream.Count}}".
+const jsonValuePrefix = "${json}"
+
+// decodeJSONValues replaces the string values of a rendered body marked with
+// jsonValuePrefix with the JSON values they hold.
+func decodeJSONValues(name string, body json.RawMessage) (json.RawMessage, error) {
+	if !bytes.Contains(body, []byte(jsonValuePrefix)) {
+		return body, nil
+	}
+	v, err := decodeJSON(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response %s: %w", name, err)
+	}
+	if v, err = walkJSONValues(name, v, false); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// checkJSONValues checks the marked string values of a body that are not
+// templates, which must already hold valid JSON.
+func checkJSONValues(name string, body json.RawMessage) error {
+	if !bytes.Contains(body, []byte(jsonValuePrefix)) {
+		return nil
+	}
+	v, err := decodeJSON(body)
+	if err != nil {
+		return fmt.Errorf("failed to decode response %s: %w", name, err)
+	}
+	_, err = walkJSONValues(name, v, true)
+	return err
+}
+
+// walkJSONValues replaces the string values marked with jsonValuePrefix with
+// the JSON values they hold, skipping templates if skipTemplates is set.
+func walkJSONValues(name string, v interface{}, skipTemplates bool) (interface{}, error) {
+	var err error
+	switch t := v.(type) {
+	case string:
+		text, ok := strings.CutPrefix(t, jsonValuePrefix)
+		if !ok || (skipTemplates && strings.Contains(text, "{{")) {
+			return t, nil
+		}
+		value, err := decodeJSON([]byte(text))
+		if err != nil {
+			return nil, fmt.Errorf("response %s: %s value %q is not JSON", name, jsonValuePrefix, text)
+		}
+		return value, nil
+	case map[string]interface{}:
+		for k, elem := range t {
+			if t[k], err = walkJSONValues(name+"."+k, elem, skipTemplates); err != nil {
+				return nil, err
+			}
+		}
+	case []interface{}:
+		for i, elem := range t {
+			if t[i], err = walkJSONValues(fmt.Sprintf("%s[%d]", name, i), elem, skipTemplates); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return v, nil
+}
+
+// decodeJSON decodes a single JSON value, keeping numbers as json.Number so
+// 64-bit integers keep their precision.
+func decodeJSON(data []byte) (interface{}, error) {
+	if !json.Valid(data) {
+		return nil, errors.New("invalid JSON")
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	err := dec.Decode(&v)
+	return v, err
+}
+
+// walkTemplate applies fn to text if it is a template, i.e. has actions.
+func walkTemplate(name, text string, fn func(name, text string) (string, error)) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	return fn(name, text)
+}
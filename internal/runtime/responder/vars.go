@@ -0,0 +1,49 @@
+package responder
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// setVars renders the SetVars templates of a rendered response and stores the
+// variables. The templates have the response's data, plus the rendered body as
+// .Response, e.g. to keep an ID generated by a Create method for later calls.
+func (r *Responder) setVars(
+	fullMethodName string,
+	resp *runtime.MockResponse,
+	reqBodyProto proto.Message,
+	headers metadata.MD,
+	matches map[string]string,
+) error {
+	compiled, err := r.compiledResponse(resp)
+	if err != nil {
+		return err
+	}
+	reads := compiled.SetVarsReads
+	data := templateData{Matches: matches, Headers: headers}
+	if reads.Vars {
+		data.Vars = r.Store.GetVars()
+	}
+	if reads.Request {
+		if data.Request, err = requestJSON(reqBodyProto); err != nil {
+			return fmt.Errorf("failed to read request for %s: %w", fullMethodName, err)
+		}
+	}
+	if reads.Response && len(resp.Body) > 0 {
+		if err := json.Unmarshal(resp.Body, &data.Response); err != nil {
+			return fmt.Errorf("failed to read response of %s: %w", fullMethodName, err)
+		}
+	}
+	vars := make(map[string]string, len(resp.SetVars))
+	for k, v := range resp.SetVars {
+		if vars[k], err = walkTemplate("setVars."+k, v, r.executeTemplate(compiled, data)); err != nil {
+			return err
+		}
+	}
+	r.Store.SetVars(vars)
+	return nil
+}
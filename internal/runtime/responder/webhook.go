@@ -0,0 +1,84 @@
+package responder
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// FireWebhooks renders the webhooks of the matched expectation with the call's
+// data, as responses are, and sends them in the background, each after its
+// delay on the store's clock. Failures are logged, never reported to the caller.
+func (r *Responder) FireWebhooks(
+	fullMethodName string,
+	exp *runtime.GRPCCallExpectation,
+	reqBodyProto proto.Message,
+	headers metadata.MD,
+	matches map[string]string,
+) {
+	if len(exp.Webhooks) == 0 {
+		return
+	}
+	data := templateData{Matches: matches, Headers: headers}
+	for i, w := range exp.Webhooks {
+		compiled := w.Compiled
+		if compiled == nil {
+			var err error
+			if compiled, err = r.compileWebhook(i, w); err != nil {
+				log.Printf("grpcmockruntime: failed to compile webhook %d of %s: %v", i, fullMethodName, err)
+				continue
+			}
+		}
+		if compiled.Reads.Request && data.Request == nil {
+			var err error
+			if data.Request, err = requestJSON(reqBodyProto); err != nil {
+				log.Printf("grpcmockruntime: failed to read request for %s webhooks: %v", fullMethodName, err)
+				return
+			}
+		}
+		err := walkWebhookTemplates(i, &w, r.executeTemplate(compiled, data))
+		if err == nil {
+			w.Body, err = decodeJSONValues(fmt.Sprintf("webhooks[%d].body", i), w.Body)
+		}
+		if err != nil {
+			log.Printf("grpcmockruntime: failed to render webhook %d of %s: %v", i, fullMethodName, err)
+			continue
+		}
+		go r.sendWebhook(fullMethodName, w)
+	}
+}
+
+// sendWebhook sends a rendered webhook once its delay has elapsed.
+func (r *Responder) sendWebhook(fullMethodName string, w runtime.WebhookMock) {
+	if w.DelayMs > 0 {
+		<-r.Store.Clock().After(time.Duration(w.DelayMs) * time.Millisecond)
+	}
+	req, err := http.NewRequest(w.HTTPMethod(), w.URL, bytes.NewReader(w.Body))
+	if err != nil {
+		log.Printf("grpcmockruntime: invalid webhook of %s: %v", fullMethodName, err)
+		return
+	}
+	for k, v := range w.Headers {
+		req.Header.Set(k, v)
+	}
+	if len(w.Body) > 0 && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		log.Printf("grpcmockruntime: failed to send webhook %s %s of %s: %v", req.Method, w.URL, fullMethodName, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("grpcmockruntime: webhook %s %s of %s answered %s", req.Method, w.URL, fullMethodName, resp.Status)
+		return
+	}
+	log.Printf("grpcmockruntime: Sent webhook %s %s of %s", req.Method, w.URL, fullMethodName)
+}
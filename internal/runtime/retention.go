@@ -0,0 +1,65 @@
+package runtime
+
+import (
+	"fmt"
+	"time"
+)
+
+// Retention bounds the recorded and unmatched calls a namespace keeps, so
+// long-lived namespaces keep little history while others keep everything
+// until cleared. Zero means unlimited.
+type Retention struct {
+	MaxCalls int   `json:"maxCalls,omitempty"` // Applies to recorded and unmatched calls separately; the oldest are discarded
+	TTLMs    int64 `json:"ttlMs,omitempty"`    // Age after which calls are discarded
+}
+
+// TTL returns the age after which calls are discarded, or 0 if they are kept.
+func (r Retention) TTL() time.Duration {
+	return time.Duration(r.TTLMs) * time.Millisecond
+}
+
+// Unlimited reports whether the retention keeps every call.
+func (r Retention) Unlimited() bool {
+	return r.MaxCalls <= 0 && r.TTLMs <= 0
+}
+
+// RetentionPolicy sets the retention of each namespace, see Namespace and
+// GRPCCallExpectation.Namespace.
+type RetentionPolicy struct {
+	Default    Retention            `json:"default"`              // Applies to namespaces without their own retention
+	Namespaces map[string]Retention `json:"namespaces,omitempty"` // By namespace
+}
+
+// For returns the retention of a namespace.
+func (p RetentionPolicy) For(ns string) Retention {
+	if r, ok := p.Namespaces[ns]; ok {
+		return r
+	}
+	return p.Default
+}
+
+// Unlimited reports whether every namespace keeps every call.
+func (p RetentionPolicy) Unlimited() bool {
+	if !p.Default.Unlimited() {
+		return false
+	}
+	for _, r := range p.Namespaces {
+		if !r.Unlimited() {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate checks that the limits are not negative.
+func (p RetentionPolicy) Validate() error {
+	if p.Default.MaxCalls < 0 || p.Default.TTLMs < 0 {
+		return fmt.Errorf("retention limits must not be negative")
+	}
+	for ns, r := range p.Namespaces {
+		if r.MaxCalls < 0 || r.TTLMs < 0 {
+			return fmt.Errorf("retention limits of namespace %q must not be negative", ns)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package runtime
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Sampling sets the share of calls recorded, so the mock can sustain load-test
+// throughput while retaining a sample of the traffic. Rates range from 0 (none)
+// to 1 (all). Sampled-out calls are still served; unmatched calls are always recorded.
+type Sampling struct {
+	Rate    float64            `json:"rate"`              // Applies to methods without their own rate
+	Methods map[string]float64 `json:"methods,omitempty"` // Per-method rates by full method name
+}
+
+// DefaultSampling records every call.
+var DefaultSampling = Sampling{Rate: 1}
+
+// RecordsAll reports whether every call is recorded.
+func (s Sampling) RecordsAll() bool {
+	if s.Rate < 1 {
+		return false
+	}
+	for _, rate := range s.Methods {
+		if rate < 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// UsesRecordedCalls reports whether the expectation reads the recorded calls,
+// through After or templates calling lastCall, which sampling would hide from
+// it. Templates are only known once compiled, at registration.
+func (e *GRPCCallExpectation) UsesRecordedCalls() bool {
+	if e.After != nil || (e.Response != nil && e.Response.Compiled.readsRecordedCalls()) {
+		return true
+	}
+	for i := range e.Responses {
+		if e.Responses[i].Compiled.readsRecordedCalls() {
+			return true
+		}
+	}
+	for i := range e.Webhooks {
+		if e.Webhooks[i].Compiled.readsRecordedCalls() {
+			return true
+		}
+	}
+	return false
+}
+
+// RateFor returns the sampling rate of a method.
+func (s Sampling) RateFor(fullMethodName string) float64 {
+	if rate, ok := s.Methods[fullMethodName]; ok {
+		return rate
+	}
+	return s.Rate
+}
+
+// Validate checks that the rates range from 0 to 1.
+func (s Sampling) Validate() error {
+	if s.Rate < 0 || s.Rate > 1 {
+		return fmt.Errorf("sampling rate %v must be between 0 and 1", s.Rate)
+	}
+	for method, rate := range s.Methods {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("sampling rate %v of %s must be between 0 and 1", rate, method)
+		}
+	}
+	return nil
+}
+
+// ParseSamplingRates parses per-method rates of the form
+// "/pkg.Svc/Method=0.1,/pkg.Svc/Other=0".
+func ParseSamplingRates(s string) (map[string]float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	rates := make(map[string]float64)
+	for _, entry := range strings.Split(s, ",") {
+		method, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || method == "" {
+			return nil, fmt.Errorf("invalid sampling rate %q, want /pkg.Svc/Method=rate", entry)
+		}
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sampling rate for %s: %w", method, err)
+		}
+		rates[method] = rate
+	}
+	return rates, nil
+}
+
+// SamplingUsage reports the sampling rates and their effect.
+type SamplingUsage struct {
+	Sampling     Sampling `json:"sampling"`
+	SkippedCalls int      `json:"skippedCalls"` // Calls served but not recorded since startup
+}
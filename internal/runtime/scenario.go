@@ -0,0 +1,24 @@
+package runtime
+
+import "fmt"
+
+// ScenarioStarted is the state of a scenario until an expectation moves it to another.
+const ScenarioStarted = "Started"
+
+// ScenarioState is the current state of a scenario.
+type ScenarioState struct {
+	State string `json:"state"`
+}
+
+// ValidateScenario checks that the expectation sets the scenario its states refer to.
+func (e *GRPCCallExpectation) ValidateScenario() error {
+	if e.Scenario == "" && (e.RequiredState != "" || e.NewState != "") {
+		return fmt.Errorf("requiredState and newState require scenario")
+	}
+	return nil
+}
+
+// InScenarioState reports whether a scenario in the given state lets the expectation match.
+func (e *GRPCCallExpectation) InScenarioState(state string) bool {
+	return e.Scenario == "" || e.RequiredState == "" || e.RequiredState == state
+}
@@ -0,0 +1,109 @@
+package runtime
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Validate checks that all windows of the schedule can be evaluated.
+func (s *Schedule) Validate() error {
+	for i, w := range s.Windows {
+		if _, _, _, _, err := w.parse(); err != nil {
+			return fmt.Errorf("schedule window %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Active reports whether the schedule allows matching at the given time.
+// A nil schedule is always active. Invalid windows never contain any time.
+func (s *Schedule) Active(now time.Time) bool {
+	if s == nil {
+		return true
+	}
+	if s.ActiveFrom != nil && now.Before(*s.ActiveFrom) {
+		return false
+	}
+	if s.ActiveUntil != nil && !now.Before(*s.ActiveUntil) {
+		return false
+	}
+	if len(s.Windows) == 0 {
+		return true
+	}
+	for _, w := range s.Windows {
+		if w.contains(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether the window contains the given time.
+func (w ScheduleWindow) contains(now time.Time) bool {
+	days, start, end, loc, err := w.parse()
+	if err != nil {
+		return false
+	}
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	day := local.Weekday()
+	if start <= end {
+		return days[day] && minute >= start && minute < end
+	}
+	// The window wraps past midnight: the early-morning part belongs to the previous day's window.
+	if minute >= start {
+		return days[day]
+	}
+	return days[(day+6)%7] && minute < end
+}
+
+// parse returns the allowed weekdays, the start and end minutes of the day, and the location.
+func (w ScheduleWindow) parse() (days [7]bool, start, end int, loc *time.Location, err error) {
+	if start, err = parseClock(w.Start); err != nil {
+		return days, 0, 0, nil, fmt.Errorf("invalid start: %w", err)
+	}
+	if end, err = parseClock(w.End); err != nil {
+		return days, 0, 0, nil, fmt.Errorf("invalid end: %w", err)
+	}
+	loc = time.UTC
+	if w.Timezone != "" {
+		if loc, err = time.LoadLocation(w.Timezone); err != nil {
+			return days, 0, 0, nil, fmt.Errorf("invalid timezone: %w", err)
+		}
+	}
+	if len(w.Days) == 0 {
+		for i := range days {
+			days[i] = true
+		}
+		return days, start, end, loc, nil
+	}
+	for _, d := range w.Days {
+		wd, ok := parseWeekday(d)
+		if !ok {
+			return days, 0, 0, nil, fmt.Errorf("invalid day %q", d)
+		}
+		days[wd] = true
+	}
+	return days, start, end, loc, nil
+}
+
+// parseClock parses "HH:MM" into minutes since midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// parseWeekday parses a full or three-letter English weekday name, case-insensitively.
+func parseWeekday(s string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := d.String()
+		if strings.EqualFold(s, name) || strings.EqualFold(s, name[:3]) {
+			return d, true
+		}
+	}
+	return 0, false
+}
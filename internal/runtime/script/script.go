@@ -0,0 +1,80 @@
+package script
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+	"github.com/rbroggi/grpcmock/internal/runtime/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// messageMatcher checks single stream messages against request matchers.
+type messageMatcher interface {
+	MatchMessage(ctx context.Context, headers metadata.MD, rm *runtime.RequestMatcher, msg proto.Message) bool
+}
+
+// Runner walks bidirectional streams through the scripts of their expectations.
+type Runner struct {
+	Matcher messageMatcher
+}
+
+// New creates a Runner checking messages with the given matcher.
+func New(matcher messageMatcher) *Runner {
+	return &Runner{Matcher: matcher}
+}
+
+// Run plays a stream script. first is the message already received to match the
+// expectation (nil if the client sent none); newRequest and newResponse allocate
+// the method's request and response messages. A client message that does not
+// match its step, or a stream closed before the script ends, fails the call with
+// FailedPrecondition. The server side of the stream closes after the last step.
+func (r *Runner) Run(
+	stream grpc.ServerStream,
+	fullMethodName string,
+	steps []runtime.StreamStep,
+	first proto.Message,
+	newRequest, newResponse func() proto.Message,
+) error {
+	headers, _ := metadata.FromIncomingContext(stream.Context())
+	pending := first
+	received := 0
+	if first != nil {
+		received = 1
+	}
+	for i, step := range steps {
+		if step.ExpectRequest != nil {
+			msg := pending
+			pending = nil
+			if msg == nil {
+				msg = newRequest()
+				if err := stream.RecvMsg(msg); err == io.EOF {
+					return status.Errorf(codes.FailedPrecondition, "grpcmock: stream ended before script step %d", i)
+				} else if err != nil {
+					return err
+				}
+				received++
+			}
+			if !r.Matcher.MatchMessage(stream.Context(), headers, step.ExpectRequest, msg) {
+				log.Printf("grpcmockruntime: %s message #%d does not match script step %d", fullMethodName, received, i)
+				return status.Errorf(codes.FailedPrecondition, "grpcmock: message #%d does not match script step %d", received, i)
+			}
+		}
+		for _, body := range step.SendResponses {
+			resp := newResponse()
+			if err := storage.DefaultUnmarshaler.Unmarshal(body, resp); err != nil {
+				return status.Errorf(codes.Internal, "failed to unmarshal script response of step %d: %v", i, err)
+			}
+			if err := stream.SendMsg(resp); err != nil {
+				return fmt.Errorf("failed to send script response of step %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
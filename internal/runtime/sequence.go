@@ -0,0 +1,155 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Behaviors of response sequences once every response was served, see GRPCCallExpectation.Responses.
+const (
+	AfterResponsesRepeatLast = "repeatLast"
+	AfterResponsesRotate     = "rotate"
+	AfterResponsesError      = "error"
+)
+
+// ValidateResponses checks the expectation's response or response sequence.
+func (e *GRPCCallExpectation) ValidateResponses() error {
+	if err := e.validateSequence(); err != nil {
+		return err
+	}
+	if e.FailFirst != nil {
+		if err := e.FailFirst.Validate(); err != nil {
+			return fmt.Errorf("invalid failFirst: %w", err)
+		}
+	}
+	for i, resp := range e.allResponses() {
+		field := "response"
+		if len(e.Responses) > 0 {
+			field = fmt.Sprintf("responses[%d]", i)
+		}
+		if err := resp.ValidateStatusCodes(); err != nil {
+			return fmt.Errorf("%s: %w", field, err)
+		}
+		if resp.Delay != nil {
+			if err := resp.Delay.Validate(); err != nil {
+				return fmt.Errorf("%s: invalid delay: %w", field, err)
+			}
+		}
+		if err := resp.ValidateFault(); err != nil {
+			return fmt.Errorf("%s: %w", field, err)
+		}
+		if err := resp.ValidateCompression(); err != nil {
+			return fmt.Errorf("%s: %w", field, err)
+		}
+		if err := resp.ValidateMessageInterval(); err != nil {
+			return fmt.Errorf("%s: %w", field, err)
+		}
+		if resp.Infinite != nil {
+			if err := resp.Infinite.Validate(); err != nil {
+				return fmt.Errorf("%s: invalid infinite stream: %w", field, err)
+			}
+		}
+	}
+	return nil
+}
+
+// allResponses returns Response, if set, followed by Responses.
+func (e *GRPCCallExpectation) allResponses() []*MockResponse {
+	var resps []*MockResponse
+	if e.Response != nil {
+		resps = append(resps, e.Response)
+	}
+	for i := range e.Responses {
+		resps = append(resps, &e.Responses[i])
+	}
+	return resps
+}
+
+// validateSequence checks the expectation's response sequence settings.
+func (e *GRPCCallExpectation) validateSequence() error {
+	if len(e.Responses) == 0 {
+		if e.AfterResponses != "" {
+			return fmt.Errorf("afterResponses requires responses")
+		}
+		return nil
+	}
+	if e.Response != nil {
+		return fmt.Errorf("response and responses cannot both be set")
+	}
+	switch e.AfterResponses {
+	case "", AfterResponsesRepeatLast, AfterResponsesRotate, AfterResponsesError:
+		return nil
+	default:
+		return fmt.Errorf("invalid afterResponses %q, want %s, %s or %s", e.AfterResponses,
+			AfterResponsesRepeatLast, AfterResponsesRotate, AfterResponsesError)
+	}
+}
+
+// ResponseFor returns the response to the nth call (from 1) matched by the
+// expectation: Response, or the nth of Responses, which are exhausted after
+// their last one as set by AfterResponses.
+// The first calls fail instead if FailFirst is set, and the sequence starts after them.
+func (e *GRPCCallExpectation) ResponseFor(n int) *MockResponse {
+	if e.FailFirst != nil {
+		if n <= e.FailFirst.Count {
+			return e.FailFirst.response(n)
+		}
+		n -= e.FailFirst.Count
+	}
+	if len(e.Responses) == 0 {
+		return e.Response
+	}
+	if n < 1 {
+		n = 1
+	}
+	if n <= len(e.Responses) {
+		return &e.Responses[n-1]
+	}
+	switch e.AfterResponses {
+	case AfterResponsesRotate:
+		return &e.Responses[(n-1)%len(e.Responses)]
+	case AfterResponsesError:
+		return &MockResponse{Error: &RPCError{
+			Code:    codes.ResourceExhausted,
+			Message: fmt.Sprintf("grpcmock: all %d responses of the expectation were served", len(e.Responses)),
+		}}
+	default:
+		return &e.Responses[len(e.Responses)-1]
+	}
+}
+
+// Validate checks that the count is positive and the code canonical.
+func (f *FailFirstMock) Validate() error {
+	if f.Count <= 0 {
+		return fmt.Errorf("count must be positive")
+	}
+	return ValidateStatusCode(f.Code)
+}
+
+// MarshalJSON renders Code by name, as RPCError does.
+func (f FailFirstMock) MarshalJSON() ([]byte, error) {
+	type failFirstMock FailFirstMock
+	return json.Marshal(struct {
+		Code json.RawMessage `json:"code,omitempty"`
+		failFirstMock
+	}{statusCodeJSON(f.code()), failFirstMock(f)})
+}
+
+// code returns the status code of the failures.
+func (f *FailFirstMock) code() codes.Code {
+	if f.Code == codes.OK {
+		return codes.Unavailable
+	}
+	return f.Code
+}
+
+// response returns the failure of the nth call (from 1).
+func (f *FailFirstMock) response(n int) *MockResponse {
+	msg := f.Message
+	if msg == "" {
+		msg = fmt.Sprintf("grpcmock: failing call %d of the first %d", n, f.Count)
+	}
+	return &MockResponse{Error: &RPCError{Code: f.code(), Message: msg}}
+}
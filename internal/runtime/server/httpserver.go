@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/rbroggi/grpcmock/internal/runtime"
@@ -14,16 +16,21 @@ import (
 
 // storeInterface defines the methods that a store should implement.
 type storeInterface interface {
-	AddExpectation(exp runtime.GRPCCallExpectation) error
+	AddExpectation(exp runtime.GRPCCallExpectation) (string, error)
 	GetExpectations() map[string][]runtime.GRPCCallExpectation
 	GetRecordedCalls() []runtime.RecordedGRPCCall
+	GetUnmatchedCalls() []runtime.UnmatchedGRPCCall
 	ClearAll()
 }
 
 // writeErrorResponse writes an error response in JSON format.
 func writeErrorResponse(w http.ResponseWriter, statusCode int, message string, err error) {
+	resp := map[string]string{"error": message}
+	if err != nil {
+		resp["details"] = err.Error()
+	}
 	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(map[string]string{"error": message, "details": err.Error()})
+	json.NewEncoder(w).Encode(resp)
 }
 
 // writeJSONResponse writes a response in JSON format.
@@ -41,9 +48,295 @@ func StartHTTPServer(httpPort string, httpMux *http.ServeMux, store storeInterfa
 	httpMux.HandleFunc("/expectations", func(w http.ResponseWriter, r *http.Request) {
 		handleExpectations(w, r, store)
 	})
+	httpMux.HandleFunc("/expectations/export", func(w http.ResponseWriter, r *http.Request) {
+		handleExport(w, r, store)
+	})
 	httpMux.HandleFunc("/verifications", func(w http.ResponseWriter, r *http.Request) {
 		handleVerifications(w, r, store)
 	})
+	httpMux.HandleFunc("/unmatched", func(w http.ResponseWriter, r *http.Request) {
+		handleUnmatched(w, r, store)
+	})
+	apiDoc := openAPI()
+	httpMux.HandleFunc("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		handleOpenAPI(w, r, apiDoc)
+	})
+
+	if itemStore, ok := store.(expectationItemStore); ok {
+		httpMux.HandleFunc("/expectations/", func(w http.ResponseWriter, r *http.Request) {
+			handleExpectation(w, r, itemStore)
+		})
+	}
+
+	if importStore, ok := store.(interface {
+		ImportExpectations(exps []runtime.GRPCCallExpectation) ([]string, error)
+	}); ok {
+		httpMux.HandleFunc("/expectations/import", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+				return
+			}
+			data, err := io.ReadAll(r.Body)
+			if err != nil {
+				writeErrorResponse(w, http.StatusBadRequest, "Failed to read expectations", err)
+				return
+			}
+			exps, err := runtime.ParseExpectations(data)
+			if err == nil {
+				var ids []string
+				if ids, err = importStore.ImportExpectations(exps); err == nil {
+					writeJSONResponse(w, http.StatusCreated, map[string]interface{}{"message": "Expectations imported", "ids": ids})
+					return
+				}
+			}
+			var itemErrs runtime.ImportErrors
+			switch {
+			case errors.As(err, &itemErrs):
+				writeJSONResponse(w, http.StatusBadRequest, map[string]interface{}{"error": "Invalid expectations, none imported", "items": itemErrs})
+			case errors.Is(err, runtime.ErrQuotaExceeded):
+				writeErrorResponse(w, http.StatusTooManyRequests, "Expectation quota exceeded, none imported", err)
+			default:
+				writeErrorResponse(w, http.StatusBadRequest, "Failed to decode expectations", err)
+			}
+		})
+	}
+
+	if connStore, ok := store.(interface {
+		GetConnectionEvents() []runtime.ConnectionEvent
+	}); ok {
+		httpMux.HandleFunc("/verifications/connections", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+				return
+			}
+			writeJSONResponse(w, http.StatusOK, connStore.GetConnectionEvents())
+		})
+	}
+
+	// streamsDone ends the call streams when the server shuts down, as they never end by themselves.
+	streamsDone := make(chan struct{})
+	if callStore, ok := store.(callSubscriber); ok {
+		httpMux.HandleFunc("/verifications/stream", func(w http.ResponseWriter, r *http.Request) {
+			handleCallStream(w, r, callStore, streamsDone)
+		})
+	}
+
+	if dupStore, ok := store.(interface {
+		GetDuplicateRequests() []runtime.DuplicateRequest
+	}); ok {
+		httpMux.HandleFunc("/verifications/duplicates", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+				return
+			}
+			writeJSONResponse(w, http.StatusOK, dupStore.GetDuplicateRequests())
+		})
+	}
+
+	if slowStore, ok := store.(interface {
+		GetSlowCalls() runtime.SlowCallReport
+	}); ok {
+		httpMux.HandleFunc("/verifications/slow-calls", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+				return
+			}
+			writeJSONResponse(w, http.StatusOK, slowStore.GetSlowCalls())
+		})
+	}
+
+	if samplingStore, ok := store.(interface {
+		SetSampling(sampling runtime.Sampling) error
+		SamplingUsage() runtime.SamplingUsage
+	}); ok {
+		httpMux.HandleFunc("/sampling", func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet:
+				writeJSONResponse(w, http.StatusOK, samplingStore.SamplingUsage())
+			case http.MethodPut:
+				var sampling runtime.Sampling
+				if err := json.NewDecoder(r.Body).Decode(&sampling); err != nil {
+					writeErrorResponse(w, http.StatusBadRequest, "Failed to decode sampling", err)
+					return
+				}
+				if err := samplingStore.SetSampling(sampling); err != nil {
+					writeErrorResponse(w, http.StatusBadRequest, "Invalid sampling", err)
+					return
+				}
+				writeJSONResponse(w, http.StatusOK, samplingStore.SamplingUsage())
+			default:
+				writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+			}
+		})
+	}
+
+	if reflectionStore, ok := store.(interface {
+		SetReflection(reflection runtime.Reflection)
+		GetReflection() runtime.Reflection
+	}); ok {
+		httpMux.HandleFunc("/reflection", func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet:
+				writeJSONResponse(w, http.StatusOK, reflectionStore.GetReflection())
+			case http.MethodPut:
+				var reflection runtime.Reflection
+				if err := json.NewDecoder(r.Body).Decode(&reflection); err != nil {
+					writeErrorResponse(w, http.StatusBadRequest, "Failed to decode reflection", err)
+					return
+				}
+				reflectionStore.SetReflection(reflection)
+				writeJSONResponse(w, http.StatusOK, reflectionStore.GetReflection())
+			case http.MethodDelete:
+				reflectionStore.SetReflection(runtime.Reflection{})
+				writeJSONResponse(w, http.StatusOK, map[string]string{"message": "All methods advertised"})
+			default:
+				writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+			}
+		})
+	}
+
+	if scenarioStore, ok := store.(interface {
+		GetScenarios() map[string]runtime.ScenarioState
+		SetScenarioState(name, state string)
+		ResetScenarios()
+	}); ok {
+		httpMux.HandleFunc("/scenarios", func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet:
+				writeJSONResponse(w, http.StatusOK, scenarioStore.GetScenarios())
+			case http.MethodDelete:
+				scenarioStore.ResetScenarios()
+				writeJSONResponse(w, http.StatusOK, map[string]string{"message": "All scenarios reset"})
+			default:
+				writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+			}
+		})
+		httpMux.HandleFunc("/scenarios/", func(w http.ResponseWriter, r *http.Request) {
+			name := strings.TrimPrefix(r.URL.Path, "/scenarios/")
+			if name == "" {
+				writeErrorResponse(w, http.StatusNotFound, "Scenario name is required", nil)
+				return
+			}
+			switch r.Method {
+			case http.MethodGet:
+				state, ok := scenarioStore.GetScenarios()[name]
+				if !ok {
+					writeErrorResponse(w, http.StatusNotFound, "Unknown scenario", nil)
+					return
+				}
+				writeJSONResponse(w, http.StatusOK, state)
+			case http.MethodPut:
+				var state runtime.ScenarioState
+				if err := json.NewDecoder(r.Body).Decode(&state); err != nil {
+					writeErrorResponse(w, http.StatusBadRequest, "Failed to decode scenario state", err)
+					return
+				}
+				if state.State == "" {
+					writeErrorResponse(w, http.StatusBadRequest, "Invalid scenario state", errors.New("state is required"))
+					return
+				}
+				scenarioStore.SetScenarioState(name, state.State)
+				writeJSONResponse(w, http.StatusOK, state)
+			case http.MethodDelete:
+				scenarioStore.SetScenarioState(name, runtime.ScenarioStarted)
+				writeJSONResponse(w, http.StatusOK, runtime.ScenarioState{State: runtime.ScenarioStarted})
+			default:
+				writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+			}
+		})
+	}
+
+	if varStore, ok := store.(interface {
+		SetVars(vars map[string]string)
+		GetVars() map[string]string
+		ClearVars()
+	}); ok {
+		httpMux.HandleFunc("/vars", func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet:
+				writeJSONResponse(w, http.StatusOK, varStore.GetVars())
+			case http.MethodPut:
+				var vars map[string]string
+				if err := json.NewDecoder(r.Body).Decode(&vars); err != nil {
+					writeErrorResponse(w, http.StatusBadRequest, "Failed to decode variables", err)
+					return
+				}
+				varStore.SetVars(vars)
+				writeJSONResponse(w, http.StatusOK, varStore.GetVars())
+			case http.MethodDelete:
+				varStore.ClearVars()
+				writeJSONResponse(w, http.StatusOK, map[string]string{"message": "All variables cleared"})
+			default:
+				writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+			}
+		})
+	}
+
+	if fixtureStore, ok := store.(interface {
+		SetFixture(fixture runtime.Fixture) error
+		GetFixtures() map[string]json.RawMessage
+		ClearFixtures()
+	}); ok {
+		httpMux.HandleFunc("/fixtures", func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet:
+				writeJSONResponse(w, http.StatusOK, fixtureStore.GetFixtures())
+			case http.MethodPost:
+				var fixture runtime.Fixture
+				if err := json.NewDecoder(r.Body).Decode(&fixture); err != nil {
+					writeErrorResponse(w, http.StatusBadRequest, "Failed to decode fixture", err)
+					return
+				}
+				if err := fixtureStore.SetFixture(fixture); err != nil {
+					writeErrorResponse(w, http.StatusBadRequest, "Invalid fixture", err)
+					return
+				}
+				writeJSONResponse(w, http.StatusCreated, map[string]string{"message": "Fixture set"})
+			case http.MethodDelete:
+				fixtureStore.ClearFixtures()
+				writeJSONResponse(w, http.StatusOK, map[string]string{"message": "All fixtures cleared"})
+			default:
+				writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+			}
+		})
+	}
+
+	if quotaStore, ok := store.(interface {
+		QuotaUsage() runtime.QuotaUsage
+	}); ok {
+		httpMux.HandleFunc("/quotas", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+				return
+			}
+			writeJSONResponse(w, http.StatusOK, quotaStore.QuotaUsage())
+		})
+	}
+
+	if retentionStore, ok := store.(interface {
+		SetRetention(policy runtime.RetentionPolicy) error
+		Retention() runtime.RetentionPolicy
+	}); ok {
+		httpMux.HandleFunc("/retention", func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet:
+				writeJSONResponse(w, http.StatusOK, retentionStore.Retention())
+			case http.MethodPut:
+				var policy runtime.RetentionPolicy
+				if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+					writeErrorResponse(w, http.StatusBadRequest, "Failed to decode retention policy", err)
+					return
+				}
+				if err := retentionStore.SetRetention(policy); err != nil {
+					writeErrorResponse(w, http.StatusBadRequest, "Invalid retention policy", err)
+					return
+				}
+				writeJSONResponse(w, http.StatusOK, retentionStore.Retention())
+			default:
+				writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+			}
+		})
+	}
 
 	// Add endpoints for match counts and satisfaction verification
 	typedStore, ok := store.(interface {
@@ -86,6 +379,7 @@ func StartHTTPServer(httpPort string, httpMux *http.ServeMux, store storeInterfa
 		Addr:    fmt.Sprintf(":%s", httpPort),
 		Handler: httpMux,
 	}
+	httpServer.RegisterOnShutdown(func() { close(streamsDone) })
 
 	go func() {
 		log.Printf("grpcmockruntime: HTTP mock control server listening on :%s", httpPort)
@@ -107,6 +401,59 @@ func StartHTTPServer(httpPort string, httpMux *http.ServeMux, store storeInterfa
 	return httpServer, shutdownFunc
 }
 
+// replayChecker evaluates proposed expectations against the recorded calls.
+type replayChecker interface {
+	ReplayCheck(exp runtime.GRPCCallExpectation) runtime.ReplayCheckResult
+}
+
+// HandleReplayCheck registers POST /expectations/replay-check, which reports
+// the recorded calls a proposed expectation would have matched, on the mux.
+func HandleReplayCheck(httpMux *http.ServeMux, checker replayChecker) {
+	httpMux.HandleFunc("/expectations/replay-check", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+			return
+		}
+		var exp runtime.GRPCCallExpectation
+		if err := json.NewDecoder(r.Body).Decode(&exp); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "Failed to decode expectation", err)
+			return
+		}
+		if exp.FullMethodName == "" && exp.FullMethodNameRegex == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid expectation", errors.New("fullMethodName is required in expectation"))
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, checker.ReplayCheck(exp))
+	})
+}
+
+// reloader reloads the expectations of stub files.
+type reloader interface {
+	Reload() ([]string, error)
+}
+
+// HandleReload registers POST /expectations/reload, which reloads the
+// expectations of the stub files directory, on the mux.
+func HandleReload(httpMux *http.ServeMux, loader reloader) {
+	httpMux.HandleFunc("/expectations/reload", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+			return
+		}
+		ids, err := loader.Reload()
+		if err != nil {
+			var itemErrs runtime.ImportErrors
+			if errors.As(err, &itemErrs) {
+				writeJSONResponse(w, http.StatusBadRequest, map[string]interface{}{"error": "Invalid expectations, none reloaded", "details": err.Error(), "items": itemErrs})
+				return
+			}
+			writeErrorResponse(w, http.StatusBadRequest, "Failed to reload expectations", err)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]interface{}{"message": "Expectations reloaded", "ids": ids})
+	})
+}
+
 // handleExpectations manages HTTP requests for CRUD operations on expectations.
 func handleExpectations(w http.ResponseWriter, r *http.Request, store storeInterface) {
 	switch r.Method {
@@ -116,11 +463,16 @@ func handleExpectations(w http.ResponseWriter, r *http.Request, store storeInter
 			writeErrorResponse(w, http.StatusBadRequest, "Failed to decode expectation", err)
 			return
 		}
-		if err := store.AddExpectation(exp); err != nil {
+		id, err := store.AddExpectation(exp)
+		if err != nil {
+			if errors.Is(err, runtime.ErrQuotaExceeded) {
+				writeErrorResponse(w, http.StatusTooManyRequests, "Expectation quota exceeded", err)
+				return
+			}
 			writeErrorResponse(w, http.StatusBadRequest, "Invalid expectation", err)
 			return
 		}
-		writeJSONResponse(w, http.StatusCreated, map[string]string{"message": "Expectation added"})
+		writeJSONResponse(w, http.StatusCreated, map[string]string{"message": "Expectation added", "id": id})
 	case http.MethodGet:
 		writeJSONResponse(w, http.StatusOK, store.GetExpectations())
 	case http.MethodDelete:
@@ -131,11 +483,192 @@ func handleExpectations(w http.ResponseWriter, r *http.Request, store storeInter
 	}
 }
 
-// handleVerifications manages HTTP requests for retrieving recorded calls.
+// expectationItemStore defines the storage methods needed to manage expectations by ID.
+type expectationItemStore interface {
+	GetExpectation(id string) (runtime.GRPCCallExpectation, bool)
+	ReplaceExpectation(id string, exp runtime.GRPCCallExpectation) error
+	RemoveExpectation(id string) bool
+}
+
+// handleExpectation manages HTTP requests on the expectation with the ID given in
+// the path, /expectations/{id}: GET returns it, PUT replaces it and DELETE removes it.
+func handleExpectation(w http.ResponseWriter, r *http.Request, store expectationItemStore) {
+	id := strings.TrimPrefix(r.URL.Path, "/expectations/")
+	if id == "" || strings.Contains(id, "/") {
+		writeErrorResponse(w, http.StatusNotFound, "Not found", nil)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		exp, ok := store.GetExpectation(id)
+		if !ok {
+			writeErrorResponse(w, http.StatusNotFound, "Expectation not found", nil)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, exp)
+	case http.MethodPut:
+		var exp runtime.GRPCCallExpectation
+		if err := json.NewDecoder(r.Body).Decode(&exp); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "Failed to decode expectation", err)
+			return
+		}
+		if err := store.ReplaceExpectation(id, exp); err != nil {
+			if errors.Is(err, runtime.ErrExpectationNotFound) {
+				writeErrorResponse(w, http.StatusNotFound, "Expectation not found", err)
+				return
+			}
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid expectation", err)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]string{"message": "Expectation replaced", "id": id})
+	case http.MethodDelete:
+		if !store.RemoveExpectation(id) {
+			writeErrorResponse(w, http.StatusNotFound, "Expectation not found", nil)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]string{"message": "Expectation removed", "id": id})
+	default:
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+	}
+}
+
+// handleExport returns all expectations as a JSON array, or as YAML with
+// ?format=yaml, in the format of POST /expectations/import and stub files.
+func handleExport(w http.ResponseWriter, r *http.Request, store storeInterface) {
+	if r.Method != http.MethodGet {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+	exps := runtime.ExportExpectations(store.GetExpectations())
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(exps)
+	case "yaml":
+		b, err := runtime.ExportYAML(exps)
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to export expectations", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(b)
+	default:
+		writeErrorResponse(w, http.StatusBadRequest, "Unknown format", fmt.Errorf("format %q, want json or yaml", format))
+	}
+}
+
+// handleVerifications manages HTTP requests for retrieving and clearing recorded calls.
 func handleVerifications(w http.ResponseWriter, r *http.Request, store storeInterface) {
 	switch r.Method {
 	case http.MethodGet:
-		writeJSONResponse(w, http.StatusOK, store.GetRecordedCalls())
+		filter, err := runtime.ParseCallFilter(r.URL.Query())
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid filter", err)
+			return
+		}
+		if filter.IsZero() {
+			writeJSONResponse(w, http.StatusOK, store.GetRecordedCalls())
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, filterRecordedCalls(store, filter))
+	case http.MethodDelete:
+		clearer, ok := store.(interface{ ClearCalls() })
+		if !ok {
+			writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+			return
+		}
+		clearer.ClearCalls()
+		writeJSONResponse(w, http.StatusOK, map[string]string{"message": "All recorded calls cleared"})
+	default:
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+	}
+}
+
+// filterRecordedCalls returns the recorded calls selected by the filter, using
+// the store's filtering if it has one.
+func filterRecordedCalls(store storeInterface, filter runtime.CallFilter) []runtime.RecordedGRPCCall {
+	if filtering, ok := store.(interface {
+		FilterRecordedCalls(filter runtime.CallFilter) []runtime.RecordedGRPCCall
+	}); ok {
+		return filtering.FilterRecordedCalls(filter)
+	}
+	calls := make([]runtime.RecordedGRPCCall, 0)
+	for _, call := range store.GetRecordedCalls() {
+		if filter.Matches(&call) {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// callStreamKeepAlive is the interval of the comments keeping idle call streams
+// open through proxies.
+const callStreamKeepAlive = 15 * time.Second
+
+// callSubscriber delivers the recorded calls as they are answered.
+type callSubscriber interface {
+	SubscribeCalls() (<-chan runtime.RecordedGRPCCall, func())
+}
+
+// handleCallStream sends every call recorded from now on and selected by the
+// query's filter, with its response, as a server-sent event whose ID is the
+// call's, until the client disconnects or done is closed.
+func handleCallStream(w http.ResponseWriter, r *http.Request, store callSubscriber, done <-chan struct{}) {
+	if r.Method != http.MethodGet {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeErrorResponse(w, http.StatusInternalServerError, "Streaming unsupported", nil)
+		return
+	}
+	filter, err := runtime.ParseCallFilter(r.URL.Query())
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Invalid filter", err)
+		return
+	}
+	calls, cancel := store.SubscribeCalls()
+	defer cancel()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	keepAlive := time.NewTicker(callStreamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-done:
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case call, ok := <-calls:
+			if !ok {
+				return
+			}
+			if !filter.Matches(&call) {
+				continue
+			}
+			data, err := json.Marshal(call)
+			if err != nil {
+				log.Printf("grpcmockruntime: failed to encode call %d for streaming: %v", call.ID, err)
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\ndata: %s\n\n", call.ID, data)
+		}
+		flusher.Flush()
+	}
+}
+
+// handleUnmatched manages HTTP requests for retrieving calls that matched no expectation.
+func handleUnmatched(w http.ResponseWriter, r *http.Request, store storeInterface) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSONResponse(w, http.StatusOK, store.GetUnmatchedCalls())
 	default:
 		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
 	}
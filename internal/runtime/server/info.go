@@ -0,0 +1,78 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+	"google.golang.org/grpc"
+)
+
+// infoStore defines the storage methods reporting the configuration in Info.
+type infoStore interface {
+	GetFixtures() map[string]json.RawMessage
+	SamplingUsage() runtime.SamplingUsage
+	QuotaUsage() runtime.QuotaUsage
+	Retention() runtime.RetentionPolicy
+	GetReflection() runtime.Reflection
+	GetSlowCalls() runtime.SlowCallReport
+	GetExecCommands() []string
+}
+
+// CollectInfo completes base, holding the ports, start time and modes known to
+// the caller only, with the build, the services and the store's configuration.
+func CollectInfo(base runtime.Info, services map[string]grpc.ServiceInfo, store infoStore) runtime.Info {
+	info := base
+	info.Version, info.GoVersion = runtime.BuildVersion()
+
+	info.Services = make([]runtime.ServiceInfo, 0, len(services))
+	for name, svc := range services {
+		s := runtime.ServiceInfo{Name: name, Methods: make([]string, 0, len(svc.Methods))}
+		s.File, _ = svc.Metadata.(string)
+		for _, m := range svc.Methods {
+			s.Methods = append(s.Methods, "/"+name+"/"+m.Name)
+		}
+		sort.Strings(s.Methods)
+		info.Services = append(info.Services, s)
+	}
+	sort.Slice(info.Services, func(i, j int) bool { return info.Services[i].Name < info.Services[j].Name })
+
+	info.Modes = append([]string{}, base.Modes...)
+	if sampling := store.SamplingUsage().Sampling; sampling.Rate < 1 || len(sampling.Methods) > 0 {
+		info.Modes = append(info.Modes, "sampling")
+	}
+	if store.QuotaUsage().Quotas != (runtime.Quotas{}) {
+		info.Modes = append(info.Modes, "quotas")
+	}
+	if !store.Retention().Unlimited() {
+		info.Modes = append(info.Modes, "retention")
+	}
+	if store.GetReflection().Methods != nil {
+		info.Modes = append(info.Modes, "reflectionFilter")
+	}
+	if store.GetSlowCalls().BudgetMs > 0 {
+		info.Modes = append(info.Modes, "slowCallBudget")
+	}
+	if len(store.GetExecCommands()) > 0 {
+		info.Modes = append(info.Modes, "exec")
+	}
+
+	info.Fixtures = []string{}
+	for name := range store.GetFixtures() {
+		info.Fixtures = append(info.Fixtures, name)
+	}
+	sort.Strings(info.Fixtures)
+	return info
+}
+
+// HandleInfo registers GET /info, which reports the Info returned by info, on the mux.
+func HandleInfo(httpMux *http.ServeMux, info func() runtime.Info) {
+	httpMux.HandleFunc("/info", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, info())
+	})
+}
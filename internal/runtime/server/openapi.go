@@ -0,0 +1,300 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+	"google.golang.org/grpc/codes"
+)
+
+// messageResponse is the answer of the control endpoints that only acknowledge a change.
+type messageResponse struct {
+	Message string `json:"message"`
+}
+
+// idResponse is the answer of the endpoints changing one expectation.
+type idResponse struct {
+	Message string `json:"message"`
+	ID      string `json:"id"`
+}
+
+// idsResponse is the answer of the endpoints loading several expectations.
+type idsResponse struct {
+	Message string   `json:"message"`
+	IDs     []string `json:"ids"`
+}
+
+// errorResponse is the body of every error answer; Items lists the refused
+// expectations of imports and reloads.
+type errorResponse struct {
+	Error   string                `json:"error"`
+	Details string                `json:"details,omitempty"`
+	Items   []runtime.ImportError `json:"items,omitempty"`
+}
+
+// apiOperation describes an operation of the control API. Request and Response
+// are values of the Go types of the bodies, nil if there is none.
+type apiOperation struct {
+	Path     string
+	Method   string
+	Summary  string
+	Query    map[string]string // Query parameter name to description
+	Request  interface{}
+	Status   int
+	Response interface{}
+	// ContentType is the response media type, application/json if empty.
+	ContentType string
+}
+
+// callFilterParams describes the query parameters read by runtime.ParseCallFilter.
+var callFilterParams = map[string]string{
+	"method":  "Only select the calls to this full method name",
+	"traceId": "Only select the calls of this trace",
+	"since":   "Only select the calls received at or after this RFC 3339 time or Unix nano timestamp",
+	"until":   "Only select the calls received before this RFC 3339 time or Unix nano timestamp",
+	"header":  "Only select the calls with this header, given as name:value or name; repeatable",
+}
+
+// apiOperations lists the operations of the control API, including those the
+// mock server only registers in some modes, such as /expectations/reload.
+var apiOperations = []apiOperation{
+	{Path: "/expectations", Method: http.MethodPost, Summary: "Register an expectation", Request: runtime.GRPCCallExpectation{}, Status: http.StatusCreated, Response: idResponse{}},
+	{Path: "/expectations", Method: http.MethodGet, Summary: "List the expectations by full method name", Status: http.StatusOK, Response: map[string][]runtime.GRPCCallExpectation{}},
+	{Path: "/expectations", Method: http.MethodDelete, Summary: "Clear all expectations and recorded calls", Status: http.StatusOK, Response: messageResponse{}},
+	{Path: "/expectations/{id}", Method: http.MethodGet, Summary: "Get an expectation", Status: http.StatusOK, Response: runtime.GRPCCallExpectation{}},
+	{Path: "/expectations/{id}", Method: http.MethodPut, Summary: "Replace an expectation, keeping its ID", Request: runtime.GRPCCallExpectation{}, Status: http.StatusOK, Response: idResponse{}},
+	{Path: "/expectations/{id}", Method: http.MethodDelete, Summary: "Remove an expectation", Status: http.StatusOK, Response: idResponse{}},
+	{Path: "/expectations/import", Method: http.MethodPost, Summary: "Register expectations atomically; YAML documents are accepted too", Request: []runtime.GRPCCallExpectation{}, Status: http.StatusCreated, Response: idsResponse{}},
+	{Path: "/expectations/export", Method: http.MethodGet, Summary: "Export all expectations in the format of imports and stub files", Query: map[string]string{"format": "json (default) or yaml"}, Status: http.StatusOK, Response: []runtime.GRPCCallExpectation{}},
+	{Path: "/expectations/reload", Method: http.MethodPost, Summary: "Reload the expectations of the stub files directory", Status: http.StatusOK, Response: idsResponse{}},
+	{Path: "/expectations/replay-check", Method: http.MethodPost, Summary: "Report the recorded calls an expectation would have matched", Request: runtime.GRPCCallExpectation{}, Status: http.StatusOK, Response: runtime.ReplayCheckResult{}},
+	{Path: "/verifications", Method: http.MethodGet, Summary: "List the recorded calls", Query: callFilterParams, Status: http.StatusOK, Response: []runtime.RecordedGRPCCall{}},
+	{Path: "/verifications", Method: http.MethodDelete, Summary: "Clear the recorded calls and match counts, keeping the expectations", Status: http.StatusOK, Response: messageResponse{}},
+	{Path: "/verifications/stream", Method: http.MethodGet, Summary: "Stream the calls as they are answered, as server-sent events", Query: callFilterParams, Status: http.StatusOK, Response: runtime.RecordedGRPCCall{}, ContentType: "text/event-stream"},
+	{Path: "/verifications/counts", Method: http.MethodGet, Summary: "Count the calls matched by each expectation, keyed by method#index", Status: http.StatusOK, Response: map[string]int{}},
+	{Path: "/verifications/satisfied", Method: http.MethodGet, Summary: "Tell whether each expectation's times are satisfied, keyed by method#index", Status: http.StatusOK, Response: map[string]bool{}},
+	{Path: "/verifications/connections", Method: http.MethodGet, Summary: "List the connection events", Status: http.StatusOK, Response: []runtime.ConnectionEvent{}},
+	{Path: "/verifications/duplicates", Method: http.MethodGet, Summary: "List the repeated idempotent requests", Status: http.StatusOK, Response: []runtime.DuplicateRequest{}},
+	{Path: "/verifications/slow-calls", Method: http.MethodGet, Summary: "Report the calls over the latency budget", Status: http.StatusOK, Response: runtime.SlowCallReport{}},
+	{Path: "/unmatched", Method: http.MethodGet, Summary: "List the calls no expectation matched, with the near misses", Status: http.StatusOK, Response: []runtime.UnmatchedGRPCCall{}},
+	{Path: "/sampling", Method: http.MethodGet, Summary: "Get the recording sampling and its usage", Status: http.StatusOK, Response: runtime.SamplingUsage{}},
+	{Path: "/sampling", Method: http.MethodPut, Summary: "Set the recording sampling", Request: runtime.Sampling{}, Status: http.StatusOK, Response: runtime.SamplingUsage{}},
+	{Path: "/reflection", Method: http.MethodGet, Summary: "Get the methods advertised by server reflection", Status: http.StatusOK, Response: runtime.Reflection{}},
+	{Path: "/reflection", Method: http.MethodPut, Summary: "Set the methods advertised by server reflection", Request: runtime.Reflection{}, Status: http.StatusOK, Response: runtime.Reflection{}},
+	{Path: "/reflection", Method: http.MethodDelete, Summary: "Advertise all methods", Status: http.StatusOK, Response: messageResponse{}},
+	{Path: "/scenarios", Method: http.MethodGet, Summary: "List the scenario states by name", Status: http.StatusOK, Response: map[string]runtime.ScenarioState{}},
+	{Path: "/scenarios", Method: http.MethodDelete, Summary: "Reset all scenarios", Status: http.StatusOK, Response: messageResponse{}},
+	{Path: "/scenarios/{name}", Method: http.MethodGet, Summary: "Get a scenario state", Status: http.StatusOK, Response: runtime.ScenarioState{}},
+	{Path: "/scenarios/{name}", Method: http.MethodPut, Summary: "Set a scenario state", Request: runtime.ScenarioState{}, Status: http.StatusOK, Response: runtime.ScenarioState{}},
+	{Path: "/scenarios/{name}", Method: http.MethodDelete, Summary: "Reset a scenario", Status: http.StatusOK, Response: runtime.ScenarioState{}},
+	{Path: "/vars", Method: http.MethodGet, Summary: "List the template variables", Status: http.StatusOK, Response: map[string]string{}},
+	{Path: "/vars", Method: http.MethodPut, Summary: "Set template variables", Request: map[string]string{}, Status: http.StatusOK, Response: map[string]string{}},
+	{Path: "/vars", Method: http.MethodDelete, Summary: "Clear the template variables", Status: http.StatusOK, Response: messageResponse{}},
+	{Path: "/fixtures", Method: http.MethodGet, Summary: "List the fixtures by name", Status: http.StatusOK, Response: map[string]json.RawMessage{}},
+	{Path: "/fixtures", Method: http.MethodPost, Summary: "Set a fixture", Request: runtime.Fixture{}, Status: http.StatusCreated, Response: messageResponse{}},
+	{Path: "/fixtures", Method: http.MethodDelete, Summary: "Clear the fixtures", Status: http.StatusOK, Response: messageResponse{}},
+	{Path: "/quotas", Method: http.MethodGet, Summary: "Get the quotas and their usage", Status: http.StatusOK, Response: runtime.QuotaUsage{}},
+	{Path: "/retention", Method: http.MethodGet, Summary: "Get the retention policy of recorded calls", Status: http.StatusOK, Response: runtime.RetentionPolicy{}},
+	{Path: "/retention", Method: http.MethodPut, Summary: "Set the retention policy of recorded calls", Request: runtime.RetentionPolicy{}, Status: http.StatusOK, Response: runtime.RetentionPolicy{}},
+	{Path: "/info", Method: http.MethodGet, Summary: "Describe the mock server", Status: http.StatusOK, Response: runtime.Info{}},
+}
+
+// openAPI returns the OpenAPI 3 document of the control API, whose schemas are
+// derived from the JSON encoding of the runtime types.
+func openAPI() map[string]interface{} {
+	g := &schemaGenerator{components: map[string]interface{}{}}
+	paths := map[string]map[string]interface{}{}
+	for _, op := range apiOperations {
+		operation := map[string]interface{}{
+			"summary":     op.Summary,
+			"operationId": operationID(op),
+			"responses": map[string]interface{}{
+				strconv.Itoa(op.Status): map[string]interface{}{
+					"description": http.StatusText(op.Status),
+					"content":     content(op.ContentType, g.schema(reflect.TypeOf(op.Response))),
+				},
+				"default": map[string]interface{}{
+					"description": "Error",
+					"content":     content("", g.schema(reflect.TypeOf(errorResponse{}))),
+				},
+			},
+		}
+		var params []interface{}
+		for _, seg := range strings.Split(op.Path, "/") {
+			if strings.HasPrefix(seg, "{") {
+				params = append(params, map[string]interface{}{
+					"name": strings.Trim(seg, "{}"), "in": "path", "required": true,
+					"schema": map[string]interface{}{"type": "string"},
+				})
+			}
+		}
+		names := make([]string, 0, len(op.Query))
+		for name := range op.Query {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			params = append(params, map[string]interface{}{
+				"name": name, "in": "query", "description": op.Query[name],
+				"schema": map[string]interface{}{"type": "string"},
+			})
+		}
+		if params != nil {
+			operation["parameters"] = params
+		}
+		if op.Request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  content("", g.schema(reflect.TypeOf(op.Request))),
+			}
+		}
+		if paths[op.Path] == nil {
+			paths[op.Path] = map[string]interface{}{}
+		}
+		paths[op.Path][strings.ToLower(op.Method)] = operation
+	}
+	version, _ := runtime.BuildVersion()
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "grpcmock control API",
+			"description": "Registers expectations on the gRPC mock server and verifies the calls it received.",
+			"version":     version,
+		},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": g.components},
+	}
+}
+
+// handleOpenAPI serves the OpenAPI document of the control API.
+func handleOpenAPI(w http.ResponseWriter, r *http.Request, doc map[string]interface{}) {
+	if r.Method != http.MethodGet {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeJSONResponse(w, http.StatusOK, doc)
+}
+
+// operationID names an operation after its method and path, e.g. getExpectationsById.
+func operationID(op apiOperation) string {
+	id := strings.ToLower(op.Method)
+	for _, seg := range strings.FieldsFunc(op.Path, func(r rune) bool { return r == '/' || r == '-' || r == '.' }) {
+		if strings.HasPrefix(seg, "{") {
+			id += "By"
+			seg = strings.Trim(seg, "{}")
+		}
+		id += strings.ToUpper(seg[:1]) + seg[1:]
+	}
+	return id
+}
+
+// content wraps a schema as a media type, application/json if contentType is empty.
+func content(contentType string, schema map[string]interface{}) map[string]interface{} {
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	return map[string]interface{}{contentType: map[string]interface{}{"schema": schema}}
+}
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	codeType        = reflect.TypeOf(codes.OK)
+	rawMessageType  = reflect.TypeOf(json.RawMessage{})
+	statusCodeNames = func() []interface{} {
+		names := make([]interface{}, 0, 17)
+		for c := codes.OK; c <= codes.Unauthenticated; c++ {
+			names = append(names, runtime.StatusCodeName(c))
+		}
+		return names
+	}()
+)
+
+// schemaGenerator derives schemas from the JSON encoding of Go types, collecting
+// the named struct types as components referenced by name.
+type schemaGenerator struct {
+	components map[string]interface{}
+}
+
+// schema returns the schema of t.
+func (g *schemaGenerator) schema(t reflect.Type) map[string]interface{} {
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case codeType:
+		return map[string]interface{}{
+			"description": "Canonical status code name, or number",
+			"oneOf": []interface{}{
+				map[string]interface{}{"type": "string", "enum": statusCodeNames},
+				map[string]interface{}{"type": "integer"},
+			},
+		}
+	case rawMessageType:
+		return map[string]interface{}{"description": "Any JSON value"}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.schema(t.Elem())
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+		if _, ok := g.components[name]; !ok {
+			g.components[name] = nil // Placeholder for recursive types
+			g.components[name] = g.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]interface{}{"description": "Any JSON value"}
+}
+
+// object returns the object schema of struct t.
+func (g *schemaGenerator) object(t reflect.Type) map[string]interface{} {
+	props := map[string]interface{}{}
+	g.properties(t, props)
+	return map[string]interface{}{"type": "object", "properties": props}
+}
+
+// properties adds the properties of struct t to props, inlining embedded structs
+// as encoding/json does.
+func (g *schemaGenerator) properties(t reflect.Type, props map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			g.properties(f.Type, props)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = g.schema(f.Type)
+	}
+}
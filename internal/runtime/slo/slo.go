@@ -0,0 +1,123 @@
+package slo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+	"github.com/rbroggi/grpcmock/internal/runtime/latency"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Objective is the production behavior of a method as described by its SLO.
+type Objective struct {
+	P50Ms     float64    `json:"p50Ms"`               // Median latency
+	P99Ms     float64    `json:"p99Ms"`               // 99th percentile latency
+	ErrorRate float64    `json:"errorRate,omitempty"` // Fraction of failing calls, e.g. 0.001 for a 99.9% availability SLO
+	ErrorCode codes.Code `json:"errorCode,omitempty"` // Code of injected errors, number or name; default UNAVAILABLE
+}
+
+// Config holds the objectives of the mocked methods.
+type Config struct {
+	Default *Objective           `json:"default,omitempty"` // Applied to methods without their own objective
+	Methods map[string]Objective `json:"methods,omitempty"` // Keyed by full method name, e.g. "/pkg.Svc/Method"
+}
+
+// Load reads a JSON SLO config file.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SLO config: %w", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse SLO config: %w", err)
+	}
+	for method, o := range cfg.Methods {
+		if err := o.validate(); err != nil {
+			return nil, fmt.Errorf("invalid objective for %s: %w", method, err)
+		}
+	}
+	if cfg.Default != nil {
+		if err := cfg.Default.validate(); err != nil {
+			return nil, fmt.Errorf("invalid default objective: %w", err)
+		}
+	}
+	return &cfg, nil
+}
+
+func (o Objective) validate() error {
+	if o.P50Ms < 0 || o.P99Ms < 0 {
+		return fmt.Errorf("latencies must not be negative")
+	}
+	if o.ErrorRate < 0 || o.ErrorRate > 1 {
+		return fmt.Errorf("errorRate must be between 0 and 1")
+	}
+	return nil
+}
+
+// clockSource provides the clock used to wait for injected delays.
+type clockSource interface {
+	Clock() runtime.Clock
+}
+
+// Policy injects the latency and errors derived from a Config into calls.
+type Policy struct {
+	config *Config
+	clocks clockSource
+	mu     sync.Mutex
+	rand   *rand.Rand
+}
+
+// NewPolicy creates a Policy for the given config.
+func NewPolicy(config *Config, clocks clockSource) *Policy {
+	return &Policy{config: config, clocks: clocks, rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// Apply delays the call by a latency drawn from the method's log-normal
+// distribution (fitted to its p50 and p99) and then fails it with the
+// method's error rate. A nil Policy does nothing.
+func (p *Policy) Apply(ctx context.Context, fullMethodName string) error {
+	if p == nil {
+		return nil
+	}
+	o, ok := p.config.Methods[fullMethodName]
+	if !ok {
+		if p.config.Default == nil {
+			return nil
+		}
+		o = *p.config.Default
+	}
+
+	dist := latency.FromPercentiles(msToDuration(o.P50Ms), msToDuration(o.P99Ms))
+	p.mu.Lock()
+	delay := dist.Sample(p.rand)
+	fail := p.rand.Float64() < o.ErrorRate
+	p.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-p.clocks.Clock().After(delay):
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+	if fail {
+		code := o.ErrorCode
+		if code == codes.OK {
+			code = codes.Unavailable
+		}
+		return status.Errorf(code, "grpcmock: injected error for %s (SLO error rate %v)", fullMethodName, o.ErrorRate)
+	}
+	return nil
+}
+
+func msToDuration(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
@@ -0,0 +1,18 @@
+package runtime
+
+// SlowCall records a call whose handling took longer than the slow call budget.
+type SlowCall struct {
+	CallID         uint64 `json:"callId"` // ID of the recorded call, 0 if it was not recorded
+	FullMethodName string `json:"fullMethodName"`
+	LatencyMs      int64  `json:"latencyMs"`
+	BudgetMs       int64  `json:"budgetMs"`  // Slow call budget it exceeded
+	Timestamp      int64  `json:"timestamp"` // Unix nano timestamp
+}
+
+// SlowCallReport lists the calls that exceeded the slow call budget.
+type SlowCallReport struct {
+	BudgetMs int64          `json:"budgetMs"`           // 0 if slow calls are not tracked
+	Count    int            `json:"count"`              // Slow calls since startup, including cleared and dropped ones
+	ByMethod map[string]int `json:"byMethod,omitempty"` // Count by full method name
+	Calls    []SlowCall     `json:"calls"`              // The latest slow calls, at most 4096
+}
@@ -0,0 +1,182 @@
+package runtime
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	_ "google.golang.org/genproto/googleapis/rpc/errdetails" // Registers the standard error detail types
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// statusCodeNames are the canonical names of the gRPC status codes, indexed by code.
+// codes.Code accepts them, quoted, as well as numbers when unmarshalling JSON.
+var statusCodeNames = [...]string{
+	"OK", "CANCELLED", "UNKNOWN", "INVALID_ARGUMENT", "DEADLINE_EXCEEDED", "NOT_FOUND",
+	"ALREADY_EXISTS", "PERMISSION_DENIED", "RESOURCE_EXHAUSTED", "FAILED_PRECONDITION",
+	"ABORTED", "OUT_OF_RANGE", "UNIMPLEMENTED", "INTERNAL", "UNAVAILABLE", "DATA_LOSS",
+	"UNAUTHENTICATED",
+}
+
+// StatusCodeName returns the canonical name of a status code, e.g. "NOT_FOUND",
+// or its number for codes outside the canonical set.
+func StatusCodeName(c codes.Code) string {
+	if int(c) < len(statusCodeNames) {
+		return statusCodeNames[c]
+	}
+	return strconv.FormatUint(uint64(c), 10)
+}
+
+// ValidateStatusCode reports an error for codes outside the canonical set.
+func ValidateStatusCode(c codes.Code) error {
+	if int(c) >= len(statusCodeNames) {
+		return fmt.Errorf("invalid status code %d", c)
+	}
+	return nil
+}
+
+// statusCodeJSON renders a status code by name, or by number outside the canonical set.
+func statusCodeJSON(c codes.Code) json.RawMessage {
+	if int(c) < len(statusCodeNames) {
+		return json.RawMessage(strconv.Quote(statusCodeNames[c]))
+	}
+	return json.RawMessage(strconv.FormatUint(uint64(c), 10))
+}
+
+// MarshalJSON renders Code by name, so exported expectations round-trip with the
+// names used in fixtures; codes are accepted by name or number.
+func (e RPCError) MarshalJSON() ([]byte, error) {
+	type rpcError RPCError
+	return json.Marshal(struct {
+		Code json.RawMessage `json:"code"`
+		rpcError
+	}{statusCodeJSON(e.Code), rpcError(e)})
+}
+
+// Status converts the error to a gRPC status carrying its Details, or those of
+// DetailsBin. Detail types must be registered in the mock server, as are those
+// of google/rpc/error_details.proto; those of DetailsBin need not be.
+func (e *RPCError) Status() (*status.Status, error) {
+	if e.DetailsBin != "" {
+		return e.binStatus()
+	}
+	st := &spb.Status{Code: int32(e.Code), Message: e.Message}
+	for i, d := range e.Details {
+		detail := new(anypb.Any)
+		if err := protojson.Unmarshal(d, detail); err != nil {
+			return nil, fmt.Errorf("details[%d]: %w", i, err)
+		}
+		if !strings.Contains(detail.TypeUrl, "/") {
+			detail.TypeUrl = "type.googleapis.com/" + detail.TypeUrl
+		}
+		st.Details = append(st.Details, detail)
+	}
+	return status.FromProto(st), nil
+}
+
+// binStatus returns the status DetailsBin holds, so the grpc-status-details-bin
+// trailer sent is DetailsBin itself. The error's code and message only fill
+// those DetailsBin leaves unset.
+func (e *RPCError) binStatus() (*status.Status, error) {
+	st, err := e.decodeDetailsBin()
+	if err != nil {
+		return nil, err
+	}
+	if st.Code == 0 {
+		st.Code = int32(e.Code)
+	}
+	if st.Message == "" {
+		st.Message = e.Message
+	}
+	return status.FromProto(st), nil
+}
+
+// decodeDetailsBin decodes the google.rpc.Status of DetailsBin.
+func (e *RPCError) decodeDetailsBin() (*spb.Status, error) {
+	if len(e.Details) > 0 {
+		return nil, fmt.Errorf("details and detailsBin cannot both be set")
+	}
+	// Binary metadata is base64 encoded without padding on the wire; accept both forms.
+	b, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(e.DetailsBin, "="))
+	if err != nil {
+		return nil, fmt.Errorf("detailsBin: %w", err)
+	}
+	st := new(spb.Status)
+	if err := proto.Unmarshal(b, st); err != nil {
+		return nil, fmt.Errorf("detailsBin is not a google.rpc.Status: %w", err)
+	}
+	return st, nil
+}
+
+// validateDetailsBin rejects errors whose code or message disagree with those
+// of DetailsBin, which are the ones sent.
+func (e *RPCError) validateDetailsBin() error {
+	st, err := e.decodeDetailsBin()
+	if err != nil {
+		return err
+	}
+	if st.Code != 0 && codes.Code(st.Code) != e.Code {
+		return fmt.Errorf("code %s disagrees with the %s of detailsBin", StatusCodeName(e.Code), StatusCodeName(codes.Code(st.Code)))
+	}
+	if st.Message != "" && e.Message != "" && e.Message != st.Message {
+		return fmt.Errorf("message %q disagrees with the %q of detailsBin", e.Message, st.Message)
+	}
+	return nil
+}
+
+// Err returns the error as a gRPC status error. Details that cannot be
+// converted are logged and dropped.
+func (e *RPCError) Err() error {
+	st, err := e.Status()
+	if err != nil {
+		log.Printf("grpcmockruntime: dropping details of %s error: %v", StatusCodeName(e.Code), err)
+		return status.Error(e.Code, e.Message)
+	}
+	return st.Err()
+}
+
+// MarshalJSON renders StatusCode by name.
+func (r RecordedResponse) MarshalJSON() ([]byte, error) {
+	type recordedResponse RecordedResponse
+	return json.Marshal(struct {
+		recordedResponse
+		StatusCode json.RawMessage `json:"statusCode"`
+	}{recordedResponse(r), statusCodeJSON(r.StatusCode)})
+}
+
+// ValidateStatusCodes checks the codes of the response's errors.
+func (r *MockResponse) ValidateStatusCodes() error {
+	errs := map[string]*RPCError{"error": r.Error}
+	if r.Pagination != nil {
+		errs["pagination.expiredTokenError"] = r.Pagination.ExpiredTokenError
+	}
+	if r.Operation != nil {
+		errs["operation.error"] = r.Operation.Error
+	}
+	for field, rpcErr := range errs {
+		if rpcErr == nil {
+			continue
+		}
+		if err := ValidateStatusCode(rpcErr.Code); err != nil {
+			return fmt.Errorf("%s: %w", field, err)
+		}
+		if _, err := rpcErr.Status(); err != nil {
+			return fmt.Errorf("%s: %w", field, err)
+		}
+		if rpcErr.DetailsBin == "" {
+			continue
+		}
+		if err := rpcErr.validateDetailsBin(); err != nil {
+			return fmt.Errorf("%s: %w", field, err)
+		}
+	}
+	return nil
+}
@@ -1,9 +1,15 @@
 package storage
 
 import (
+	"container/list"
 	"encoding/json"
 	"fmt"
 	"log"
+	"math/rand/v2"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -23,8 +29,45 @@ var (
 type Store struct {
 	expectationsStore map[string][]runtime.GRPCCallExpectation
 	recordedCalls     []runtime.RecordedGRPCCall
-	matchCounts       map[string]int // key: fullMethodName#index
+	callsByTrace      map[string][]int // Indexes in recordedCalls by trace ID
+	answeredByMethod  map[string][]int // Indexes in recordedCalls of answered calls by method
+	answeredByExp     map[string][]int // Indexes in recordedCalls of answered calls by expectation ID
+	unmatchedCalls    []runtime.UnmatchedGRPCCall
+	connectionEvents  []runtime.ConnectionEvent
+	requestKeys       map[string]*list.Element // Of requestKeyOrder, by method and idempotency key
+	requestKeyOrder   *list.List               // *runtime.DuplicateRequest, most recently seen first
+	matchCounts       map[string]int           // By expectation ID
+	expKeys           map[string]string        // Key of each expectation, by ID
+	operations        map[string]runtime.OperationState
+	fixtures          map[string]json.RawMessage
+	clock             runtime.Clock
+	quotas            runtime.Quotas
+	usage             map[string]*runtime.NamespaceUsage // By namespace, Expectations aside
+	sampling          runtime.Sampling
+	skippedCalls      int // Calls not recorded because of sampling
+	retention         runtime.RetentionPolicy
+	nextExpiry        int64 // Unix nano time the oldest call under a TTL expires, 0 if none
+	slowCallBudget    time.Duration
+	slowCalls         []runtime.SlowCall
+	slowCallCount     int            // Slow calls since startup
+	slowCallsByMethod map[string]int // Slow calls since startup by method
+	lastCallID        uint64
+	lastExpID         uint64 // Sequence number of the last generated expectation ID
+	subscribers       map[chan runtime.ExpectationEvent]struct{}
+	validators        []func(*runtime.GRPCCallExpectation) error
 	mu                sync.RWMutex
+	// Observers are called without holding mu, so they may use the store.
+	observersMu   sync.RWMutex
+	expObservers  []func(runtime.GRPCCallExpectation)
+	callObservers []func(runtime.RecordedGRPCCall)
+	unmatchedObs  []func(runtime.UnmatchedGRPCCall)
+	slowCallObs   []func(runtime.SlowCall)
+	reflection    runtime.Reflection // Methods advertised by gRPC reflection
+	scenarios     map[string]string  // Current state by scenario name, if not ScenarioStarted
+	vars          map[string]string  // Variables set by responses, see MockResponse.SetVars
+	execCommands  []string           // Executables responses may run, see MockResponse.Exec
+	// callSubscribers receive every recorded call once answered, see SubscribeCalls.
+	callSubscribers map[chan runtime.RecordedGRPCCall]struct{}
 }
 
 // New creates a new Store instance.
@@ -32,29 +75,791 @@ func New() *Store {
 	return &Store{
 		expectationsStore: make(map[string][]runtime.GRPCCallExpectation),
 		recordedCalls:     make([]runtime.RecordedGRPCCall, 0),
+		callsByTrace:      make(map[string][]int),
+		answeredByMethod:  make(map[string][]int),
+		answeredByExp:     make(map[string][]int),
+		unmatchedCalls:    make([]runtime.UnmatchedGRPCCall, 0),
+		connectionEvents:  make([]runtime.ConnectionEvent, 0),
+		slowCalls:         make([]runtime.SlowCall, 0),
+		slowCallsByMethod: make(map[string]int),
+		requestKeys:       make(map[string]*list.Element),
+		requestKeyOrder:   list.New(),
 		matchCounts:       make(map[string]int),
+		expKeys:           make(map[string]string),
+		operations:        make(map[string]runtime.OperationState),
+		fixtures:          make(map[string]json.RawMessage),
+		clock:             runtime.SystemClock{},
+		usage:             make(map[string]*runtime.NamespaceUsage),
+		sampling:          runtime.DefaultSampling,
+		subscribers:       make(map[chan runtime.ExpectationEvent]struct{}),
+		scenarios:         make(map[string]string),
+		vars:              make(map[string]string),
+		callSubscribers:   make(map[chan runtime.RecordedGRPCCall]struct{}),
 	}
 }
 
-// AddExpectation adds a new gRPC call expectation.
-func (s *Store) AddExpectation(exp runtime.GRPCCallExpectation) error {
+// SetClock replaces the clock used by the runtime, e.g. with a fake clock in tests.
+func (s *Store) SetClock(clock runtime.Clock) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if exp.FullMethodName == "" {
+	s.clock = clock
+}
+
+// SetQuotas sets the limits on stored expectations and recorded calls.
+func (s *Store) SetQuotas(quotas runtime.Quotas) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.quotas = quotas
+}
+
+// QuotaUsage returns the quotas along with the current usage and rejection
+// counts, in total and for each namespace.
+func (s *Store) QuotaUsage() runtime.QuotaUsage {
+	s.expireCalls()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	usage := runtime.QuotaUsage{Quotas: s.quotas, Namespaces: make(map[string]runtime.NamespaceUsage)}
+	for ns, u := range s.usage {
+		usage.Namespaces[ns] = *u
+	}
+	for ns, n := range s.expectationCounts() {
+		u := usage.Namespaces[ns]
+		u.Expectations = n
+		usage.Namespaces[ns] = u
+	}
+	for _, u := range usage.Namespaces {
+		usage.Expectations += u.Expectations
+		usage.RecordedCalls += u.RecordedCalls
+		usage.UnmatchedCalls += u.UnmatchedCalls
+		usage.RejectedExpectations += u.RejectedExpectations
+		usage.DroppedCalls += u.DroppedCalls
+		usage.DiscardedCalls += u.DiscardedCalls
+	}
+	return usage
+}
+
+// SetRetention sets how many recorded and unmatched calls each namespace
+// keeps, and for how long, discarding those the policy no longer keeps.
+func (s *Store) SetRetention(policy runtime.RetentionPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retention = policy
+	s.pruneCalls(s.clock.Now().UnixNano())
+	return nil
+}
+
+// Retention returns the retention policy.
+func (s *Store) Retention() runtime.RetentionPolicy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.retention
+}
+
+// expireCalls discards the calls whose retention expired, before reading them.
+func (s *Store) expireCalls() {
+	s.mu.RLock()
+	due := s.nextExpiry != 0 && s.clock.Now().UnixNano() >= s.nextExpiry
+	s.mu.RUnlock()
+	if due {
+		s.mu.Lock()
+		s.applyRetention()
+		s.mu.Unlock()
+	}
+}
+
+// applyRetention discards the calls the retention policy no longer keeps,
+// only scanning them once a namespace holds more calls than it keeps or a call
+// expired. The caller must hold the lock.
+func (s *Store) applyRetention() {
+	if s.retention.Unlimited() {
+		return
+	}
+	now := s.clock.Now().UnixNano()
+	due := s.nextExpiry != 0 && now >= s.nextExpiry
+	for ns, u := range s.usage {
+		if limit := s.retention.For(ns).MaxCalls; limit > 0 && (u.RecordedCalls > limit || u.UnmatchedCalls > limit) {
+			due = true
+		}
+	}
+	if due {
+		s.pruneCalls(now)
+	}
+}
+
+// pruneCalls discards the recorded and unmatched calls the retention policy
+// no longer keeps, counting them in the usage of their namespaces, and
+// rebuilds the indexes of the recorded calls. The caller must hold the lock.
+func (s *Store) pruneCalls(now int64) {
+	var recordedExpiry, unmatchedExpiry int64
+	var discarded map[string]int
+	s.recordedCalls, discarded, recordedExpiry = retain(s.recordedCalls, s.retention, now, func(c *runtime.RecordedGRPCCall) (string, int64) {
+		return runtime.Namespace(c.Headers), c.Timestamp
+	})
+	for ns, n := range discarded {
+		u := s.nsUsage(ns)
+		u.RecordedCalls -= n
+		u.DiscardedCalls += n
+	}
+	s.unmatchedCalls, discarded, unmatchedExpiry = retain(s.unmatchedCalls, s.retention, now, func(c *runtime.UnmatchedGRPCCall) (string, int64) {
+		return runtime.Namespace(c.Headers), c.Timestamp
+	})
+	for ns, n := range discarded {
+		u := s.nsUsage(ns)
+		u.UnmatchedCalls -= n
+		u.DiscardedCalls += n
+	}
+	s.nextExpiry = recordedExpiry
+	if unmatchedExpiry != 0 && (s.nextExpiry == 0 || unmatchedExpiry < s.nextExpiry) {
+		s.nextExpiry = unmatchedExpiry
+	}
+
+	s.callsByTrace = make(map[string][]int)
+	s.answeredByMethod = make(map[string][]int)
+	s.answeredByExp = make(map[string][]int)
+	for i, call := range s.recordedCalls {
+		if call.TraceID != "" {
+			s.callsByTrace[call.TraceID] = append(s.callsByTrace[call.TraceID], i)
+		}
+		if call.Response != nil {
+			s.answeredByMethod[call.FullMethodName] = append(s.answeredByMethod[call.FullMethodName], i)
+			if call.Response.ExpectationID != "" {
+				s.answeredByExp[call.Response.ExpectationID] = append(s.answeredByExp[call.Response.ExpectationID], i)
+			}
+		}
+	}
+}
+
+// retain returns the calls, oldest first, that a retention policy keeps at
+// the given time, the number discarded by namespace and the time the first
+// kept call expires, or 0 if none does. describe returns the namespace and
+// timestamp of a call.
+func retain[C any](calls []C, policy runtime.RetentionPolicy, now int64, describe func(*C) (string, int64)) ([]C, map[string]int, int64) {
+	counts := make(map[string]int)
+	for i := range calls {
+		ns, _ := describe(&calls[i])
+		counts[ns]++
+	}
+	kept := make([]C, 0, len(calls))
+	discarded := make(map[string]int)
+	var expiry int64
+	for i := range calls {
+		ns, ts := describe(&calls[i])
+		r := policy.For(ns)
+		counts[ns]-- // Now the number of newer calls of the namespace
+		ttl := r.TTL().Nanoseconds()
+		if (r.MaxCalls > 0 && counts[ns] >= r.MaxCalls) || (ttl > 0 && ts+ttl <= now) {
+			discarded[ns]++
+			continue
+		}
+		if ttl > 0 && (expiry == 0 || ts+ttl < expiry) {
+			expiry = ts + ttl
+		}
+		kept = append(kept, calls[i])
+	}
+	return kept, discarded, expiry
+}
+
+// SetSlowCallBudget sets the handling time above which calls are reported as slow; zero disables it.
+func (s *Store) SetSlowCallBudget(budget time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.slowCallBudget = budget
+}
+
+// maxSlowCalls bounds the slow calls kept for GET /verifications/slow-calls;
+// the oldest are dropped when it is reached, the counts keep them.
+const maxSlowCalls = 4096
+
+// CheckCallLatency logs a warning, counts and records a slow call and notifies
+// the slow call observers if a call's handling took longer than the slow call budget.
+func (s *Store) CheckCallLatency(callID uint64, fullMethodName string, latency time.Duration) {
+	s.mu.Lock()
+	if s.slowCallBudget <= 0 || latency <= s.slowCallBudget {
+		s.mu.Unlock()
+		return
+	}
+	log.Printf("grpcmockruntime: WARNING: call %d to %s took %v, over the slow call budget of %v", callID, fullMethodName, latency, s.slowCallBudget)
+	call := runtime.SlowCall{
+		CallID:         callID,
+		FullMethodName: fullMethodName,
+		LatencyMs:      latency.Milliseconds(),
+		BudgetMs:       s.slowCallBudget.Milliseconds(),
+		Timestamp:      s.clock.Now().UnixNano(),
+	}
+	s.slowCallCount++
+	s.slowCallsByMethod[fullMethodName]++
+	if len(s.slowCalls) >= maxSlowCalls {
+		s.slowCalls = append(s.slowCalls[:0], s.slowCalls[len(s.slowCalls)-maxSlowCalls+1:]...)
+	}
+	s.slowCalls = append(s.slowCalls, call)
+	s.mu.Unlock()
+
+	s.observersMu.RLock()
+	defer s.observersMu.RUnlock()
+	for _, observe := range s.slowCallObs {
+		observe(call)
+	}
+}
+
+// OnSlowCall registers a function called with every call that exceeded the slow call budget.
+func (s *Store) OnSlowCall(fn func(runtime.SlowCall)) {
+	s.observersMu.Lock()
+	defer s.observersMu.Unlock()
+	s.slowCallObs = append(s.slowCallObs, fn)
+}
+
+// GetSlowCalls returns the slow call budget, the slow call counts and the
+// latest calls that exceeded it.
+func (s *Store) GetSlowCalls() runtime.SlowCallReport {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	byMethod := make(map[string]int, len(s.slowCallsByMethod))
+	for method, n := range s.slowCallsByMethod {
+		byMethod[method] = n
+	}
+	return runtime.SlowCallReport{
+		BudgetMs: s.slowCallBudget.Milliseconds(),
+		Count:    s.slowCallCount,
+		ByMethod: byMethod,
+		Calls:    append([]runtime.SlowCall(nil), s.slowCalls...),
+	}
+}
+
+// SetSampling sets the share of calls recorded. Calls cannot be skipped while
+// expectations read the recorded calls, see GRPCCallExpectation.UsesRecordedCalls.
+func (s *Store) SetSampling(sampling runtime.Sampling) error {
+	if err := sampling.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !sampling.RecordsAll() {
+		for _, exps := range s.expectationsStore {
+			for _, exp := range exps {
+				if exp.UsesRecordedCalls() {
+					return fmt.Errorf("expectation %s reads the recorded calls, through after or lastCall, which sampling would skip", exp.ID)
+				}
+			}
+		}
+	}
+	s.sampling = sampling
+	return nil
+}
+
+// SamplingUsage returns the sampling rates and the number of calls they skipped.
+func (s *Store) SamplingUsage() runtime.SamplingUsage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return runtime.SamplingUsage{Sampling: s.sampling, SkippedCalls: s.skippedCalls}
+}
+
+// SetReflection sets the methods advertised by the gRPC reflection service.
+func (s *Store) SetReflection(reflection runtime.Reflection) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reflection = reflection
+}
+
+// GetReflection returns the methods advertised by the gRPC reflection service.
+func (s *Store) GetReflection() runtime.Reflection {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.reflection
+}
+
+// ScenarioState returns the current state of a scenario.
+func (s *Store) ScenarioState(name string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if state, ok := s.scenarios[name]; ok {
+		return state
+	}
+	return runtime.ScenarioStarted
+}
+
+// SetScenarioState moves a scenario to a state.
+func (s *Store) SetScenarioState(name, state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scenarios[name] = state
+	log.Printf("grpcmockruntime: Scenario %s moved to state %s", name, state)
+}
+
+// TransitionScenario moves a scenario to state to if it is in state from, or in
+// any state if from is empty, and reports whether it was. An empty to leaves the
+// scenario in its state.
+func (s *Store) TransitionScenario(name, from, to string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.transitionScenario(name, from, to)
+}
+
+// transitionScenario is TransitionScenario; the caller must hold the lock.
+func (s *Store) transitionScenario(name, from, to string) bool {
+	state, ok := s.scenarios[name]
+	if !ok {
+		state = runtime.ScenarioStarted
+	}
+	if from != "" && from != state {
+		return false
+	}
+	if to != "" {
+		s.scenarios[name] = to
+		log.Printf("grpcmockruntime: Scenario %s moved to state %s", name, to)
+	}
+	return true
+}
+
+// GetScenarios returns the current state of the scenarios named by the
+// expectations, or moved to another state since they were last reset.
+func (s *Store) GetScenarios() map[string]runtime.ScenarioState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	scenarios := make(map[string]runtime.ScenarioState)
+	for _, exps := range s.expectationsStore {
+		for _, exp := range exps {
+			if exp.Scenario != "" {
+				scenarios[exp.Scenario] = runtime.ScenarioState{State: runtime.ScenarioStarted}
+			}
+		}
+	}
+	for name, state := range s.scenarios {
+		scenarios[name] = runtime.ScenarioState{State: state}
+	}
+	return scenarios
+}
+
+// ResetScenarios moves every scenario back to ScenarioStarted.
+func (s *Store) ResetScenarios() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scenarios = make(map[string]string)
+}
+
+// SetVars sets variables, keeping the others.
+func (s *Store) SetVars(vars map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, v := range vars {
+		s.vars[k] = v
+	}
+}
+
+// GetVars returns a copy of the variables.
+func (s *Store) GetVars() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	vars := make(map[string]string, len(s.vars))
+	for k, v := range s.vars {
+		vars[k] = v
+	}
+	return vars
+}
+
+// ClearVars removes all variables.
+func (s *Store) ClearVars() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.vars = make(map[string]string)
+}
+
+// SetExecCommands sets the executables that responses may run. Expectations
+// registered before are not checked again.
+func (s *Store) SetExecCommands(commands []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.execCommands = append([]string(nil), commands...)
+}
+
+// GetExecCommands returns the executables that responses may run.
+func (s *Store) GetExecCommands() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]string(nil), s.execCommands...)
+}
+
+// expectationCounts returns the number of expectations of each namespace.
+// The caller must hold the lock.
+func (s *Store) expectationCounts() map[string]int {
+	counts := make(map[string]int)
+	for _, exps := range s.expectationsStore {
+		for _, exp := range exps {
+			counts[exp.QuotaNamespace()]++
+		}
+	}
+	return counts
+}
+
+// nsUsage returns the usage of a namespace; the caller must hold the lock.
+func (s *Store) nsUsage(ns string) *runtime.NamespaceUsage {
+	u, ok := s.usage[ns]
+	if !ok {
+		u = &runtime.NamespaceUsage{}
+		s.usage[ns] = u
+	}
+	return u
+}
+
+// expectationQuotaError counts expectations of a namespace refused by the
+// quotas and returns the error reporting it. The caller must hold the lock.
+func (s *Store) expectationQuotaError(ns string, rejected int) error {
+	s.nsUsage(ns).RejectedExpectations += rejected
+	return fmt.Errorf("%w: at most %d expectations in namespace %q", runtime.ErrQuotaExceeded, s.quotas.MaxExpectations, ns)
+}
+
+// callQuotaExceeded reports whether a namespace already holds n calls of a
+// list, counting the dropped call if so. The caller must hold the lock.
+func (s *Store) callQuotaExceeded(u *runtime.NamespaceUsage, n int, ns, fullMethodName string) bool {
+	if s.quotas.MaxRecordedCalls <= 0 || n < s.quotas.MaxRecordedCalls {
+		return false
+	}
+	u.DroppedCalls++
+	log.Printf("grpcmockruntime: recorded calls quota (%d) of namespace %s reached, dropping call to %s", s.quotas.MaxRecordedCalls, ns, fullMethodName)
+	return true
+}
+
+// Clock returns the clock used by the runtime.
+func (s *Store) Clock() runtime.Clock {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.clock
+}
+
+// AddExpectation adds a new gRPC call expectation and notifies the observers.
+// Expectations without an ID are given one; the expectation's ID is returned.
+func (s *Store) AddExpectation(exp runtime.GRPCCallExpectation) (string, error) {
+	exp, err := s.addExpectation(exp)
+	if err != nil {
+		return "", err
+	}
+	s.notifyAdded(exp)
+	return exp.ID, nil
+}
+
+// notifyAdded calls the expectation observers; the caller must not hold the lock.
+func (s *Store) notifyAdded(exp runtime.GRPCCallExpectation) {
+	s.observersMu.RLock()
+	defer s.observersMu.RUnlock()
+	for _, observe := range s.expObservers {
+		observe(exp)
+	}
+}
+
+func (s *Store) addExpectation(exp runtime.GRPCCallExpectation) (runtime.GRPCCallExpectation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.validateExpectation(&exp, nil); err != nil {
+		return exp, err
+	}
+	if ns := exp.QuotaNamespace(); s.quotas.MaxExpectations > 0 && s.expectationCounts()[ns] >= s.quotas.MaxExpectations {
+		return exp, s.expectationQuotaError(ns, 1)
+	}
+	if exp.ID == "" {
+		exp.ID = s.newExpectationID()
+	}
+	key := s.insert(exp)
+	s.publish(runtime.ExpectationEvent{Type: runtime.ExpectationEventAdded, Expectation: &exp})
+	log.Printf("grpcmockruntime: Added expectation %s for %s", exp.ID, key)
+	return exp, nil
+}
+
+// newExpectationID returns an unused expectation ID; the caller must hold the lock.
+func (s *Store) newExpectationID() string {
+	for {
+		s.lastExpID++
+		id := fmt.Sprintf("exp-%d", s.lastExpID)
+		if _, _, taken := s.findByID(id); !taken {
+			return id
+		}
+	}
+}
+
+// validateExpectation checks an expectation before it is stored, possibly
+// replacing the expectations whose IDs are in replacing, and lets the validators
+// attach their derived data to it. The caller must hold the lock.
+func (s *Store) validateExpectation(exp *runtime.GRPCCallExpectation, replacing map[string]bool) error {
+	if exp.FullMethodName == "" && exp.FullMethodNameRegex == "" {
 		return fmt.Errorf("fullMethodName is required in expectation")
 	}
-	if exp.Response == nil {
+	if exp.FullMethodNameRegex != "" {
+		if exp.FullMethodName != "" {
+			return fmt.Errorf("fullMethodName and fullMethodNameRegex are mutually exclusive")
+		}
+		if _, err := regexp.Compile(exp.FullMethodNameRegex); err != nil {
+			return fmt.Errorf("invalid fullMethodNameRegex: %w", err)
+		}
+	}
+	if exp.Response == nil && len(exp.Responses) == 0 {
 		return fmt.Errorf("response is required in expectation")
 	}
-	s.expectationsStore[exp.FullMethodName] = append(s.expectationsStore[exp.FullMethodName], exp)
-	log.Printf("grpcmockruntime: Added expectation for %s", exp.FullMethodName)
+	if err := exp.ValidateResponses(); err != nil {
+		return err
+	}
+	if strings.Contains(exp.FullMethodName, "*") && !runtime.IsServiceWildcard(exp.FullMethodName) {
+		return fmt.Errorf("fullMethodName may only use * for all methods of a service, e.g. /pkg.Service/*")
+	}
+	if err := exp.ValidateID(); err != nil {
+		return err
+	}
+	if err := exp.ValidateScenario(); err != nil {
+		return err
+	}
+	if err := exp.ValidateWebhooks(); err != nil {
+		return err
+	}
+	if err := exp.ValidateExec(s.execCommands); err != nil {
+		return err
+	}
+	if exp.Schedule != nil {
+		if err := exp.Schedule.Validate(); err != nil {
+			return fmt.Errorf("invalid schedule: %w", err)
+		}
+	}
+	if exp.Alert != nil {
+		if exp.ID == "" {
+			return fmt.Errorf("alert requires the expectation to have an id")
+		}
+		if exp.Alert.WebhookURL == "" || (exp.Alert.UnmatchedForMs <= 0 && exp.Alert.UnmatchedCalls <= 0) {
+			return fmt.Errorf("alert requires webhookUrl and unmatchedForMs or unmatchedCalls")
+		}
+	}
+	if exp.ID != "" && !replacing[exp.ID] {
+		if _, _, ok := s.findByID(exp.ID); ok {
+			return fmt.Errorf("an expectation with id %q already exists", exp.ID)
+		}
+	}
+	for _, alias := range exp.Aliases {
+		if alias.FullMethodName == "" || alias.FullMethodName == exp.FullMethodName {
+			return fmt.Errorf("alias fullMethodName must be set and differ from the expectation's")
+		}
+	}
+	if exp.ActiveWhen != nil && exp.ActiveWhen.ExpectationID == "" {
+		return fmt.Errorf("activeWhen.expectationId is required")
+	}
+	if exp.After != nil && exp.After.FullMethodName == "" && exp.After.ExpectationID == "" {
+		return fmt.Errorf("after.fullMethodName or after.expectationId is required")
+	}
+	for _, validate := range s.validators {
+		if err := validate(exp); err != nil {
+			return err
+		}
+	}
+	if !s.sampling.RecordsAll() && exp.UsesRecordedCalls() {
+		return fmt.Errorf("after and lastCall read the recorded calls, which sampling skips: they require recording every call")
+	}
+	return nil
+}
+
+// ImportExpectations adds expectations atomically: either all of them are
+// added, or, if any is invalid, none is and runtime.ImportErrors reports the
+// invalid ones. It returns the IDs of the expectations, generated if unset.
+func (s *Store) ImportExpectations(exps []runtime.GRPCCallExpectation) ([]string, error) {
+	return s.SwapExpectations(nil, exps)
+}
+
+// SwapExpectations atomically removes the expectations with the given IDs, if
+// they still exist, and adds exps as ImportExpectations does. If any of exps
+// is invalid, nothing changes. exps may reuse the IDs of removed expectations.
+func (s *Store) SwapExpectations(remove []string, exps []runtime.GRPCCallExpectation) ([]string, error) {
+	added, err := s.swapExpectations(remove, exps)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(added))
+	for i, exp := range added {
+		s.notifyAdded(exp)
+		ids[i] = exp.ID
+	}
+	return ids, nil
+}
+
+func (s *Store) swapExpectations(remove []string, exps []runtime.GRPCCallExpectation) ([]runtime.GRPCCallExpectation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := make(map[string]bool, len(remove))
+	for _, id := range remove {
+		if _, _, ok := s.findByID(id); ok {
+			removed[id] = true
+		}
+	}
+	var errs runtime.ImportErrors
+	ids := make(map[string]int, len(exps))
+	exps = slices.Clone(exps) // Validators may attach data to the expectations
+	for i := range exps {
+		exp := &exps[i]
+		err := s.validateExpectation(exp, removed)
+		if first, dup := ids[exp.ID]; err == nil && exp.ID != "" && dup {
+			err = fmt.Errorf("id %q is also used by expectation %d", exp.ID, first)
+		}
+		if err != nil {
+			errs = append(errs, runtime.ImportError{Index: i, ID: exp.ID, Error: err.Error()})
+		}
+		if _, dup := ids[exp.ID]; !dup {
+			ids[exp.ID] = i
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	if err := s.checkSwapQuotas(removed, exps); err != nil {
+		return nil, err
+	}
+	for id := range removed {
+		key, idx, _ := s.findByID(id)
+		exp := s.expectationsStore[key][idx]
+		s.removeAt(key, idx)
+		s.publish(runtime.ExpectationEvent{Type: runtime.ExpectationEventRemoved, Expectation: &exp})
+	}
+	added := make([]runtime.GRPCCallExpectation, len(exps))
+	for i, exp := range exps {
+		if exp.ID == "" {
+			exp.ID = s.newExpectationID()
+		}
+		s.insert(exp)
+		s.publish(runtime.ExpectationEvent{Type: runtime.ExpectationEventAdded, Expectation: &exp})
+		added[i] = exp
+	}
+	if len(removed) > 0 {
+		log.Printf("grpcmockruntime: Removed %d expectations", len(removed))
+	}
+	log.Printf("grpcmockruntime: Imported %d expectations", len(exps))
+	return added, nil
+}
+
+// checkSwapQuotas checks that removing the expectations with the given IDs and
+// adding exps keeps every namespace within the quotas. The caller must hold the lock.
+func (s *Store) checkSwapQuotas(removed map[string]bool, exps []runtime.GRPCCallExpectation) error {
+	if s.quotas.MaxExpectations <= 0 {
+		return nil
+	}
+	counts := s.expectationCounts()
+	for id := range removed {
+		key, idx, _ := s.findByID(id)
+		counts[s.expectationsStore[key][idx].QuotaNamespace()]--
+	}
+	added := make(map[string]int)
+	for _, exp := range exps {
+		counts[exp.QuotaNamespace()]++
+		added[exp.QuotaNamespace()]++
+	}
+	for _, exp := range exps {
+		if ns := exp.QuotaNamespace(); counts[ns] > s.quotas.MaxExpectations {
+			return s.expectationQuotaError(ns, added[ns])
+		}
+	}
+	return nil
+}
+
+// GetExpectation returns the expectation with the given ID.
+func (s *Store) GetExpectation(id string) (runtime.GRPCCallExpectation, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, idx, ok := s.findByID(id)
+	if !ok {
+		return runtime.GRPCCallExpectation{}, false
+	}
+	return s.expectationsStore[key][idx], true
+}
+
+// ReplaceExpectation replaces the expectation with the given ID, keeping its ID,
+// and notifies the observers. The replacement keeps the position of the old one
+// if it is for the same method, and its match count starts over.
+func (s *Store) ReplaceExpectation(id string, exp runtime.GRPCCallExpectation) error {
+	if exp.ID != "" && exp.ID != id {
+		return fmt.Errorf("id %q does not match the replaced expectation's %q", exp.ID, id)
+	}
+	exp.ID = id
+	if err := s.replaceExpectation(exp); err != nil {
+		return err
+	}
+	s.notifyAdded(exp)
 	return nil
 }
 
+func (s *Store) replaceExpectation(exp runtime.GRPCCallExpectation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	oldKey, idx, ok := s.findByID(exp.ID)
+	if !ok {
+		return fmt.Errorf("%w: %s", runtime.ErrExpectationNotFound, exp.ID)
+	}
+	if err := s.validateExpectation(&exp, map[string]bool{exp.ID: true}); err != nil {
+		return err
+	}
+	if err := s.checkSwapQuotas(map[string]bool{exp.ID: true}, []runtime.GRPCCallExpectation{exp}); err != nil {
+		return err
+	}
+	key := runtime.ExpectationKey(exp)
+	if key == oldKey {
+		s.expectationsStore[key][idx] = exp
+		delete(s.matchCounts, exp.ID)
+	} else {
+		s.removeAt(oldKey, idx)
+		s.insert(exp)
+	}
+	s.publish(runtime.ExpectationEvent{Type: runtime.ExpectationEventReplaced, Expectation: &exp})
+	log.Printf("grpcmockruntime: Replaced expectation %s for %s", exp.ID, key)
+	return nil
+}
+
+// RemoveExpectation removes the expectation with the given ID, reporting whether it existed.
+func (s *Store) RemoveExpectation(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, idx, ok := s.findByID(id)
+	if !ok {
+		return false
+	}
+	exp := s.expectationsStore[key][idx]
+	s.removeAt(key, idx)
+	s.publish(runtime.ExpectationEvent{Type: runtime.ExpectationEventRemoved, Expectation: &exp})
+	log.Printf("grpcmockruntime: Removed expectation %s for %s", id, key)
+	return true
+}
+
+// insert appends an expectation, which must have an ID, to those of its key and
+// returns the key. The caller must hold the lock.
+func (s *Store) insert(exp runtime.GRPCCallExpectation) string {
+	key := runtime.ExpectationKey(exp)
+	s.expectationsStore[key] = append(s.expectationsStore[key], exp)
+	s.expKeys[exp.ID] = key
+	return key
+}
+
+// removeAt removes the expectation at index idx of key along with its match
+// count. The caller must hold the lock.
+func (s *Store) removeAt(key string, idx int) {
+	exps := s.expectationsStore[key]
+	delete(s.matchCounts, exps[idx].ID)
+	delete(s.expKeys, exps[idx].ID)
+	if len(exps) == 1 {
+		delete(s.expectationsStore, key)
+		return
+	}
+	s.expectationsStore[key] = append(exps[:idx:idx], exps[idx+1:]...)
+}
+
+// AddValidator adds a check run on expectations before they are stored. It may
+// attach data derived from the expectation to it, e.g. compiled templates, and
+// must not call the store.
+func (s *Store) AddValidator(validate func(*runtime.GRPCCallExpectation) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.validators = append(s.validators, validate)
+}
+
 // GetExpectations returns all current expectations.
 func (s *Store) GetExpectations() map[string][]runtime.GRPCCallExpectation {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+	return s.copyExpectations()
+}
+
+// copyExpectations returns a copy of the expectations; the caller must hold the lock.
+func (s *Store) copyExpectations() map[string][]runtime.GRPCCallExpectation {
 	// Return a copy to avoid external modification issues if the caller modifies the map/slice
 	copiedExpectations := make(map[string][]runtime.GRPCCallExpectation)
 	for k, v := range s.expectationsStore {
@@ -68,63 +873,547 @@ func (s *Store) ClearAll() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.expectationsStore = make(map[string][]runtime.GRPCCallExpectation)
+	s.expKeys = make(map[string]string)
 	s.recordedCalls = make([]runtime.RecordedGRPCCall, 0)
+	s.callsByTrace = make(map[string][]int)
+	s.answeredByMethod = make(map[string][]int)
+	s.answeredByExp = make(map[string][]int)
+	s.unmatchedCalls = make([]runtime.UnmatchedGRPCCall, 0)
+	s.connectionEvents = make([]runtime.ConnectionEvent, 0)
+	s.slowCalls = make([]runtime.SlowCall, 0)
+	s.requestKeys = make(map[string]*list.Element)
+	s.requestKeyOrder = list.New()
+	s.operations = make(map[string]runtime.OperationState)
+	s.matchCounts = make(map[string]int)
+	s.clearCallUsage()
+	s.scenarios = make(map[string]string)
+	s.vars = make(map[string]string)
+	s.publish(runtime.ExpectationEvent{Type: runtime.ExpectationEventCleared})
 	log.Println("grpcmockruntime: All expectations and recorded calls cleared.")
 }
 
-// RecordCall records an incoming gRPC call.
-// It now correctly uses proto.Message with protojson.Marshal.
-func (s *Store) RecordCall(fullMethodName string, headers map[string][]string, reqBodyProto proto.Message) {
+// clearCallUsage resets the number of calls held by each namespace and the
+// next expiry of their calls; the caller must hold the lock.
+func (s *Store) clearCallUsage() {
+	s.nextExpiry = 0
+	for _, u := range s.usage {
+		u.RecordedCalls = 0
+		u.UnmatchedCalls = 0
+	}
+}
+
+// ClearCalls clears the recorded and unmatched calls, the slow calls, the
+// duplicate requests and the match counts, keeping the expectations and the
+// state of scenarios, variables and operations.
+func (s *Store) ClearCalls() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.recordedCalls = make([]runtime.RecordedGRPCCall, 0)
+	s.callsByTrace = make(map[string][]int)
+	s.answeredByMethod = make(map[string][]int)
+	s.answeredByExp = make(map[string][]int)
+	s.unmatchedCalls = make([]runtime.UnmatchedGRPCCall, 0)
+	s.slowCalls = make([]runtime.SlowCall, 0)
+	s.requestKeys = make(map[string]*list.Element)
+	s.requestKeyOrder = list.New()
+	s.matchCounts = make(map[string]int)
+	s.clearCallUsage()
+	log.Println("grpcmockruntime: Recorded calls cleared.")
+}
 
+// RecordCall records an incoming gRPC call and returns its ID, or 0 if it was not recorded.
+// It now correctly uses proto.Message with protojson.Marshal. It returns an error
+// wrapping runtime.ErrQuotaExceeded if the namespace of the call reached its
+// recorded calls quota; the call should then be rejected.
+func (s *Store) RecordCall(fullMethodName string, headers map[string][]string, reqBodyProto proto.Message) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rate := s.sampling.RateFor(fullMethodName); rate < 1 && rand.Float64() >= rate {
+		s.skippedCalls++
+		return 0, nil
+	}
+	ns := runtime.Namespace(headers)
+	u := s.nsUsage(ns)
+	if s.callQuotaExceeded(u, u.RecordedCalls, ns, fullMethodName) {
+		return 0, fmt.Errorf("%w: at most %d recorded calls in namespace %q", runtime.ErrQuotaExceeded, s.quotas.MaxRecordedCalls, ns)
+	}
+	u.RecordedCalls++
+
+	reqBodyJSON, marshalErr, raw := recordedBody(fullMethodName, reqBodyProto)
+
+	s.lastCallID++
+	traceID := runtime.TraceID(headers)
+	if traceID != "" {
+		s.callsByTrace[traceID] = append(s.callsByTrace[traceID], len(s.recordedCalls))
+	}
+	s.recordedCalls = append(s.recordedCalls, runtime.RecordedGRPCCall{
+		ID:             s.lastCallID,
+		FullMethodName: fullMethodName,
+		Headers:        headers,
+		Body:           reqBodyJSON,
+		Timestamp:      s.clock.Now().UnixNano(),
+		TraceID:        traceID,
+		BodySha256:     runtime.BodySha256(reqBodyProto),
+		MarshalError:   marshalErr,
+		BodyRaw:        raw,
+	})
+	s.scheduleExpiry(ns, s.recordedCalls[len(s.recordedCalls)-1].Timestamp)
+	s.applyRetention()
+	log.Printf("grpcmockruntime: Recorded call to %s", fullMethodName) // Optional: for verbose logging
+	return s.lastCallID, nil
+}
+
+// scheduleExpiry brings forward the next expiry to that of a call just
+// recorded in a namespace, if its retention has a TTL. The caller must hold the lock.
+func (s *Store) scheduleExpiry(ns string, timestamp int64) {
+	if ttl := s.retention.For(ns).TTL().Nanoseconds(); ttl > 0 && (s.nextExpiry == 0 || timestamp+ttl < s.nextExpiry) {
+		s.nextExpiry = timestamp + ttl
+	}
+}
+
+// recordedBody returns the protojson form of a recorded request, or, if it
+// cannot be marshalled, an empty body, the error and its binary encoding.
+func recordedBody(fullMethodName string, reqBodyProto proto.Message) (json.RawMessage, string, []byte) {
 	var reqBodyJSON json.RawMessage = []byte("{}") // Default to empty JSON if reqBodyProto is nil or marshalling fails
+	var marshalErr string
+	var raw []byte
 
 	if reqBodyProto != nil {
 		bytes, err := DefaultMarshaler.Marshal(reqBodyProto) // Directly use reqBodyProto (which is proto.Message)
 		if err != nil {
-			// Log the error but still proceed to record the call, possibly with an empty or error indicator in the body
+			// Record the call with its binary encoding so it can still be inspected and matched by hash.
 			log.Printf("grpcmockruntime: error marshalling request body to JSON for recording call '%s': %v", fullMethodName, err)
-			// Optionally, you could store an error message in reqBodyJSON or a separate field
-			errorMsg := fmt.Sprintf(`{"error_marshalling_request_body": "%s"}`, err.Error())
-			reqBodyJSON = json.RawMessage(errorMsg)
+			marshalErr = err.Error()
+			raw, _ = proto.MarshalOptions{Deterministic: true}.Marshal(reqBodyProto)
 		} else {
 			reqBodyJSON = json.RawMessage(bytes)
 		}
 	}
+	return reqBodyJSON, marshalErr, raw
+}
 
-	s.recordedCalls = append(s.recordedCalls, runtime.RecordedGRPCCall{
-		FullMethodName: fullMethodName,
-		Headers:        headers,
-		Body:           reqBodyJSON,
-		Timestamp:      time.Now().UnixNano(),
-	})
-	log.Printf("grpcmockruntime: Recorded call to %s", fullMethodName) // Optional: for verbose logging
+// RecordRequest attaches the request to the recorded call with the given ID,
+// for calls recorded before their first message was received. Calls not
+// recorded or cleared in the meantime are ignored.
+func (s *Store) RecordRequest(callID uint64, fullMethodName string, reqBodyProto proto.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.recordedCalls) - 1; i >= 0; i-- {
+		if s.recordedCalls[i].ID == callID {
+			call := &s.recordedCalls[i]
+			call.Body, call.MarshalError, call.BodyRaw = recordedBody(fullMethodName, reqBodyProto)
+			call.BodySha256 = runtime.BodySha256(reqBodyProto)
+			return
+		}
+	}
+}
+
+// RecordResponse attaches the mock's answer to the recorded call with the given ID.
+// Calls cleared in the meantime are ignored.
+func (s *Store) RecordResponse(callID uint64, resp *runtime.RecordedResponse) {
+	s.mu.Lock()
+	var call *runtime.RecordedGRPCCall
+	for i := len(s.recordedCalls) - 1; i >= 0; i-- {
+		if s.recordedCalls[i].ID == callID {
+			s.recordedCalls[i].Response = resp
+			c := s.recordedCalls[i]
+			call = &c
+			s.answeredByMethod[c.FullMethodName] = append(s.answeredByMethod[c.FullMethodName], i)
+			if resp.ExpectationID != "" {
+				s.answeredByExp[resp.ExpectationID] = append(s.answeredByExp[resp.ExpectationID], i)
+			}
+			break
+		}
+	}
+	if call != nil {
+		s.publishCall(*call)
+	}
+	s.mu.Unlock()
+	if call == nil {
+		return
+	}
+	s.observersMu.RLock()
+	defer s.observersMu.RUnlock()
+	for _, observe := range s.callObservers {
+		observe(*call)
+	}
+}
+
+// OnExpectationAdded registers a function called with every expectation added to the store.
+func (s *Store) OnExpectationAdded(fn func(runtime.GRPCCallExpectation)) {
+	s.observersMu.Lock()
+	defer s.observersMu.Unlock()
+	s.expObservers = append(s.expObservers, fn)
+}
+
+// OnCallRecorded registers a function called with every recorded call once its response is recorded.
+func (s *Store) OnCallRecorded(fn func(runtime.RecordedGRPCCall)) {
+	s.observersMu.Lock()
+	defer s.observersMu.Unlock()
+	s.callObservers = append(s.callObservers, fn)
+}
+
+// FindAnsweredCall reports whether a recorded call that was answered, to the
+// given method if set and by the expectation with the given ID if set,
+// satisfies accept, trying the latest calls first. accept is called with the
+// lock held, so it must not call the store.
+func (s *Store) FindAnsweredCall(fullMethodName, expectationID string, accept func(runtime.RecordedGRPCCall) bool) bool {
+	s.expireCalls()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	indexes := s.answeredByMethod[fullMethodName]
+	if expectationID != "" {
+		indexes = s.answeredByExp[expectationID]
+	}
+	for i := len(indexes) - 1; i >= 0; i-- {
+		call := s.recordedCalls[indexes[i]]
+		if fullMethodName != "" && call.FullMethodName != fullMethodName {
+			continue
+		}
+		if accept(call) {
+			return true
+		}
+	}
+	return false
 }
 
 // GetRecordedCalls returns all recorded calls.
 func (s *Store) GetRecordedCalls() []runtime.RecordedGRPCCall {
+	s.expireCalls()
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	// Return a copy
 	return append([]runtime.RecordedGRPCCall(nil), s.recordedCalls...)
 }
 
-// IncrementMatch increments the match count for a given expectation.
-func (s *Store) IncrementMatch(fullMethod string, idx int) {
+// GetRecordedCallsByTrace returns the recorded calls belonging to a trace.
+func (s *Store) GetRecordedCallsByTrace(traceID string) []runtime.RecordedGRPCCall {
+	s.expireCalls()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	indexes := s.callsByTrace[strings.ToLower(traceID)]
+	calls := make([]runtime.RecordedGRPCCall, 0, len(indexes))
+	for _, i := range indexes {
+		calls = append(calls, s.recordedCalls[i])
+	}
+	return calls
+}
+
+// FilterRecordedCalls returns the recorded calls selected by the filter, looking
+// up those of its trace, if it has one, in the trace index.
+func (s *Store) FilterRecordedCalls(filter runtime.CallFilter) []runtime.RecordedGRPCCall {
+	s.expireCalls()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	calls := make([]runtime.RecordedGRPCCall, 0)
+	if filter.TraceID != "" {
+		for _, i := range s.callsByTrace[strings.ToLower(filter.TraceID)] {
+			if filter.Matches(&s.recordedCalls[i]) {
+				calls = append(calls, s.recordedCalls[i])
+			}
+		}
+		return calls
+	}
+	for i := range s.recordedCalls {
+		if filter.Matches(&s.recordedCalls[i]) {
+			calls = append(calls, s.recordedCalls[i])
+		}
+	}
+	return calls
+}
+
+// RecordUnmatched records a call that did not match any expectation.
+func (s *Store) RecordUnmatched(call runtime.UnmatchedGRPCCall) {
+	s.mu.Lock()
+	ns := runtime.Namespace(call.Headers)
+	if u := s.nsUsage(ns); !s.callQuotaExceeded(u, u.UnmatchedCalls, ns, call.FullMethodName) {
+		u.UnmatchedCalls++
+		s.unmatchedCalls = append(s.unmatchedCalls, call)
+		s.scheduleExpiry(ns, call.Timestamp)
+		s.applyRetention()
+	}
+	s.mu.Unlock()
+
+	s.observersMu.RLock()
+	defer s.observersMu.RUnlock()
+	for _, observe := range s.unmatchedObs {
+		observe(call)
+	}
+}
+
+// OnUnmatchedRecorded registers a function called with every call that matched no expectation.
+func (s *Store) OnUnmatchedRecorded(fn func(runtime.UnmatchedGRPCCall)) {
+	s.observersMu.Lock()
+	defer s.observersMu.Unlock()
+	s.unmatchedObs = append(s.unmatchedObs, fn)
+}
+
+// GetUnmatchedCalls returns all calls that did not match any expectation.
+func (s *Store) GetUnmatchedCalls() []runtime.UnmatchedGRPCCall {
+	s.expireCalls()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]runtime.UnmatchedGRPCCall(nil), s.unmatchedCalls...)
+}
+
+// RecordConnectionEvent records a transport-level event, stamping it with the current time.
+func (s *Store) RecordConnectionEvent(ev runtime.ConnectionEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ev.Timestamp = s.clock.Now().UnixNano()
+	s.connectionEvents = append(s.connectionEvents, ev)
+}
+
+// GetConnectionEvents returns all recorded connection events.
+func (s *Store) GetConnectionEvents() []runtime.ConnectionEvent {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]runtime.ConnectionEvent(nil), s.connectionEvents...)
+}
+
+// maxRequestKeys bounds the idempotency keys tracked by RecordRequestKey; when
+// it is reached, the least recently seen key is forgotten.
+const maxRequestKeys = 4096
+
+// RecordRequestKey counts a request received for a method with the given
+// idempotency key, and returns the number of requests seen with it so far.
+func (s *Store) RecordRequestKey(fullMethodName, key string) int {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	key := fmt.Sprintf("%s#%d", fullMethod, idx)
-	s.matchCounts[key]++
+	now := s.clock.Now().UnixNano()
+	id := fullMethodName + "\x00" + key // Keys are scoped per method
+	elem, ok := s.requestKeys[id]
+	if ok {
+		s.requestKeyOrder.MoveToFront(elem)
+	} else {
+		if s.requestKeyOrder.Len() >= maxRequestKeys {
+			oldest := s.requestKeyOrder.Back()
+			dup := s.requestKeyOrder.Remove(oldest).(*runtime.DuplicateRequest)
+			delete(s.requestKeys, dup.FullMethodName+"\x00"+dup.Key)
+		}
+		elem = s.requestKeyOrder.PushFront(&runtime.DuplicateRequest{FullMethodName: fullMethodName, Key: key, FirstSeen: now})
+		s.requestKeys[id] = elem
+	}
+	dup := elem.Value.(*runtime.DuplicateRequest)
+	dup.Count++
+	dup.LastSeen = now
+	return dup.Count
 }
 
-// GetMatchCounts returns the current match counts for all expectations.
+// GetDuplicateRequests returns the idempotency keys received more than once,
+// ordered by method and key.
+func (s *Store) GetDuplicateRequests() []runtime.DuplicateRequest {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	dups := make([]runtime.DuplicateRequest, 0)
+	for elem := s.requestKeyOrder.Front(); elem != nil; elem = elem.Next() {
+		if dup := elem.Value.(*runtime.DuplicateRequest); dup.Count > 1 {
+			dups = append(dups, *dup)
+		}
+	}
+	sort.Slice(dups, func(i, j int) bool {
+		if dups[i].FullMethodName != dups[j].FullMethodName {
+			return dups[i].FullMethodName < dups[j].FullMethodName
+		}
+		return dups[i].Key < dups[j].Key
+	})
+	return dups
+}
+
+// ClaimMatch counts a call matched by an expectation, moves its scenario to
+// NewState as TransitionScenario does, and returns its new match count, unless,
+// checked under the same lock, the expectation was removed, reached its Times
+// limit, is no longer activated by its ActiveWhen condition or its scenario left
+// RequiredState; the call must then not be served by it.
+func (s *Store) ClaimMatch(exp *runtime.GRPCCallExpectation) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.expKeys[exp.ID]; !ok {
+		return 0, false
+	}
+	if !exp.Times.Allows(s.matchCounts[exp.ID]) {
+		return 0, false
+	}
+	if aw := exp.ActiveWhen; aw != nil {
+		if _, ok := s.expKeys[aw.ExpectationID]; !ok || !aw.Satisfied(s.matchCounts[aw.ExpectationID]) {
+			return 0, false
+		}
+	}
+	if exp.Scenario != "" && !s.transitionScenario(exp.Scenario, exp.RequiredState, exp.NewState) {
+		return 0, false
+	}
+	s.matchCounts[exp.ID]++
+	return s.matchCounts[exp.ID], true
+}
+
+// MatchCount returns the number of calls matched by the expectation with the given ID.
+func (s *Store) MatchCount(id string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.matchCounts[id]
+}
+
+// MatchCountByID returns the number of calls matched by the expectation with the given ID,
+// and false if no such expectation exists.
+func (s *Store) MatchCountByID(id string) (int, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.expKeys[id]; !ok {
+		return 0, false
+	}
+	return s.matchCounts[id], true
+}
+
+// findByID locates the expectation with the given ID. The caller must hold the lock.
+func (s *Store) findByID(id string) (string, int, bool) {
+	key, ok := s.expKeys[id]
+	if !ok {
+		return "", 0, false
+	}
+	for idx, exp := range s.expectationsStore[key] {
+		if exp.ID == id {
+			return key, idx, true
+		}
+	}
+	return "", 0, false
+}
+
+// GetMatchCounts returns the current match counts of the expectations that
+// matched calls, keyed by "key#index" of the expectations' current positions.
 func (s *Store) GetMatchCounts() map[string]int {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	copy := make(map[string]int, len(s.matchCounts))
-	for k, v := range s.matchCounts {
-		copy[k] = v
+	counts := make(map[string]int, len(s.matchCounts))
+	for key, exps := range s.expectationsStore {
+		for idx, exp := range exps {
+			if n, ok := s.matchCounts[exp.ID]; ok {
+				counts[fmt.Sprintf("%s#%d", key, idx)] = n
+			}
+		}
+	}
+	return counts
+}
+
+// SetFixture registers a fixture, replacing any fixture with the same name.
+func (s *Store) SetFixture(fixture runtime.Fixture) error {
+	if fixture.Name == "" {
+		return fmt.Errorf("fixture name is required")
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(fixture.Body, &obj); err != nil || obj == nil {
+		return fmt.Errorf("fixture body must be a JSON object")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fixtures[fixture.Name] = fixture.Body
+	log.Printf("grpcmockruntime: Set fixture %s", fixture.Name)
+	return nil
+}
+
+// GetFixture returns the body of the named fixture.
+func (s *Store) GetFixture(name string) (json.RawMessage, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	body, ok := s.fixtures[name]
+	return body, ok
+}
+
+// GetFixtures returns all fixtures by name.
+func (s *Store) GetFixtures() map[string]json.RawMessage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fixtures := make(map[string]json.RawMessage, len(s.fixtures))
+	for name, body := range s.fixtures {
+		fixtures[name] = body
+	}
+	return fixtures
+}
+
+// ClearFixtures removes all fixtures.
+func (s *Store) ClearFixtures() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fixtures = make(map[string]json.RawMessage)
+}
+
+// AddOperation starts tracking a long-running operation.
+func (s *Store) AddOperation(op runtime.OperationState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.operations[op.Name] = op
+	log.Printf("grpcmockruntime: Added operation %s", op.Name)
+}
+
+// GetOperation returns the tracked long-running operation with the given name.
+func (s *Store) GetOperation(name string) (runtime.OperationState, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	op, ok := s.operations[name]
+	return op, ok
+}
+
+// SubscribeExpectations returns a channel receiving a snapshot of the current
+// expectations followed by every subsequent change, and a function to cancel
+// the subscription. Events are dropped for subscribers that fall behind.
+func (s *Store) SubscribeExpectations() (<-chan runtime.ExpectationEvent, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ch := make(chan runtime.ExpectationEvent, 64)
+	ch <- runtime.ExpectationEvent{
+		Type:         runtime.ExpectationEventSnapshot,
+		Expectations: s.copyExpectations(),
+		Timestamp:    s.clock.Now().UnixNano(),
+	}
+	s.subscribers[ch] = struct{}{}
+	cancel := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.subscribers[ch]; ok {
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+	}
+	return ch, cancel
+}
+
+// SubscribeCalls returns a channel receiving every call recorded from now on,
+// once its response is recorded, and a function to cancel the subscription.
+// Calls are dropped for subscribers that fall behind.
+func (s *Store) SubscribeCalls() (<-chan runtime.RecordedGRPCCall, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ch := make(chan runtime.RecordedGRPCCall, 64)
+	s.callSubscribers[ch] = struct{}{}
+	cancel := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.callSubscribers[ch]; ok {
+			delete(s.callSubscribers, ch)
+			close(ch)
+		}
+	}
+	return ch, cancel
+}
+
+// publishCall sends a recorded call to all call subscribers; the caller must hold the lock.
+func (s *Store) publishCall(call runtime.RecordedGRPCCall) {
+	for ch := range s.callSubscribers {
+		select {
+		case ch <- call:
+		default:
+			log.Printf("grpcmockruntime: Dropped call %d for a slow subscriber", call.ID)
+		}
+	}
+}
+
+// publish sends an expectation event to all subscribers; the caller must hold the lock.
+func (s *Store) publish(ev runtime.ExpectationEvent) {
+	ev.Timestamp = s.clock.Now().UnixNano()
+	for ch := range s.subscribers {
+		select {
+		case ch <- ev:
+		default:
+			log.Printf("grpcmockruntime: Dropped %s expectation event for a slow subscriber", ev.Type)
+		}
 	}
-	return copy
 }
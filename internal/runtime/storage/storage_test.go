@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rbroggi/grpcmock/internal/runtime"
+)
+
+const testMethod = "/pkg.v1.Svc/Get"
+
+// addExpectation registers exp, completed with a method and a response, and
+// returns it as stored.
+func addExpectation(t *testing.T, s *Store, exp runtime.GRPCCallExpectation) *runtime.GRPCCallExpectation {
+	t.Helper()
+	exp.FullMethodName = testMethod
+	exp.Response = &runtime.MockResponse{}
+	id, err := s.AddExpectation(exp)
+	if err != nil {
+		t.Fatalf("AddExpectation: %v", err)
+	}
+	exp.ID = id
+	return &exp
+}
+
+// claims claims n matches of exp and returns which succeeded.
+func claims(s *Store, exp *runtime.GRPCCallExpectation, n int) []bool {
+	got := make([]bool, n)
+	for i := range got {
+		_, got[i] = s.ClaimMatch(exp)
+	}
+	return got
+}
+
+func TestClaimMatchTimes(t *testing.T) {
+	tests := []struct {
+		name  string
+		times *runtime.ExpectationTimes
+		want  []bool
+	}{
+		{name: "unlimited", times: nil, want: []bool{true, true, true}},
+		{name: "exact", times: &runtime.ExpectationTimes{Exact: 2}, want: []bool{true, true, false}},
+		{name: "max", times: &runtime.ExpectationTimes{Max: 1}, want: []bool{true, false, false}},
+		{name: "min only", times: &runtime.ExpectationTimes{Min: 1}, want: []bool{true, true, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			exp := addExpectation(t, s, runtime.GRPCCallExpectation{Times: tt.times})
+			got := claims(s, exp, len(tt.want))
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Fatalf("claims = %v, want %v", got, tt.want)
+				}
+			}
+			if n := s.MatchCount(exp.ID); n != countTrue(tt.want) {
+				t.Errorf("MatchCount = %d, want %d", n, countTrue(tt.want))
+			}
+		})
+	}
+}
+
+func TestClaimMatchActiveWhen(t *testing.T) {
+	tests := []struct {
+		name        string
+		minMatches  int
+		warmups     int
+		removeWatch bool
+		want        bool
+	}{
+		{name: "not yet active", minMatches: 2, warmups: 1, want: false},
+		{name: "active", minMatches: 2, warmups: 2, want: true},
+		{name: "default min matches", warmups: 1, want: true},
+		{name: "watched expectation removed", warmups: 1, removeWatch: true, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			warmup := addExpectation(t, s, runtime.GRPCCallExpectation{ID: "warmup"})
+			gated := addExpectation(t, s, runtime.GRPCCallExpectation{
+				ActiveWhen: &runtime.ActiveWhen{ExpectationID: warmup.ID, MinMatches: tt.minMatches},
+			})
+			claims(s, warmup, tt.warmups)
+			if tt.removeWatch {
+				if !s.RemoveExpectation(warmup.ID) {
+					t.Fatal("RemoveExpectation found no expectation")
+				}
+			}
+			if _, ok := s.ClaimMatch(gated); ok != tt.want {
+				t.Errorf("ClaimMatch = %v, want %v", ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestClaimMatchRemoved(t *testing.T) {
+	s := New()
+	exp := addExpectation(t, s, runtime.GRPCCallExpectation{})
+	if !s.RemoveExpectation(exp.ID) {
+		t.Fatal("RemoveExpectation found no expectation")
+	}
+	if _, ok := s.ClaimMatch(exp); ok {
+		t.Error("ClaimMatch of a removed expectation succeeded")
+	}
+}
+
+// TestClaimMatchConcurrent checks that concurrent calls never claim more
+// matches than Times allows, nor activate a gated expectation early.
+func TestClaimMatchConcurrent(t *testing.T) {
+	const limit, callers = 10, 64
+	s := New()
+	exp := addExpectation(t, s, runtime.GRPCCallExpectation{Times: &runtime.ExpectationTimes{Exact: limit}})
+	gated := addExpectation(t, s, runtime.GRPCCallExpectation{
+		ActiveWhen: &runtime.ActiveWhen{ExpectationID: exp.ID, MinMatches: limit},
+	})
+
+	var claimed, early atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, ok := s.ClaimMatch(exp); ok {
+				claimed.Add(1)
+			}
+			if _, ok := s.ClaimMatch(gated); ok && s.MatchCount(exp.ID) < limit {
+				early.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := claimed.Load(); n != limit {
+		t.Errorf("claimed %d matches, want %d", n, limit)
+	}
+	if n := early.Load(); n != 0 {
+		t.Errorf("gated expectation matched %d times before activation", n)
+	}
+}
+
+func TestClaimMatchScenario(t *testing.T) {
+	tests := []struct {
+		name          string
+		state         string // Set before claiming, if not empty
+		requiredState string
+		newState      string
+		want          bool
+		wantState     string
+	}{
+		{name: "any state", newState: "Paid", want: true, wantState: "Paid"},
+		{name: "required state reached", state: "Created", requiredState: "Created", newState: "Paid", want: true, wantState: "Paid"},
+		{name: "started by default", requiredState: runtime.ScenarioStarted, newState: "Created", want: true, wantState: "Created"},
+		{name: "other state", state: "Paid", requiredState: "Created", newState: "Shipped", want: false, wantState: "Paid"},
+		{name: "no transition", state: "Created", requiredState: "Created", want: true, wantState: "Created"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			exp := addExpectation(t, s, runtime.GRPCCallExpectation{Scenario: "order", RequiredState: tt.requiredState, NewState: tt.newState})
+			if tt.state != "" {
+				s.SetScenarioState("order", tt.state)
+			}
+			if _, ok := s.ClaimMatch(exp); ok != tt.want {
+				t.Errorf("ClaimMatch = %v, want %v", ok, tt.want)
+			}
+			if state := s.ScenarioState("order"); state != tt.wantState {
+				t.Errorf("scenario state = %q, want %q", state, tt.wantState)
+			}
+			if n := s.MatchCount(exp.ID); n != countTrue([]bool{tt.want}) {
+				t.Errorf("MatchCount = %d, want %d", n, countTrue([]bool{tt.want}))
+			}
+		})
+	}
+}
+
+// TestClaimMatchScenarioConcurrent checks that a single concurrent call moves a
+// scenario out of the state that the competing expectations require.
+func TestClaimMatchScenarioConcurrent(t *testing.T) {
+	const callers = 64
+	s := New()
+	exps := []*runtime.GRPCCallExpectation{
+		addExpectation(t, s, runtime.GRPCCallExpectation{Scenario: "order", RequiredState: runtime.ScenarioStarted, NewState: "Created"}),
+		addExpectation(t, s, runtime.GRPCCallExpectation{Scenario: "order", RequiredState: runtime.ScenarioStarted, NewState: "Rejected"}),
+	}
+
+	var claimed atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, ok := s.ClaimMatch(exps[i%len(exps)]); ok {
+				claimed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := claimed.Load(); n != 1 {
+		t.Errorf("claimed %d matches, want 1", n)
+	}
+	if state := s.ScenarioState("order"); state != "Created" && state != "Rejected" {
+		t.Errorf("scenario state = %q, want Created or Rejected", state)
+	}
+}
+
+func countTrue(bs []bool) int {
+	n := 0
+	for _, b := range bs {
+		if b {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,47 @@
+package runtime
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultStreamInterval is the interval of infinite streams when IntervalMs is unset.
+const defaultStreamInterval = time.Second
+
+// Validate checks that the interval and limits are not negative.
+func (m *InfiniteStreamMock) Validate() error {
+	if m.IntervalMs < 0 || m.MaxDurationMs < 0 || m.MaxMessages < 0 {
+		return fmt.Errorf("intervalMs, maxDurationMs and maxMessages must not be negative")
+	}
+	return nil
+}
+
+// Interval returns the time between two messages.
+func (m *InfiniteStreamMock) Interval() time.Duration {
+	if m.IntervalMs == 0 {
+		return defaultStreamInterval
+	}
+	return time.Duration(m.IntervalMs) * time.Millisecond
+}
+
+// ValidateMessageInterval checks the intervals between server stream messages.
+func (r *MockResponse) ValidateMessageInterval() error {
+	if r.MessageIntervalMs < 0 || r.JitterMs < 0 {
+		return fmt.Errorf("messageIntervalMs and jitterMs must not be negative")
+	}
+	if r.MessageIntervalMs > 0 && r.Infinite != nil {
+		return fmt.Errorf("messageIntervalMs cannot be used with infinite, set infinite.intervalMs instead")
+	}
+	return nil
+}
+
+// MessageInterval returns the time to wait before the next server stream
+// message: interval shifted by a random jitter, never negative.
+func (r *MockResponse) MessageInterval(interval time.Duration) time.Duration {
+	if r.JitterMs > 0 {
+		delayRandMu.Lock()
+		interval += time.Duration(delayRand.Int63n(2*r.JitterMs+1)-r.JitterMs) * time.Millisecond
+		delayRandMu.Unlock()
+	}
+	return max(interval, 0)
+}
@@ -0,0 +1,33 @@
+package runtime
+
+import (
+	"text/template"
+
+	"go.starlark.net/starlark"
+)
+
+// CompiledTemplates are the templates of a response or webhook, keyed by their
+// text, and the response's script, compiled by the responder when the
+// expectation is registered, along with the call data the templates read. They
+// are not part of the JSON form of expectations.
+type CompiledTemplates struct {
+	Templates    map[string]*template.Template
+	Reads        TemplateReads     // Of the templates other than the SetVars ones
+	SetVarsReads TemplateReads     // Of the SetVars templates of a response
+	Script       *starlark.Program // Compiled MockResponse.Script, if any
+}
+
+func (c *CompiledTemplates) readsRecordedCalls() bool {
+	return c != nil && (c.Reads.RecordedCalls || c.SetVarsReads.RecordedCalls)
+}
+
+// TemplateReads tells what templates read, as found in their parse trees.
+type TemplateReads struct {
+	Request       bool // .Request
+	Headers       bool // .Headers
+	Vars          bool // .Vars
+	Stream        bool // .Stream
+	Response      bool // .Response, in SetVars templates
+	RecordedCalls bool // Calls lastCall
+	Volatile      bool // Calls functions whose results differ between calls, e.g. fakeName
+}
@@ -0,0 +1,29 @@
+package runtime
+
+// Allows reports whether an expectation matched count times may match again.
+// A nil ExpectationTimes never limits matches.
+func (t *ExpectationTimes) Allows(count int) bool {
+	if t == nil {
+		return true
+	}
+	if t.Exact > 0 && count >= t.Exact {
+		return false
+	}
+	if t.Max > 0 && count >= t.Max {
+		return false
+	}
+	return true
+}
+
+// Satisfied reports whether the watched expectation, matched count times,
+// activates the expectation. A nil ActiveWhen is always satisfied.
+func (aw *ActiveWhen) Satisfied(count int) bool {
+	if aw == nil {
+		return true
+	}
+	minMatches := aw.MinMatches
+	if minMatches <= 0 {
+		minMatches = 1
+	}
+	return count >= minMatches
+}
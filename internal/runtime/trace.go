@@ -0,0 +1,63 @@
+package runtime
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// TraceID returns the trace ID propagated in a call's headers, in lowercase hex,
+// or "" if there is none. It understands W3C trace context (traceparent), B3
+// (single and multi header) and OpenCensus binary (grpc-trace-bin) propagation.
+func TraceID(headers metadata.MD) string {
+	if v := firstHeader(headers, "traceparent"); v != "" {
+		// version-traceid-parentid-flags
+		if parts := strings.Split(v, "-"); len(parts) >= 4 && validTraceID(parts[1]) {
+			return strings.ToLower(parts[1])
+		}
+	}
+	if v := firstHeader(headers, "x-b3-traceid"); validTraceID(v) {
+		return strings.ToLower(v)
+	}
+	if v := firstHeader(headers, "b3"); v != "" {
+		// traceid-spanid[-sampled[-parentspanid]]
+		if id, _, _ := strings.Cut(v, "-"); validTraceID(id) {
+			return strings.ToLower(id)
+		}
+	}
+	if v := firstHeader(headers, "grpc-trace-bin"); len(v) >= 18 && v[0] == 0 && v[1] == 0 {
+		// version 0, field 0 (trace ID) followed by 16 bytes
+		id := hex.EncodeToString([]byte(v[2:18]))
+		if validTraceID(id) {
+			return id
+		}
+	}
+	return ""
+}
+
+// firstHeader returns the first value of a header, or "".
+func firstHeader(headers metadata.MD, key string) string {
+	if vals := headers.Get(key); len(vals) > 0 {
+		return vals[0]
+	}
+	return ""
+}
+
+// validTraceID reports whether s is a 64 or 128-bit hex trace ID other than all zeros.
+func validTraceID(s string) bool {
+	if len(s) != 16 && len(s) != 32 {
+		return false
+	}
+	zero := true
+	for _, r := range s {
+		switch {
+		case r == '0':
+		case '1' <= r && r <= '9', 'a' <= r && r <= 'f', 'A' <= r && r <= 'F':
+			zero = false
+		default:
+			return false
+		}
+	}
+	return !zero
+}
@@ -2,6 +2,7 @@ package runtime
 
 import (
 	"encoding/json"
+	"time"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
@@ -9,10 +10,85 @@ import (
 
 // FieldMatcher allows for sophisticated field-level matching.
 type FieldMatcher struct {
-	Equals   interface{}   `json:"equals,omitempty"`
-	Regex    string        `json:"regex,omitempty"`
-	Contains interface{}   `json:"contains,omitempty"`
-	Range    *RangeMatcher `json:"range,omitempty"`
+	Equals    interface{}   `json:"equals,omitempty"`
+	NotEquals interface{}   `json:"notEquals,omitempty"`
+	Regex     string        `json:"regex,omitempty"`
+	NotRegex  string        `json:"notRegex,omitempty"`
+	Contains  interface{}   `json:"contains,omitempty"`
+	Range     *RangeMatcher `json:"range,omitempty"`
+	Absent    bool          `json:"absent,omitempty"` // Field must be unset on the request message
+	Any       *AnyMatcher   `json:"any,omitempty"`    // For google.protobuf.Any fields
+
+	// String comparison options, applied to Equals, NotEquals, Contains, Regex and NotRegex.
+	CaseInsensitive  bool   `json:"caseInsensitive,omitempty"`  // Compare with Unicode case folding
+	NormalizeUnicode string `json:"normalizeUnicode,omitempty"` // Normalization form applied to both sides: NFC, NFD, NFKC or NFKD
+
+	// google.protobuf.Timestamp matchers; Before/After take an RFC3339 time or "now".
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+	Within string `json:"within,omitempty"` // Max distance from now, e.g. "5m"
+
+	// google.protobuf.Duration matchers, e.g. "1.5s" or "300ms".
+	LessThan    string `json:"lessThan,omitempty"`
+	GreaterThan string `json:"greaterThan,omitempty"`
+
+	// bytes field matchers, comparing the decoded content of the base64 string protojson renders.
+	EqualsBase64 string        `json:"equalsBase64,omitempty"` // Standard or URL-safe, padded or not
+	EqualsHex    string        `json:"equalsHex,omitempty"`
+	Length       *FieldMatcher `json:"length,omitempty"` // Applied to the number of decoded bytes
+
+	// Presence matchers, distinguishing an explicitly set zero value from an unset
+	// proto3 optional field using the request message rather than its JSON form.
+	IsSet   bool `json:"isSet,omitempty"`
+	IsUnset bool `json:"isUnset,omitempty"`
+
+	// Fields applies matchers to the fields of a message value, ignoring the others.
+	Fields map[string]FieldMatcher `json:"fields,omitempty"`
+	// ArrayContaining requires some element of a repeated field, at any position, to match.
+	ArrayContaining *FieldMatcher `json:"arrayContaining,omitempty"`
+
+	// Size matchers for repeated fields (elements) and map fields (entries).
+	Count    *int `json:"count,omitempty"`
+	MinCount *int `json:"minCount,omitempty"`
+	MaxCount *int `json:"maxCount,omitempty"`
+
+	// Custom names a matcher function registered by the embedding Go code with RegisterMatcher.
+	Custom string `json:"custom,omitempty"`
+
+	// Format requires a string of a well-known format: "uuid", "email", "date-time"
+	// (RFC 3339), "date", "iso8601", "url", "ipv4" or "ipv6".
+	Format string `json:"format,omitempty"`
+
+	// Map field matchers; Fields applies matchers to the values of given keys.
+	HasKeys       []string         `json:"hasKeys,omitempty"`       // Every listed key must be present
+	MapContaining *MapEntryMatcher `json:"mapContaining,omitempty"` // Some entry must match
+
+	// Money compares amounts exactly, given as google.type.Money, decimal strings or numbers.
+	Money *MoneyMatcher `json:"money,omitempty"`
+}
+
+// MoneyMatcher compares an amount without floating-point rounding, e.g. "12.5"
+// equals "12.50" and {"currencyCode": "EUR", "units": "12", "nanos": 500000000}.
+// Bounds are decimal strings and inclusive.
+type MoneyMatcher struct {
+	Currency string `json:"currency,omitempty"` // Currency code the google.type.Money must have
+	Equals   string `json:"equals,omitempty"`
+	Min      string `json:"min,omitempty"`
+	Max      string `json:"max,omitempty"`
+}
+
+// MapEntryMatcher matches an entry of a map field on its key and value.
+// Keys are matched in their protojson form, which is always a string.
+type MapEntryMatcher struct {
+	Key   *FieldMatcher `json:"key,omitempty"`
+	Value *FieldMatcher `json:"value,omitempty"`
+}
+
+// AnyMatcher matches a google.protobuf.Any field on its type and unpacked payload.
+// The payload is only available when its message type is registered in the mock server.
+type AnyMatcher struct {
+	TypeURL string                  `json:"typeUrl,omitempty"` // Full type URL or message full name, e.g. "pkg.v1.Customer"
+	Body    map[string]FieldMatcher `json:"body,omitempty"`    // Matchers applied to the unpacked payload fields
 }
 
 type RangeMatcher struct {
@@ -22,9 +98,12 @@ type RangeMatcher struct {
 
 // HeaderMatcher allows for flexible header matching.
 type HeaderMatcher struct {
-	Exists *bool  `json:"exists,omitempty"`
-	Equals string `json:"equals,omitempty"`
-	Regex  string `json:"regex,omitempty"`
+	Exists    *bool  `json:"exists,omitempty"`
+	Equals    string `json:"equals,omitempty"`
+	NotEquals string `json:"notEquals,omitempty"` // No value may equal this
+	Regex     string `json:"regex,omitempty"`
+	NotRegex  string `json:"notRegex,omitempty"` // No value may match this
+	Absent    bool   `json:"absent,omitempty"`   // Header must not be present
 }
 
 // ExpectationTimes allows specifying how many times an expectation should be matched.
@@ -34,25 +113,185 @@ type ExpectationTimes struct {
 	Exact int `json:"exact,omitempty"`
 }
 
+// Schedule restricts when an expectation is eligible for matching.
+// All set constraints must hold; with Windows, at least one window must contain the current time.
+type Schedule struct {
+	ActiveFrom  *time.Time       `json:"activeFrom,omitempty"`  // RFC3339, inclusive
+	ActiveUntil *time.Time       `json:"activeUntil,omitempty"` // RFC3339, exclusive
+	Windows     []ScheduleWindow `json:"windows,omitempty"`
+}
+
+// ScheduleWindow is a recurring time-of-day window, e.g. a nightly maintenance window.
+type ScheduleWindow struct {
+	Days     []string `json:"days,omitempty"`     // Weekdays, e.g. ["Sat", "Sun"]; empty means every day
+	Start    string   `json:"start"`              // "HH:MM", inclusive
+	End      string   `json:"end"`                // "HH:MM", exclusive; a window may wrap past midnight
+	Timezone string   `json:"timezone,omitempty"` // IANA time zone name, default UTC
+}
+
 // StreamMock allows specifying streaming request/response sequences.
+// Its request matchers apply to the messages received on client-streaming calls.
 type StreamMock struct {
-	ExpectedRequests []RequestMatcher `json:"expectedRequests,omitempty"`
-	Responses        []MockResponse   `json:"responses,omitempty"`
+	// ExpectedRequests match the stream's messages by position: message i must
+	// match ExpectedRequests[i]. Later messages are unconstrained.
+	ExpectedRequests []RequestMatcher  `json:"expectedRequests,omitempty"`
+	MessageCount     *ExpectationTimes `json:"messageCount,omitempty"` // Bounds the number of messages
+	EveryMessage     *RequestMatcher   `json:"everyMessage,omitempty"` // Every message must match
+	SomeMessage      *RequestMatcher   `json:"someMessage,omitempty"`  // At least one message must match
+	Responses        []MockResponse    `json:"responses,omitempty"`
+	// Script drives a bidirectional stream conversation step by step.
+	Script []StreamStep `json:"script,omitempty"`
+}
+
+// StreamStep is one step of a bidirectional stream script: wait for the next
+// client message and check it, then send responses.
+type StreamStep struct {
+	ExpectRequest *RequestMatcher   `json:"expectRequest,omitempty"` // If nil, the step sends without receiving
+	SendResponses []json.RawMessage `json:"sendResponses,omitempty"`
 }
 
 // GRPCCallExpectation defines how a mock should behave.
 type GRPCCallExpectation struct {
+	ID             string            `json:"id,omitempty"` // Unique identifier, referenced by ActiveWhen; generated if unset
 	FullMethodName string            `json:"fullMethodName"`
 	RequestMatcher *RequestMatcher   `json:"requestMatcher,omitempty"`
 	Response       *MockResponse     `json:"response,omitempty"`
 	Times          *ExpectationTimes `json:"times,omitempty"`
 	Stream         *StreamMock       `json:"stream,omitempty"`
+	Schedule       *Schedule         `json:"schedule,omitempty"`
+	// Priority orders candidate expectations: higher priorities are tried first.
+	// Among equal priorities, expectations with more matcher constraints win,
+	// then the earliest registered one.
+	Priority int `json:"priority,omitempty"`
+	// ActiveWhen makes the expectation eligible only once another expectation has matched enough calls.
+	ActiveWhen *ActiveWhen `json:"activeWhen,omitempty"`
+	// Aliases let the expectation also serve other methods, e.g. the same method of a newer API version.
+	Aliases []MethodAlias `json:"aliases,omitempty"`
+	// Idempotency tracks repeated requests to the expectation, see GET /verifications/duplicates.
+	Idempotency *IdempotencyMock `json:"idempotency,omitempty"`
+	// FullMethodNameRegex, set instead of FullMethodName, applies the expectation to every
+	// method whose full name matches, e.g. "^/pkg\\.v1\\.[A-Za-z]+Service/Get.*$".
+	FullMethodNameRegex string `json:"fullMethodNameRegex,omitempty"`
+	// Default makes the expectation a fallback, tried only if no other expectation
+	// of the method (or, for service wildcards, of the service) matches the call.
+	Default bool `json:"default,omitempty"`
+	// After makes the expectation eligible only once a prior call satisfying the precondition has been answered.
+	After *After `json:"after,omitempty"`
+	// Authority restricts the expectation to calls whose :authority designates this host,
+	// e.g. "billing.internal"; the port is only compared if set, e.g. "billing.internal:443".
+	Authority string `json:"authority,omitempty"`
+	// Alert posts to a webhook when the expectation looks broken; it requires ID.
+	Alert *AlertMock `json:"alert,omitempty"`
+	// Responses, set instead of Response, answers the nth matching call with its
+	// nth response, e.g. a slow one then a fast one. AfterResponses sets what
+	// further calls get: "repeatLast" (default), "rotate" or "error" (RESOURCE_EXHAUSTED).
+	Responses      []MockResponse `json:"responses,omitempty"`
+	AfterResponses string         `json:"afterResponses,omitempty"`
+	// Scenario names a state machine shared by expectations, starting in ScenarioStarted.
+	// The expectation only matches while the scenario is in RequiredState (any state
+	// if empty) and, when it matches, moves the scenario to NewState (if set).
+	Scenario      string `json:"scenario,omitempty"`
+	RequiredState string `json:"requiredState,omitempty"`
+	NewState      string `json:"newState,omitempty"`
+	// Webhooks are HTTP requests sent to other systems after the expectation
+	// matches a call, e.g. to simulate asynchronous callbacks.
+	Webhooks []WebhookMock `json:"webhooks,omitempty"`
+	// FailFirst answers the first matching calls with an error, and the following
+	// ones with Response (or Responses), to test client retry policies.
+	FailFirst *FailFirstMock `json:"failFirst,omitempty"`
+	// Namespace is the namespace, e.g. of the test suite registering the
+	// expectation, whose quotas count it; DefaultNamespace if unset.
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// FailFirstMock fails the first Count calls matched by an expectation with Code,
+// UNAVAILABLE by default, and Message.
+type FailFirstMock struct {
+	Count   int        `json:"count"`
+	Code    codes.Code `json:"code,omitempty"`
+	Message string     `json:"message,omitempty"`
+}
+
+// AlertMock notifies the owners of an expectation, e.g. in a shared environment,
+// that it looks broken: when it has matched no call UnmatchedForMs after becoming
+// active, or when UnmatchedCalls calls to its method match no expectation within
+// WindowMs. An Alert is posted to WebhookURL at most once per condition and window.
+type AlertMock struct {
+	WebhookURL     string `json:"webhookUrl"`
+	UnmatchedForMs int64  `json:"unmatchedForMs,omitempty"`
+	UnmatchedCalls int    `json:"unmatchedCalls,omitempty"`
+	WindowMs       int64  `json:"windowMs,omitempty"` // Default 60000
+}
+
+// Alert types.
+const (
+	AlertNeverMatched     = "neverMatched"
+	AlertUnmatchedTraffic = "unmatchedTraffic"
+)
+
+// Alert is the JSON body posted to alert webhooks.
+type Alert struct {
+	Type           string `json:"type"` // AlertNeverMatched or AlertUnmatchedTraffic
+	ExpectationID  string `json:"expectationId"`
+	FullMethodName string `json:"fullMethodName,omitempty"` // Method of the unmatched calls
+	Message        string `json:"message"`
+	UnmatchedCalls int    `json:"unmatchedCalls,omitempty"`
+	Timestamp      int64  `json:"timestamp"` // Unix nano timestamp
+}
+
+// IdempotencyMock identifies repeated requests by an idempotency key.
+type IdempotencyMock struct {
+	// KeyField is the top-level request field holding the key (protojson or proto name).
+	// If empty, the key is a hash of the whole request body. Requests without the field are not tracked.
+	KeyField string `json:"keyField,omitempty"`
+	// RejectDuplicates fails repeated requests with ALREADY_EXISTS instead of serving Response.
+	RejectDuplicates bool `json:"rejectDuplicates,omitempty"`
+}
+
+// MethodAlias serves an expectation for another method. FieldMapping maps field
+// paths of the expectation's messages to their counterparts in the alias' messages
+// (e.g. "name": "displayName"); requests are mapped back before matching and
+// responses are mapped forward before being sent.
+type MethodAlias struct {
+	FullMethodName string            `json:"fullMethodName"`
+	FieldMapping   map[string]string `json:"fieldMapping,omitempty"`
+}
+
+// ActiveWhen gates an expectation on the match count of another expectation.
+type ActiveWhen struct {
+	ExpectationID string `json:"expectationId"`        // ID of the expectation whose matches are counted
+	MinMatches    int    `json:"minMatches,omitempty"` // Matches required before activation; default 1
+}
+
+// After is a precondition on the recorded calls: a call satisfying every set field
+// must have been served by an expectation before the expectation can match. Unless
+// ExpectationID is set, the call must also have succeeded.
+type After struct {
+	FullMethodName string          `json:"fullMethodName,omitempty"` // Method of the prior call
+	ExpectationID  string          `json:"expectationId,omitempty"`  // ID of the expectation that answered the prior call
+	RequestMatcher *RequestMatcher `json:"requestMatcher,omitempty"` // Matched against the prior call's request
 }
 
 // RequestMatcher defines the rules to match an incoming gRPC request.
+// All set rules must hold; AllOf, AnyOf and Not combine nested matchers.
 type RequestMatcher struct {
 	Headers map[string]HeaderMatcher `json:"headers,omitempty"`
 	Body    map[string]FieldMatcher  `json:"body,omitempty"`
+	// BodySchema is a JSON Schema document the protojson request body must validate against.
+	BodySchema json.RawMessage  `json:"bodySchema,omitempty"`
+	AllOf      []RequestMatcher `json:"allOf,omitempty"` // Every nested matcher must match
+	AnyOf      []RequestMatcher `json:"anyOf,omitempty"` // At least one nested matcher must match
+	Not        *RequestMatcher  `json:"not,omitempty"`   // The nested matcher must not match
+	// IgnoreFields lists FieldMask paths (e.g. "request_id", "metadata.timestamp")
+	// stripped from both the request body and the body matchers before comparison.
+	IgnoreFields []string `json:"ignoreFields,omitempty"`
+	// MinDeadlineMs and MaxDeadlineMs bound the caller's remaining deadline;
+	// calls without a deadline have an infinite one.
+	MinDeadlineMs int64 `json:"minDeadlineMs,omitempty"`
+	MaxDeadlineMs int64 `json:"maxDeadlineMs,omitempty"`
+	// BodySha256 is the hex SHA-256 of the request's deterministic binary serialization,
+	// as reported in the bodySha256 of recorded calls, to match large payloads exactly.
+	BodySha256 string `json:"bodySha256,omitempty"`
 }
 
 // MockResponse defines the response to be returned by the mock.
@@ -61,18 +300,236 @@ type MockResponse struct {
 	Body    json.RawMessage   `json:"body,omitempty"`
 	Bodies  []json.RawMessage `json:"bodies,omitempty"` // For streaming responses
 	Error   *RPCError         `json:"error,omitempty"`
+	// Operation makes the mock answer with a google.longrunning.Operation whose
+	// lifecycle is managed by the mock (see OperationMock).
+	Operation *OperationMock `json:"operation,omitempty"`
+	// Pagination serves pages of a fixed item set, merged into Body.
+	Pagination *PaginationMock `json:"pagination,omitempty"`
+	// FieldMask merges the request resource into Body following the request's field mask.
+	FieldMask *FieldMaskMock `json:"fieldMask,omitempty"`
+	// CacheRendered renders the response once per distinct request and reuses
+	// the result for identical requests. Ignored for Operation responses, which
+	// must start a new operation on every call.
+	CacheRendered bool `json:"cacheRendered,omitempty"`
+	// BodyRef names a fixture registered with POST /fixtures used as the response
+	// body, resolved on every call; the top-level fields of Body, if set, override its fields.
+	BodyRef string `json:"bodyRef,omitempty"`
+	// Delay postpones the response, error or not, e.g. beyond the client's deadline.
+	Delay *DelayMock `json:"delay,omitempty"`
+	// Infinite keeps server streams open, cycling through Bodies (or Body), for
+	// subscription and watch methods.
+	Infinite *InfiniteStreamMock `json:"infinite,omitempty"`
+	// ExceedClientDeadline holds the response until the caller's deadline has
+	// passed, so the caller gets DEADLINE_EXCEEDED; calls without one are answered.
+	ExceedClientDeadline bool `json:"exceedClientDeadline,omitempty"`
+	// FaultPercentage, from 0 to 100, returns Error to that share of the calls
+	// only, picked at random, and Body to the others; unset, Error is always returned.
+	FaultPercentage float64 `json:"faultPercentage,omitempty"`
+	// SetVars stores variables, whose values are templates, when the response is
+	// sent without error; later matchers and templates can refer to them.
+	SetVars map[string]string `json:"setVars,omitempty"`
+	// Exec replaces Body with the output of an executable run for every call.
+	Exec *ExecMock `json:"exec,omitempty"`
+	// Script is a Starlark script whose respond(call) function computes the
+	// body, bodies, headers or error of the response from the call.
+	Script string `json:"script,omitempty"`
+	// Compression is the compressor of the response messages, e.g. "gzip", or
+	// "identity" for none; unset, the request's compressor is used.
+	Compression string `json:"compression,omitempty"`
+	// MessageIntervalMs is the time between the messages of server streams.
+	MessageIntervalMs int64 `json:"messageIntervalMs,omitempty"`
+	// JitterMs shifts each interval between server stream messages, including
+	// those of Infinite streams, by a uniformly random offset of up to ±JitterMs.
+	JitterMs int64 `json:"jitterMs,omitempty"`
+	// Compiled holds the response's templates, SetVars included, compiled at registration.
+	Compiled *CompiledTemplates `json:"-"`
+}
+
+// InfiniteStreamMock sends a message every IntervalMs until the client cancels
+// the call or one of the limits, if set, is reached; the call then ends with OK.
+// Message templates are rendered anew for every message.
+type InfiniteStreamMock struct {
+	IntervalMs    int64 `json:"intervalMs,omitempty"` // Default 1000
+	MaxDurationMs int64 `json:"maxDurationMs,omitempty"`
+	MaxMessages   int   `json:"maxMessages,omitempty"`
+}
+
+// Fixture is a named response body shared by the expectations referencing it with BodyRef.
+type Fixture struct {
+	Name string          `json:"name"`
+	Body json.RawMessage `json:"body"` // A JSON object
+}
+
+// FieldMaskMock makes Update-style methods behave like AIP-134 servers: Body is
+// the stored resource, and the fields of the request resource selected by the
+// request's google.protobuf.FieldMask are merged into it. An empty mask selects
+// all populated fields of the request resource, and "*" replaces the resource.
+type FieldMaskMock struct {
+	ResourceField string `json:"resourceField"`       // Request field holding the resource, e.g. "employee"
+	MaskField     string `json:"maskField,omitempty"` // Request field holding the mask, default "updateMask"
+	BodyField     string `json:"bodyField,omitempty"` // Response field holding the resource; empty if the response is the resource
+}
+
+// PaginationMock slices a full item set into pages for List-style methods.
+// The page is selected from the request's page token and page size, and the
+// response carries the token of the following page (empty on the last page).
+type PaginationMock struct {
+	ItemsField         string            `json:"itemsField"`                   // Response field holding the page items, e.g. "customers"
+	Items              []json.RawMessage `json:"items"`                        // Full item set to paginate
+	PageSize           int               `json:"pageSize,omitempty"`           // Page size used when the request does not set one
+	PageSizeField      string            `json:"pageSizeField,omitempty"`      // Request field with the page size, default "pageSize"
+	PageTokenField     string            `json:"pageTokenField,omitempty"`     // Request field with the page token, default "pageToken"
+	NextPageTokenField string            `json:"nextPageTokenField,omitempty"` // Response field with the next page token, default "nextPageToken"
+	TokenTTLMs         int64             `json:"tokenTtlMs,omitempty"`         // Page tokens expire this long after being issued; 0 for never
+	ExpiredTokenError  *RPCError         `json:"expiredTokenError,omitempty"`  // Error for expired tokens, default INVALID_ARGUMENT
+	// InvalidTokenPages lists page numbers (2 for the first page requested with a token)
+	// whose valid token is nevertheless rejected with INVALID_ARGUMENT.
+	InvalidTokenPages []int `json:"invalidTokenPages,omitempty"`
+}
+
+// OperationMock describes a long-running operation returned by the mock.
+// The matched call receives a pending Operation; subsequent
+// google.longrunning.Operations/GetOperation calls report it as done once
+// DoneAfterMs has elapsed, carrying either Response or Error. Those calls only
+// reach the mock if google/longrunning/operations.proto is among the files the
+// mock server is generated from; otherwise they fail with UNIMPLEMENTED.
+type OperationMock struct {
+	Name        string          `json:"name,omitempty"`        // Operation name; generated when empty
+	DoneAfterMs int64           `json:"doneAfterMs,omitempty"` // Delay before the operation is done
+	Metadata    json.RawMessage `json:"metadata,omitempty"`    // google.protobuf.Any JSON, e.g. {"@type": "...", ...}
+	Response    json.RawMessage `json:"response,omitempty"`    // google.protobuf.Any JSON returned once done
+	Error       *RPCError       `json:"error,omitempty"`       // Error returned once done, instead of Response
+}
+
+// OperationState is a long-running operation tracked by the mock.
+type OperationState struct {
+	OperationMock
+	DoneAt int64 `json:"doneAt"` // Unix nano timestamp after which the operation is done
 }
 
 // RPCError defines a gRPC error to be returned.
 type RPCError struct {
-	Code    codes.Code `json:"code"`
+	Code    codes.Code `json:"code"` // Canonical name, e.g. "NOT_FOUND", or number
 	Message string     `json:"message"`
+	// Details are google.rpc.Status details in the protojson form of google.protobuf.Any,
+	// e.g. {"@type": "type.googleapis.com/google.rpc.ErrorInfo", "reason": "QUOTA"}.
+	Details []json.RawMessage `json:"details,omitempty"`
+	// DetailsBin is a base64-encoded google.rpc.Status, e.g. a captured
+	// grpc-status-details-bin trailer, whose details are sent as they are.
+	DetailsBin string `json:"detailsBin,omitempty"`
 }
 
 // RecordedGRPCCall stores information about an actual call received by the mock.
 type RecordedGRPCCall struct {
+	ID             uint64            `json:"id"` // Sequence number of the call
+	FullMethodName string            `json:"fullMethodName"`
+	Headers        metadata.MD       `json:"headers"`            // Store as metadata.MD for easier access
+	Body           json.RawMessage   `json:"body"`               // JSON representation of the protobuf request
+	Timestamp      int64             `json:"timestamp"`          // Unix nano timestamp
+	TraceID        string            `json:"traceId,omitempty"`  // Trace the call belongs to, if it carried trace context
+	Response       *RecordedResponse `json:"response,omitempty"` // Set once the mock has answered
+	// BodySha256 is the hash of the request, as matched by RequestMatcher.BodySha256.
+	BodySha256 string `json:"bodySha256,omitempty"`
+	// MarshalError is set if the request could not be rendered as protojson. Body is
+	// then empty and BodyRaw holds the request's binary encoding.
+	MarshalError string `json:"marshalError,omitempty"`
+	BodyRaw      []byte `json:"bodyRaw,omitempty"`
+}
+
+// RecordedResponse stores what the mock answered to a recorded call.
+type RecordedResponse struct {
+	ExpectationID string            `json:"expectationId,omitempty"` // ID of the matched expectation, if it has one
+	Matched       bool              `json:"matched"`                 // False if no expectation matched the call
+	Overridden    bool              `json:"overridden,omitempty"`    // True if an override function answered the call
+	Headers       map[string]string `json:"headers,omitempty"`
+	Body          json.RawMessage   `json:"body,omitempty"`   // Single response message
+	Bodies        []json.RawMessage `json:"bodies,omitempty"` // Server-stream response messages
+	StatusCode    codes.Code        `json:"statusCode"`
+	StatusMessage string            `json:"statusMessage,omitempty"`
+	LatencyMs     float64           `json:"latencyMs"` // Time from receiving the call to answering it
+}
+
+// Expectation event types.
+const (
+	ExpectationEventSnapshot = "snapshot" // All expectations registered when the subscription started
+	ExpectationEventAdded    = "added"
+	ExpectationEventReplaced = "replaced"
+	ExpectationEventRemoved  = "removed"
+	ExpectationEventCleared  = "cleared"
+)
+
+// ExpectationEvent describes a change to the registered expectations.
+type ExpectationEvent struct {
+	Type         string                           `json:"type"`
+	Expectation  *GRPCCallExpectation             `json:"expectation,omitempty"`  // For "added", "replaced" and "removed"
+	Expectations map[string][]GRPCCallExpectation `json:"expectations,omitempty"` // For "snapshot"
+	Timestamp    int64                            `json:"timestamp"`              // Unix nano timestamp
+}
+
+// FieldDiff describes why a body field matcher rejected a request.
+type FieldDiff struct {
+	Field    string       `json:"field"`
+	Reason   string       `json:"reason"` // "missing", "unknownField", "extra", "typeMismatch" or "mismatch"
+	Expected FieldMatcher `json:"expected"`
+	Actual   interface{}  `json:"actual,omitempty"`
+}
+
+// NearMiss describes an expectation for the called method that did not match a call.
+type NearMiss struct {
+	ExpectationIndex int         `json:"expectationIndex"`
+	AliasOf          string      `json:"aliasOf,omitempty"` // Method of the expectation, if it matched through an alias
+	Reason           string      `json:"reason"`            // "schedule", "activeWhen", "after", "authority", "headers", "body", "bodySchema", "bodySha256", "deadline", "composite", "stream" or "times"
+	BodyDiff         []FieldDiff `json:"bodyDiff,omitempty"`
+	SchemaErrors     []string    `json:"schemaErrors,omitempty"`
+}
+
+// UnmatchedGRPCCall stores a call that no expectation matched, with the near misses.
+type UnmatchedGRPCCall struct {
 	FullMethodName string          `json:"fullMethodName"`
-	Headers        metadata.MD     `json:"headers"`   // Store as metadata.MD for easier access
-	Body           json.RawMessage `json:"body"`      // JSON representation of the protobuf request
+	Headers        metadata.MD     `json:"headers"`
+	Body           json.RawMessage `json:"body"`
 	Timestamp      int64           `json:"timestamp"` // Unix nano timestamp
+	NearMisses     []NearMiss      `json:"nearMisses"`
+	// MarshalError is set if the request could not be rendered as protojson.
+	MarshalError string `json:"marshalError,omitempty"`
+}
+
+// Connection event types.
+const (
+	ConnectionEventOpened = "opened"
+	ConnectionEventClosed = "closed"
+	ConnectionEventGoAway = "goAwaySent" // The server asked the client to stop using the connection
+)
+
+// ConnectionEvent records a transport-level event on a client connection.
+type ConnectionEvent struct {
+	Type         string `json:"type"`
+	ConnectionID uint64 `json:"connectionId"` // Identifies the connection across its events
+	RemoteAddr   string `json:"remoteAddr"`
+	LocalAddr    string `json:"localAddr"`
+	Detail       string `json:"detail,omitempty"` // For goAwaySent: the HTTP/2 error code and debug data
+	Timestamp    int64  `json:"timestamp"`        // Unix nano timestamp
+}
+
+// DuplicateRequest counts the requests received for a method with the same idempotency key.
+type DuplicateRequest struct {
+	FullMethodName string `json:"fullMethodName"`
+	Key            string `json:"key"`       // Idempotency key, or "sha256:<hex>" body hash
+	Count          int    `json:"count"`     // Requests received with the key, including the first one
+	FirstSeen      int64  `json:"firstSeen"` // Unix nano timestamp
+	LastSeen       int64  `json:"lastSeen"`  // Unix nano timestamp
+}
+
+// ReplayedCall tells whether a recorded call would have matched a proposed expectation.
+type ReplayedCall struct {
+	CallID         uint64    `json:"callId"`
+	FullMethodName string    `json:"fullMethodName"`
+	Matched        bool      `json:"matched"`
+	NearMiss       *NearMiss `json:"nearMiss,omitempty"` // Why the call would not have matched
+}
+
+// ReplayCheckResult reports how a proposed expectation would have matched the recorded calls.
+type ReplayCheckResult struct {
+	MatchedCount int            `json:"matchedCount"`
+	Calls        []ReplayedCall `json:"calls"` // Recorded calls to the expectation's method and aliases
 }
@@ -0,0 +1,49 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// WebhookMock is an HTTP request sent DelayMs after an expectation matches a
+// call. URL, header values and the string values of Body can be response
+// templates, rendered with the call's data.
+type WebhookMock struct {
+	URL     string            `json:"url"`
+	Method  string            `json:"method,omitempty"` // Default POST
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"` // Sent as application/json unless Headers set a Content-Type
+	DelayMs int64             `json:"delayMs,omitempty"`
+	// Compiled holds the webhook's templates, compiled at registration.
+	Compiled *CompiledTemplates `json:"-"`
+}
+
+// HTTPMethod returns the method of the webhook request.
+func (w *WebhookMock) HTTPMethod() string {
+	if w.Method == "" {
+		return http.MethodPost
+	}
+	return strings.ToUpper(w.Method)
+}
+
+// ValidateWebhooks checks the expectation's webhooks.
+func (e *GRPCCallExpectation) ValidateWebhooks() error {
+	for i, w := range e.Webhooks {
+		switch {
+		case w.URL == "":
+			return fmt.Errorf("webhooks[%d]: url is required", i)
+		case w.DelayMs < 0:
+			return fmt.Errorf("webhooks[%d]: delayMs must not be negative", i)
+		case len(w.Body) > 0 && !json.Valid(w.Body):
+			return fmt.Errorf("webhooks[%d]: body must be JSON", i)
+		}
+		switch w.HTTPMethod() {
+		case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			return fmt.Errorf("webhooks[%d]: unsupported method %q", i, w.Method)
+		}
+	}
+	return nil
+}
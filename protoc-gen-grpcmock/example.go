@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// exampleJSON returns the protojson form of a message with each top-level field
+// (only the first of each oneof) set to a placeholder of its type.
+func exampleJSON(msg *protogen.Message) string {
+	var fields []string
+	seenOneofs := make(map[*protogen.Oneof]bool)
+	for _, field := range msg.Fields {
+		if oneof := field.Oneof; oneof != nil && !oneof.Desc.IsSynthetic() {
+			if seenOneofs[oneof] {
+				continue
+			}
+			seenOneofs[oneof] = true
+		}
+		fields = append(fields, fmt.Sprintf("%q: %s", field.Desc.JSONName(), exampleValue(field.Desc)))
+	}
+	return "{" + strings.Join(fields, ", ") + "}"
+}
+
+// exampleValue returns a placeholder value of a field's type in protojson.
+func exampleValue(fd protoreflect.FieldDescriptor) string {
+	switch {
+	case fd.IsMap():
+		return "{}"
+	case fd.IsList():
+		return "[]"
+	}
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return "false"
+	case protoreflect.StringKind, protoreflect.BytesKind:
+		return `""`
+	case protoreflect.EnumKind:
+		return strconv.Quote(string(fd.Enum().Values().Get(0).Name()))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return `"0"` // protojson renders 64-bit integers as strings
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		if strings.HasPrefix(string(fd.Message().FullName()), "google.protobuf.") {
+			return "null" // Well-known types have special JSON forms
+		}
+		return "{}"
+	default:
+		return "0"
+	}
+}
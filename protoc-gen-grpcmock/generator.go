@@ -39,11 +39,15 @@ type MethodData struct {
 	ServerStreaming           bool   // True if server streaming
 	FullMethodName            string // Full gRPC method name
 	QualifiedStreamServerType string // Fully qualified stream server type (if streaming)
+	ExampleResponseBody       string // Output message with placeholder field values, in protojson
 }
 
 //go:embed server.tmpl
 var serverTemplateContent string
 
+//go:embed usage.tmpl
+var usageTemplateContent string
+
 // pendingService is a helper struct for the first pass of service collection.
 type pendingService struct {
 	file    *protogen.File
@@ -92,7 +96,7 @@ func hasClientStreaming(services []ServiceData) bool {
 
 func generateMockServer(
 	gen *protogen.Plugin,
-	outputFilename, targetPackageName, httpPort, grpcPort string,
+	outputFilename, targetPackageName, httpPort, grpcPort, usageFilename string,
 ) error {
 	if targetPackageName == "" {
 		targetPackageName = "main"
@@ -172,6 +176,7 @@ func generateMockServer(
 				ServerStreaming:           method.Desc.IsStreamingServer(),
 				FullMethodName:            fullMethodName,
 				QualifiedStreamServerType: qualifiedStreamServerType,
+				ExampleResponseBody:       exampleJSON(method.Output),
 			})
 		}
 		allServices = append(allServices, svcData)
@@ -197,5 +202,25 @@ func generateMockServer(
 	}
 
 	g.P(buffer.String())
+
+	if usageFilename != "" {
+		if err := generateUsage(gen, usageFilename, templateData); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generateUsage writes a markdown file describing how to drive the generated mock server.
+func generateUsage(gen *protogen.Plugin, usageFilename string, data TemplateData) error {
+	tmpl, err := template.New("grpcmockUsage").Parse(usageTemplateContent)
+	if err != nil {
+		return fmt.Errorf("failed to parse usage template: %w", err)
+	}
+	var buffer strings.Builder
+	if err := tmpl.Execute(&buffer, data); err != nil {
+		return fmt.Errorf("failed to execute usage template: %w", err)
+	}
+	gen.NewGeneratedFile(usageFilename, "").P(buffer.String())
 	return nil
 }
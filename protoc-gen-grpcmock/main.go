@@ -22,6 +22,7 @@ type Config struct {
 	grpcPort       string
 	outputFilename string
 	packageName    string
+	usageFilename  string // Markdown usage file to generate alongside the server; empty for none
 }
 
 // parseConfig parses flags and request parameters into a Config struct.
@@ -37,6 +38,7 @@ func parseConfig(req *pluginpb.CodeGeneratorRequest) Config {
 	flags.StringVar(&cfg.grpcPort, "grpc_port", cfg.grpcPort, "Default gRPC port for the mock server")
 	flags.StringVar(&cfg.outputFilename, "output_filename", cfg.outputFilename, "Name of the single generated mock server file")
 	flags.StringVar(&cfg.packageName, "package_name", cfg.packageName, "Go package name for the generated server file")
+	flags.StringVar(&cfg.usageFilename, "usage_filename", cfg.usageFilename, "Name of the generated markdown usage file; empty for none")
 
 	// Parse parameters from protoc request
 	if req != nil && req.Parameter != nil {
@@ -53,6 +55,8 @@ func parseConfig(req *pluginpb.CodeGeneratorRequest) Config {
 					cfg.outputFilename = parts[1]
 				case "package_name":
 					cfg.packageName = parts[1]
+				case "usage_filename":
+					cfg.usageFilename = parts[1]
 				}
 			}
 		}
@@ -90,7 +94,7 @@ func mainLogic() int {
 
 	plugin.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
 
-	if err := generateMockServer(plugin, cfg.outputFilename, cfg.packageName, cfg.httpPort, cfg.grpcPort); err != nil {
+	if err := generateMockServer(plugin, cfg.outputFilename, cfg.packageName, cfg.httpPort, cfg.grpcPort, cfg.usageFilename); err != nil {
 		plugin.Error(err)
 		log.Printf("grpcmock: error generating mock server: %v", err)
 	}